@@ -0,0 +1,157 @@
+package drbg
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+
+	"github.com/need-being/gmcrypto/sm4"
+)
+
+const (
+	ctrKeyLen   = 16                      // length of the SM4 key in bytes
+	ctrBlockLen = 16                      // length of the SM4 block in bytes
+	ctrSeedLen  = ctrKeyLen + ctrBlockLen // length of the seed in bytes
+)
+
+// ctrDRBG is the internal state of SM4_CTR_DRBG, which uses the derivation
+// function.
+type ctrDRBG struct {
+	block   cipher.Block // SM4 keyed by Key
+	v       [ctrBlockLen]byte
+	counter uint64 // reseed counter
+}
+
+// newCTRDRBG instantiates SM4_CTR_DRBG.
+func newCTRDRBG(entropy, nonce, personalization []byte) *ctrDRBG {
+	// seed_material = Block_Cipher_df(entropy_input || nonce ||
+	// personalization_string, seedlen)
+	var seed [ctrSeedLen]byte
+	blockCipherDF(seed[:], entropy, nonce, personalization)
+
+	// Key = 0^keylen, V = 0^blocklen
+	var key [ctrKeyLen]byte
+	d := &ctrDRBG{
+		block: newSM4(key[:]),
+	}
+	d.update(&seed)
+	d.counter = 1
+	return d
+}
+
+// reseed reseeds the DRBG with the entropy input and the additional input.
+func (d *ctrDRBG) reseed(entropy, additionalInput []byte) {
+	// seed_material = Block_Cipher_df(entropy_input || additional_input, seedlen)
+	var seed [ctrSeedLen]byte
+	blockCipherDF(seed[:], entropy, additionalInput)
+	d.update(&seed)
+	d.counter = 1
+}
+
+// generate fills out with pseudorandom bytes.
+func (d *ctrDRBG) generate(out, additionalInput []byte) {
+	var seed [ctrSeedLen]byte
+	if len(additionalInput) > 0 {
+		blockCipherDF(seed[:], additionalInput)
+		d.update(&seed)
+	}
+
+	var buf [ctrBlockLen]byte
+	for len(out) > 0 {
+		increment(&d.v)
+		d.block.Encrypt(buf[:], d.v[:])
+		out = out[copy(out, buf[:]):]
+	}
+
+	d.update(&seed)
+	d.counter++
+}
+
+// reseedCounter returns the number of requests since the last reseed plus 1.
+func (d *ctrDRBG) reseedCounter() uint64 {
+	return d.counter
+}
+
+// update is CTR_DRBG_Update, which updates Key and V with the provided data.
+func (d *ctrDRBG) update(data *[ctrSeedLen]byte) {
+	var temp [ctrSeedLen]byte
+	for i := 0; i < ctrSeedLen; i += ctrBlockLen {
+		increment(&d.v)
+		d.block.Encrypt(temp[i:], d.v[:])
+	}
+	for i := range temp {
+		temp[i] ^= data[i]
+	}
+	d.block = newSM4(temp[:ctrKeyLen])
+	copy(d.v[:], temp[ctrKeyLen:])
+}
+
+// increment sets V = (V + 1) mod 2^blocklen.
+func increment(v *[ctrBlockLen]byte) {
+	for i := len(v) - 1; i >= 0; i-- {
+		v[i]++
+		if v[i] != 0 {
+			return
+		}
+	}
+}
+
+// blockCipherDF is the derivation function Block_Cipher_df with SM4, which
+// fills out with the bytes derived from the concatenation of inputs.
+func blockCipherDF(out []byte, inputs ...[]byte) {
+	// S = L || N || input_string || 0x80, padded with zeros to a multiple of
+	// blocklen
+	var n int
+	for _, input := range inputs {
+		n += len(input)
+	}
+	s := make([]byte, 8, (8+n+1+ctrBlockLen-1)/ctrBlockLen*ctrBlockLen+ctrBlockLen)
+	binary.BigEndian.PutUint32(s, uint32(n))
+	binary.BigEndian.PutUint32(s[4:], uint32(len(out)))
+	for _, input := range inputs {
+		s = append(s, input...)
+	}
+	s = append(s, 0x80)
+	for len(s)%ctrBlockLen != 0 {
+		s = append(s, 0)
+	}
+
+	// K = leftmost(0x00010203...1F, keylen)
+	var key [ctrKeyLen]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	block := newSM4(key[:])
+
+	// temp = BCC(K, IV(i) || S) for i = 0, 1, ... where IV(i) = i || 0^(blocklen-32)
+	var temp [ctrSeedLen]byte
+	for i := 0; i < ctrSeedLen; i += ctrBlockLen {
+		var chain [ctrBlockLen]byte
+		binary.BigEndian.PutUint32(chain[:], uint32(i/ctrBlockLen))
+		block.Encrypt(chain[:], chain[:])
+		for j := 0; j < len(s); j += ctrBlockLen {
+			for k := range chain {
+				chain[k] ^= s[j+k]
+			}
+			block.Encrypt(chain[:], chain[:])
+		}
+		copy(temp[i:], chain[:])
+	}
+
+	// K = leftmost(temp, keylen), X = select(temp, keylen+1, keylen+outlen),
+	// and output X = Encrypt(K, X) repeatedly.
+	block = newSM4(temp[:ctrKeyLen])
+	x := temp[ctrKeyLen:]
+	for len(out) > 0 {
+		block.Encrypt(x, x)
+		out = out[copy(out, x):]
+	}
+}
+
+// newSM4 returns SM4 keyed by key, which must be ctrKeyLen bytes long.
+func newSM4(key []byte) cipher.Block {
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		panic(err) // key size is fixed
+	}
+	return block
+}
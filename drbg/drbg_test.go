@@ -0,0 +1,291 @@
+package drbg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/need-being/gmcrypto/sm2"
+)
+
+// sequence returns n bytes counting up from start.
+func sequence(n int, start byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = start + byte(i)
+	}
+	return b
+}
+
+// The known answers are not taken from the sample vectors of GM/T 0105-2021.
+// They are produced by the EVP_RAND API of OpenSSL 3.0.17: "HASH-DRBG" with
+// the digest "SM3", and "CTR-DRBG" with the cipher "SM4-CTR" and use_df set,
+// both seeded from a "TEST-RAND" parent. Each DRBG is instantiated with the
+// entropy input sequence(32, 0x00), the nonce sequence(16, 0x20) and the
+// personalization string sequence(32, 0x40), generates 64 bytes with the
+// additional input a1, reseeds with the entropy input sequence(32, 0x80) and
+// the additional input ar, and generates 64 bytes with the additional input a2.
+var tests = []struct {
+	name   string
+	new    func(*Options) (*DRBG, error)
+	add    bool // a1, ar, a2 = sequence(32, 0x60), sequence(32, 0xa0), sequence(32, 0xc0)
+	first  string
+	second string
+}{
+	{
+		name:   "SM3_Hash_DRBG",
+		new:    NewHashDRBG,
+		first:  "320011f052ce09ea661b90fc6038eea40dc065d22f16939515886c06b4befe4700cfeb8f39ace31434b4e851112a8aebc95cddf03d5954d1487e72c27e3d632f",
+		second: "f3b913a7a552f19380736bdf53df7c7c0d9cbb9748c1bf5f2282f3c4553d6ba6f37bdda849018224d0d63c4b75a0cf6603785bd695c6a4200198551139365b80",
+	},
+	{
+		name:   "SM3_Hash_DRBG with additional input",
+		new:    NewHashDRBG,
+		add:    true,
+		first:  "83b191c6db322f6debaf528008952fecaf37943862936e194dc3925cdf38b94ea70be09b24400e2c584c6c219e681192e885b492e70e24cc69c6f207f6ec09cc",
+		second: "e96c72d88ef7e474d4add968ac8608e75b6b3759485dec47c520346f6f43db78b5be929873c8cd9f35c26fbd3ac88718a3f71f24215c8f2fa0c6dea7c0e97ffc",
+	},
+	{
+		name:   "SM4_CTR_DRBG",
+		new:    NewCTRDRBG,
+		first:  "d7b4ed41e294fecd9ff146aa1e2d72804da188960d4acd541eb9711295dbe6b8efe26689b662edab48d59cdd923e7544a7a287fc6d8a56cece29acec3437923b",
+		second: "2cd54b8e6eed03ecb6c1d8cc0d76e0afadd317dcbb26713fe0bcc773446f8db53f55f9fa35dbc6718e9cb2ae0313c6a0eb4fff4a7bc6074b0c5f69807acd8a24",
+	},
+	{
+		name:   "SM4_CTR_DRBG with additional input",
+		new:    NewCTRDRBG,
+		add:    true,
+		first:  "e698665d33d1cf376d4efb913c26cbc49466939114b177ca80ca81f504936ab34648940aa08eda35ebc83997e6e497a2a975503ff7660af321f87fccb07bcb3b",
+		second: "eaa230d5bd926435707a5a76f2c55ec9dbb281ed49c0afb1997257fde73be0b7143a890379e557464c9382794ae9a094ce9408a2c90b71f889fbae5108586dbc",
+	},
+}
+
+func TestDRBG(t *testing.T) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a1, ar, a2 []byte
+			if tt.add {
+				a1, ar, a2 = sequence(32, 0x60), sequence(32, 0xa0), sequence(32, 0xc0)
+			}
+			entropy := bytes.NewReader(append(append(sequence(32, 0x00), sequence(16, 0x20)...), sequence(32, 0x80)...))
+			d, err := tt.new(&Options{
+				Entropy:         entropy,
+				Personalization: sequence(32, 0x40),
+			})
+			if err != nil {
+				t.Fatal("instantiate:", err)
+			}
+
+			out := make([]byte, 64)
+			if err := d.Generate(out, a1); err != nil {
+				t.Fatal("Generate:", err)
+			}
+			if got := hex.EncodeToString(out); got != tt.first {
+				t.Errorf("Generate() = %s, want %s", got, tt.first)
+			}
+			if err := d.Reseed(ar); err != nil {
+				t.Fatal("Reseed:", err)
+			}
+			if err := d.Generate(out, a2); err != nil {
+				t.Fatal("Generate:", err)
+			}
+			if got := hex.EncodeToString(out); got != tt.second {
+				t.Errorf("Generate() after Reseed() = %s, want %s", got, tt.second)
+			}
+
+			// the entropy source is exhausted.
+			if err := d.Reseed(nil); err == nil {
+				t.Error("Reseed() without entropy succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestDRBG_predictionResistance(t *testing.T) {
+	for _, tt := range tests {
+		if !tt.add {
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			// a DRBG with prediction resistance reseeds with the additional
+			// input before generating.
+			entropy := bytes.NewReader(append(append(sequence(32, 0x00), sequence(16, 0x20)...), sequence(32, 0x80)...))
+			d, err := tt.new(&Options{
+				Entropy:              entropy,
+				Personalization:      sequence(32, 0x40),
+				PredictionResistance: true,
+			})
+			if err != nil {
+				t.Fatal("instantiate:", err)
+			}
+			got := make([]byte, 64)
+			if err := d.Generate(got, sequence(32, 0xa0)); err != nil {
+				t.Fatal("Generate:", err)
+			}
+
+			entropy = bytes.NewReader(append(append(sequence(32, 0x00), sequence(16, 0x20)...), sequence(32, 0x80)...))
+			want, err := tt.new(&Options{
+				Entropy:         entropy,
+				Personalization: sequence(32, 0x40),
+			})
+			if err != nil {
+				t.Fatal("instantiate:", err)
+			}
+			if err := want.Reseed(sequence(32, 0xa0)); err != nil {
+				t.Fatal("Reseed:", err)
+			}
+			wantOut := make([]byte, 64)
+			if err := want.Generate(wantOut, nil); err != nil {
+				t.Fatal("Generate:", err)
+			}
+			if !bytes.Equal(got, wantOut) {
+				t.Errorf("Generate() = %x, want %x", got, wantOut)
+			}
+
+			// the entropy source is exhausted.
+			if err := d.Generate(got, nil); err == nil {
+				t.Error("Generate() without entropy succeeded, want error")
+			}
+		})
+	}
+}
+
+// countingReader counts the bytes read from an infinite entropy source.
+type countingReader struct {
+	n int
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	r.n += len(b)
+	return len(b), nil
+}
+
+func TestDRBG_reseedInterval(t *testing.T) {
+	for _, newDRBG := range []func(*Options) (*DRBG, error){NewHashDRBG, NewCTRDRBG} {
+		entropy := &countingReader{}
+		d, err := newDRBG(&Options{Entropy: entropy}) // Level2 by default
+		if err != nil {
+			t.Fatal("instantiate:", err)
+		}
+		interval := uint64(1 << 10)
+		buf := make([]byte, 1)
+		for i := uint64(0); i < interval; i++ {
+			if err := d.Generate(buf, nil); err != nil {
+				t.Fatal("Generate:", err)
+			}
+		}
+		if want := entropySize + nonceSize; entropy.n != want {
+			t.Fatalf("read %d bytes of entropy after %d requests, want %d", entropy.n, interval, want)
+		}
+		if err := d.Generate(buf, nil); err != nil {
+			t.Fatal("Generate:", err)
+		}
+		if want := 2*entropySize + nonceSize; entropy.n != want {
+			t.Errorf("read %d bytes of entropy after %d requests, want %d", entropy.n, interval+1, want)
+		}
+		if got := d.mech.reseedCounter(); got != 2 {
+			t.Errorf("reseed counter = %d, want 2", got)
+		}
+
+		// reseed after the reseed time
+		d.lastReseed = time.Now().Add(-time.Hour)
+		if err := d.Generate(buf, nil); err != nil {
+			t.Fatal("Generate:", err)
+		}
+		if want := 3*entropySize + nonceSize; entropy.n != want {
+			t.Errorf("read %d bytes of entropy after the reseed time, want %d", entropy.n, want)
+		}
+	}
+}
+
+func TestDRBG_Read(t *testing.T) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entropy := sequence(48, 0)
+			d, err := tt.new(&Options{Entropy: bytes.NewReader(entropy)})
+			if err != nil {
+				t.Fatal("instantiate:", err)
+			}
+			got := make([]byte, MaxRequestSize+100)
+			if _, err := io.ReadFull(d, got); err != nil {
+				t.Fatal("Read:", err)
+			}
+
+			d, err = tt.new(&Options{Entropy: bytes.NewReader(entropy)})
+			if err != nil {
+				t.Fatal("instantiate:", err)
+			}
+			want := make([]byte, len(got))
+			if err := d.Generate(want[:MaxRequestSize], nil); err != nil {
+				t.Fatal("Generate:", err)
+			}
+			if err := d.Generate(want[MaxRequestSize:], nil); err != nil {
+				t.Fatal("Generate:", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Error("Read() differs from the requests of MaxRequestSize bytes")
+			}
+
+			if err := d.Generate(make([]byte, MaxRequestSize+1), nil); err == nil {
+				t.Error("Generate() of more than MaxRequestSize bytes succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestDRBG_invalid(t *testing.T) {
+	if _, err := NewHashDRBG(&Options{SecurityLevel: 3}); err == nil {
+		t.Error("NewHashDRBG() with an invalid security level succeeded, want error")
+	}
+	if _, err := NewCTRDRBG(&Options{Personalization: make([]byte, MaxInputSize+1)}); err == nil {
+		t.Error("NewCTRDRBG() with a long personalization string succeeded, want error")
+	}
+	if _, err := NewCTRDRBG(&Options{Entropy: bytes.NewReader(make([]byte, 47))}); err == nil {
+		t.Error("NewCTRDRBG() with insufficient entropy succeeded, want error")
+	}
+}
+
+func TestDRBG_sm2(t *testing.T) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := tt.new(nil)
+			if err != nil {
+				t.Fatal("instantiate:", err)
+			}
+			priv, err := sm2.GenerateKey(sm2.Curve(), d)
+			if err != nil {
+				t.Fatal("sm2.GenerateKey:", err)
+			}
+			message := []byte("message")
+			sig, err := sm2.Sign(d, priv, message)
+			if err != nil {
+				t.Fatal("sm2.Sign:", err)
+			}
+			if !sm2.Verify(&priv.PublicKey, message, sig) {
+				t.Error("sm2.Verify() = false, want true")
+			}
+		})
+	}
+}
+
+func BenchmarkHashDRBG(b *testing.B) {
+	benchmarkDRBG(b, NewHashDRBG)
+}
+
+func BenchmarkCTRDRBG(b *testing.B) {
+	benchmarkDRBG(b, NewCTRDRBG)
+}
+
+func benchmarkDRBG(b *testing.B, newDRBG func(*Options) (*DRBG, error)) {
+	d, err := newDRBG(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	b.SetBytes(int64(len(buf)))
+	for i := 0; i < b.N; i++ {
+		d.Read(buf)
+	}
+}
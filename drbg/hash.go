@@ -0,0 +1,118 @@
+package drbg
+
+import (
+	"encoding/binary"
+	"hash"
+
+	"github.com/need-being/gmcrypto/sm3"
+)
+
+// hashSeedLen is the length of the seed of SM3_Hash_DRBG in bytes, which is
+// 440 bits.
+const hashSeedLen = 55
+
+// hashDRBG is the internal state of SM3_Hash_DRBG.
+type hashDRBG struct {
+	h       hash.Hash
+	v       [hashSeedLen]byte
+	c       [hashSeedLen]byte
+	counter uint64 // reseed counter
+}
+
+// newHashDRBG instantiates SM3_Hash_DRBG.
+func newHashDRBG(entropy, nonce, personalization []byte) *hashDRBG {
+	d := &hashDRBG{
+		h: sm3.New(),
+	}
+	// seed = Hash_df(entropy_input || nonce || personalization_string, seedlen)
+	d.hashDF(d.v[:], entropy, nonce, personalization)
+	d.update()
+	return d
+}
+
+// reseed reseeds the DRBG with the entropy input and the additional input.
+func (d *hashDRBG) reseed(entropy, additionalInput []byte) {
+	// seed = Hash_df(0x01 || V || entropy_input || additional_input, seedlen)
+	var v [hashSeedLen]byte
+	d.hashDF(v[:], []byte{0x01}, d.v[:], entropy, additionalInput)
+	d.v = v
+	d.update()
+}
+
+// update sets C = Hash_df(0x00 || V, seedlen), and resets the reseed counter.
+func (d *hashDRBG) update() {
+	d.hashDF(d.c[:], []byte{0x00}, d.v[:])
+	d.counter = 1
+}
+
+// generate fills out with pseudorandom bytes.
+func (d *hashDRBG) generate(out, additionalInput []byte) {
+	if len(additionalInput) > 0 {
+		// w = Hash(0x02 || V || additional_input), V = (V + w) mod 2^seedlen
+		addBytes(d.v[:], d.hash(nil, []byte{0x02}, d.v[:], additionalInput))
+	}
+
+	// Hashgen
+	data := d.v
+	var sum [sm3.Size]byte
+	for len(out) > 0 {
+		d.hash(sum[:0], data[:])
+		out = out[copy(out, sum[:]):]
+		addBytes(data[:], []byte{0x01})
+	}
+
+	// H = Hash(0x03 || V), V = (V + H + C + reseed_counter) mod 2^seedlen
+	d.hash(sum[:0], []byte{0x03}, d.v[:])
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], d.counter)
+	addBytes(d.v[:], sum[:])
+	addBytes(d.v[:], d.c[:])
+	addBytes(d.v[:], counter[:])
+	d.counter++
+}
+
+// reseedCounter returns the number of requests since the last reseed plus 1.
+func (d *hashDRBG) reseedCounter() uint64 {
+	return d.counter
+}
+
+// hash appends the SM3 checksum of the concatenation of inputs to b.
+func (d *hashDRBG) hash(b []byte, inputs ...[]byte) []byte {
+	d.h.Reset()
+	for _, input := range inputs {
+		d.h.Write(input) // write on sm3 never returns error
+	}
+	return d.h.Sum(b)
+}
+
+// hashDF is the derivation function Hash_df, which fills out with the bytes
+// derived from the concatenation of inputs.
+func (d *hashDRBG) hashDF(out []byte, inputs ...[]byte) {
+	var prefix [5]byte // counter || no_of_bits_to_return
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(out)*8))
+	var sum [sm3.Size]byte
+	for counter := byte(1); len(out) > 0; counter++ {
+		prefix[0] = counter
+		d.h.Reset()
+		d.h.Write(prefix[:])
+		for _, input := range inputs {
+			d.h.Write(input)
+		}
+		out = out[copy(out, d.h.Sum(sum[:0])):]
+	}
+}
+
+// addBytes sets a = (a + b) mod 2^(8*len(a)), where a and b are big-endian
+// integers, and len(b) <= len(a).
+func addBytes(a, b []byte) {
+	var carry uint16
+	i, j := len(a)-1, len(b)-1
+	for ; i >= 0; i, j = i-1, j-1 {
+		sum := uint16(a[i]) + carry
+		if j >= 0 {
+			sum += uint16(b[j])
+		}
+		a[i] = byte(sum)
+		carry = sum >> 8
+	}
+}
@@ -0,0 +1,10 @@
+// Package cpu implements processor feature detection for the assembly
+// implementations in this module.
+package cpu
+
+// X86 contains the features of x86 processors, which are detected at
+// initialization. All features are false on other architectures.
+var X86 struct {
+	HasAVX2 bool // AVX2 instructions supported by the processor and the OS
+	HasBMI2 bool // BMI2 instructions
+}
@@ -0,0 +1,39 @@
+//go:build amd64 && gc
+// +build amd64,gc
+
+package cpu
+
+// cpuid is implemented in cpu_x86.s.
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+// xgetbv with ecx = 0 is implemented in cpu_x86.s.
+func xgetbv() (eax, edx uint32)
+
+// bits of the features in the results of cpuid.
+const (
+	cpuidOSXSAVE = 1 << 27 // ecx of leaf 1
+	cpuidAVX     = 1 << 28 // ecx of leaf 1
+	cpuidAVX2    = 1 << 5  // ebx of leaf 7
+	cpuidBMI2    = 1 << 8  // ebx of leaf 7
+)
+
+func init() {
+	maxID, _, _, _ := cpuid(0, 0)
+	if maxID < 7 {
+		return
+	}
+
+	// AVX requires the OS to save the XMM and YMM registers on context
+	// switches, as reported by XCR0.
+	_, _, ecx1, _ := cpuid(1, 0)
+	osSupportsAVX := false
+	if ecx1&cpuidOSXSAVE != 0 {
+		eax, _ := xgetbv()
+		osSupportsAVX = eax&(1<<1|1<<2) == 1<<1|1<<2
+	}
+	hasAVX := ecx1&cpuidAVX != 0 && osSupportsAVX
+
+	_, ebx7, _, _ := cpuid(7, 0)
+	X86.HasAVX2 = hasAVX && ebx7&cpuidAVX2 != 0
+	X86.HasBMI2 = ebx7&cpuidBMI2 != 0
+}
@@ -0,0 +1,82 @@
+package sm2
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2/internal/convert"
+)
+
+// signatureASN1 is the ASN.1 structure of an SM2 signature defined in
+// GM/T 0009-2012.
+//
+//	SM2Signature ::= SEQUENCE {
+//	    R INTEGER,
+//	    S INTEGER
+//	}
+type signatureASN1 struct {
+	R, S *big.Int
+}
+
+// SignASN1 signs the message with a private key and returns a signature
+// encoded in ASN.1 DER as specified in GM/T 0009-2012.
+// SM3 is used for hash algorithm.
+func SignASN1(rand io.Reader, priv *PrivateKey, message []byte) ([]byte, error) {
+	sig, err := Sign(rand, priv, message)
+	if err != nil {
+		return nil, err
+	}
+	return RawToASN1(sig)
+}
+
+// VerifyASN1 reports whether sig, encoded in ASN.1 DER, is a valid signature
+// of message by the given public key.
+func VerifyASN1(pub *PublicKey, message, sig []byte) bool {
+	raw, err := ASN1ToRaw(pub.Curve, sig)
+	if err != nil {
+		return false
+	}
+	return Verify(pub, message, raw)
+}
+
+// RawToASN1 converts a signature in the form of r || s, where r and s have the
+// same length, to ASN.1 DER.
+func RawToASN1(sig []byte) ([]byte, error) {
+	if len(sig) == 0 || len(sig)%2 != 0 {
+		return nil, errors.New("sm2: invalid signature length")
+	}
+	n := len(sig) / 2
+	return asn1.Marshal(signatureASN1{
+		R: convert.BytesToInteger(sig[:n]),
+		S: convert.BytesToInteger(sig[n:]),
+	})
+}
+
+// ASN1ToRaw converts a signature encoded in ASN.1 DER to the form of r || s,
+// where the length of r and s is determined by the curve.
+func ASN1ToRaw(c elliptic.Curve, sig []byte) ([]byte, error) {
+	var v signatureASN1
+	rest, err := asn1.Unmarshal(sig, &v)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("sm2: trailing data after signature")
+	}
+	if v.R.Sign() <= 0 || v.S.Sign() <= 0 {
+		return nil, errors.New("sm2: non-positive signature integer")
+	}
+
+	n := (c.Params().BitSize + 7) / 8
+	raw := make([]byte, n*2)
+	if err = convert.IntegerToBytes(v.R, raw[:n]); err != nil {
+		return nil, err
+	}
+	if err = convert.IntegerToBytes(v.S, raw[n:]); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
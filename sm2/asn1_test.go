@@ -0,0 +1,190 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"testing"
+)
+
+// signature from GB/T 32918.5-2017 A.2
+var (
+	testSignatureRaw = []byte{
+		// r
+		0xf5, 0xa0, 0x3b, 0x06, 0x48, 0xd2, 0xc4, 0x63,
+		0x0e, 0xea, 0xc5, 0x13, 0xe1, 0xbb, 0x81, 0xa1,
+		0x59, 0x44, 0xda, 0x38, 0x27, 0xd5, 0xb7, 0x41,
+		0x43, 0xac, 0x7e, 0xac, 0xee, 0xe7, 0x20, 0xb3,
+
+		// s
+		0xb1, 0xb6, 0xaa, 0x29, 0xdf, 0x21, 0x2f, 0xd8,
+		0x76, 0x31, 0x82, 0xbc, 0x0d, 0x42, 0x1c, 0xa1,
+		0xbb, 0x90, 0x38, 0xfd, 0x1f, 0x7f, 0x42, 0xd4,
+		0x84, 0x0b, 0x69, 0xc4, 0x85, 0xbb, 0xc1, 0xaa,
+	}
+	testSignatureASN1 = concat(
+		[]byte{0x30, 0x46, 0x02, 0x21, 0x00},
+		testSignatureRaw[:32],
+		[]byte{0x02, 0x21, 0x00},
+		testSignatureRaw[32:],
+	)
+)
+
+func TestRawToASN1(t *testing.T) {
+	tests := []struct {
+		name    string
+		sig     []byte
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name: "GB/T 32918.5-2017 A.2",
+			sig:  testSignatureRaw,
+			want: testSignatureASN1,
+		},
+		{
+			name: "leading zeros",
+			sig:  []byte{0x00, 0x01, 0x00, 0x7f},
+			want: []byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x7f},
+		},
+		{
+			name:    "odd length",
+			sig:     testSignatureRaw[1:],
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RawToASN1(tt.sig)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RawToASN1() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("RawToASN1() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestASN1ToRaw(t *testing.T) {
+	tests := []struct {
+		name    string
+		sig     []byte
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name: "GB/T 32918.5-2017 A.2",
+			sig:  testSignatureASN1,
+			want: testSignatureRaw,
+		},
+		{
+			name: "leading zeros",
+			sig:  []byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x7f},
+			want: concat(make([]byte, 31), []byte{0x01}, make([]byte, 31), []byte{0x7f}),
+		},
+		{
+			name:    "trailing data",
+			sig:     concat(testSignatureASN1, []byte{0x00}),
+			wantErr: true,
+		},
+		{
+			name:    "negative integer",
+			sig:     []byte{0x30, 0x06, 0x02, 0x01, 0x81, 0x02, 0x01, 0x7f},
+			wantErr: true,
+		},
+		{
+			name:    "integer too large",
+			sig:     concat([]byte{0x30, 0x27, 0x02, 0x21, 0x01}, make([]byte, 32), []byte{0x02, 0x01, 0x7f}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ASN1ToRaw(Curve(), tt.sig)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ASN1ToRaw() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("ASN1ToRaw() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrivateKey_Sign(t *testing.T) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	priv.ID = []byte("signer")
+	message := []byte("message")
+
+	tests := []struct {
+		name    string
+		opts    crypto.SignerOpts
+		verify  func(*PublicKey, []byte, []byte) bool
+		wantErr bool
+	}{
+		{
+			name:   "no hash",
+			opts:   crypto.Hash(0),
+			verify: Verify,
+		},
+		{
+			name:   "raw signature",
+			opts:   &SignerOpts{Format: RawSignature},
+			verify: Verify,
+		},
+		{
+			name:   "ASN.1 signature",
+			opts:   &SignerOpts{Format: ASN1Signature},
+			verify: VerifyASN1,
+		},
+		{
+			name:   "deterministic ASN.1 signature",
+			opts:   &SignerOpts{Format: ASN1Signature, Nonce: DeterministicNonce},
+			verify: VerifyASN1,
+		},
+		{
+			name:    "unknown nonce mode",
+			opts:    &SignerOpts{Nonce: NonceMode(-1)},
+			wantErr: true,
+		},
+		{
+			name:    "hashed message",
+			opts:    crypto.SHA256,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sig, err := priv.Sign(rand.Reader, message, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PrivateKey.Sign() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !tt.verify(&priv.PublicKey, message, sig) {
+				t.Errorf("PrivateKey.Sign() = %x, which cannot be verified", sig)
+			}
+		})
+	}
+}
+
+func TestVerifyASN1(t *testing.T) {
+	pub := &PublicKey{
+		Curve: curve,
+		X:     testKey.X,
+		Y:     testKey.Y,
+		ID:    []byte("1234567812345678"),
+	}
+	message := []byte("message digest")
+	if !VerifyASN1(pub, message, testSignatureASN1) {
+		t.Errorf("VerifyASN1() = %v, want %v", false, true)
+	}
+	if VerifyASN1(pub, message, testSignatureRaw) {
+		t.Errorf("VerifyASN1(raw signature) = %v, want %v", true, false)
+	}
+}
@@ -0,0 +1,371 @@
+package sm2
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/need-being/gmcrypto/sm2/internal/convert"
+	"github.com/need-being/gmcrypto/sm2/internal/sm2ec"
+	"github.com/need-being/gmcrypto/sm3"
+)
+
+const (
+	// batchSize is the maximum number of signatures of known signs of R in a
+	// combined check, whose points R are folded into the multi-scalar
+	// multiplication.
+	batchSize = 64
+
+	// searchSize is the maximum number of signatures of unknown signs of R in
+	// a combined check, whose signs are searched in 2^(searchSize-1)
+	// additions.
+	searchSize = 8
+
+	// tableThreshold is the number of signatures of unknown signs of R by a
+	// public key, from which the key gets a table of its multiples, and the
+	// signatures are verified one by one as a Verifier does. The table takes
+	// about as long to compute as 8 scalar multiplications.
+	tableThreshold = 8
+)
+
+// BatchEntry is a signature to be verified by VerifyBatch.
+type BatchEntry struct {
+	// PublicKey is the public key of the signer, whose ID is used.
+	PublicKey *PublicKey
+
+	// Message is the signed message.
+	Message []byte
+
+	// Signature is the signature in the form of r || s, or r || s || v as
+	// produced with RecoverableSignature. The recovery ID v must be in [0, 3],
+	// and tells the sign of R on the SM2 curve, but the validity of the
+	// signature depends on r || s only, as with VerifyWithOpts.
+	Signature []byte
+}
+
+// VerifyBatch verifies the signatures of entries, and returns the indices of
+// the entries with invalid signatures in ascending order. It returns nil if
+// all signatures are valid.
+//
+// Each valid signature on the SM2 curve satisfies sG + tP = R, where the point
+// R = (x1, y1) is recovered from x1 = (r - e) mod n and the parity of y1. For
+// signatures in the form of r || s || v, the parity is given by the recovery
+// ID, and batches of up to 64 entries are checked by a single multi-scalar
+// multiplication of Σ a_i(s_i G + t_i P_i - R_i) = O with random 128-bit
+// weights a_i, which is considerably faster than verifying them one by one.
+//
+// For signatures in the form of r || s, the parity is unknown, so R_i cannot
+// be folded into the multi-scalar multiplication. Instead, each a_i R_i costs
+// a scalar multiplication of its own, and the signs are searched in batches of
+// up to 8 entries, which is faster than Verify, but not than a Verifier. Public
+// keys signing 8 entries or more get a table of their multiples once, and
+// their entries are verified one by one as a Verifier does.
+//
+// Only if a combined check fails are its entries verified one by one to find
+// the invalid ones. Entries on other curves, and those whose R cannot be
+// recovered, are verified one by one as Verify does.
+//
+// The public keys shared by entries, i.e. the same *PublicKey, are validated
+// and hashed into Z only once, and their terms in a batch are merged. The
+// entries are verified concurrently with up to GOMAXPROCS goroutines.
+func VerifyBatch(entries []BatchEntry) []int {
+	// validate and hash each distinct public key once.
+	keys := make(map[*PublicKey]*batchKey)
+	for _, entry := range entries {
+		pub := entry.PublicKey
+		if pub == nil {
+			continue
+		}
+		key, ok := keys[pub]
+		if !ok {
+			key = &batchKey{}
+			if pub.Validate() == nil {
+				key.z, _ = pub.Digest() // z is nil on error
+				if c, ok := pub.Curve.(*sm2Curve); ok {
+					key.point, _ = c.pointFromAffine(pub.X, pub.Y)
+				}
+			}
+			keys[pub] = key
+		}
+		if key.point != nil && len(entry.Signature) == 2*((curve.N.BitLen()+7)/8) {
+			key.unsigned++ // r || s on the SM2 curve
+		}
+	}
+
+	// hash the messages and recover R, or verify the entries that are not
+	// checked in batches.
+	valid := make([]bool, len(entries))
+	items := make([]*batchItem, len(entries))
+	parallel(len(entries), func(i int) {
+		entry := entries[i]
+		key := keys[entry.PublicKey]
+		if key == nil || key.z == nil {
+			return
+		}
+		sig := batchSignature(entry.PublicKey, entry.Signature)
+		if sig == nil {
+			return
+		}
+
+		// B3, B4: compute hash value e.
+		h := sm3.New() // write on sm3 never returns error
+		h.Write(key.z)
+		h.Write(entry.Message)
+		digest := h.Sum(nil)
+
+		if key.point != nil && len(sig) == len(entry.Signature) && key.unsigned >= tableThreshold {
+			valid[i] = verifyDigest(entry.PublicKey, key.fixedBaseTable(), digest, sig)
+		} else if item := newBatchItem(i, entry.PublicKey, key, digest, entry.Signature); item != nil {
+			items[i] = item
+		} else {
+			valid[i] = verifyDigest(entry.PublicKey, nil, digest, sig)
+		}
+	})
+
+	// group the items into batches by whether the signs of R are known.
+	var batches [][]*batchItem
+	var signed, unsigned []*batchItem
+	for _, item := range items {
+		switch {
+		case item == nil:
+		case item.signed:
+			if signed = append(signed, item); len(signed) == batchSize {
+				batches = append(batches, signed)
+				signed = nil
+			}
+		default:
+			if unsigned = append(unsigned, item); len(unsigned) == searchSize {
+				batches = append(batches, unsigned)
+				unsigned = nil
+			}
+		}
+	}
+	for _, batch := range [][]*batchItem{signed, unsigned} {
+		if len(batch) != 0 {
+			batches = append(batches, batch)
+		}
+	}
+	parallel(len(batches), func(i int) {
+		batch := batches[i]
+		ok := verifyBatch(batch)
+		for _, item := range batch {
+			valid[item.index] = ok || verifyDigest(item.pub, nil, item.digest, item.sig)
+		}
+	})
+
+	var failed []int
+	for i, ok := range valid {
+		if !ok {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// parallel calls f(0), f(1), ..., f(n-1) with up to GOMAXPROCS goroutines, and
+// returns after all calls return.
+func parallel(n int, f func(i int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < n; i += workers {
+				f(i)
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// batchSignature returns r || s of a signature of BatchEntry, or nil if the
+// signature is neither in the form of r || s nor r || s || v with a valid
+// recovery ID.
+func batchSignature(pub *PublicKey, sig []byte) []byte {
+	n := (pub.Curve.Params().N.BitLen() + 7) / 8
+	switch {
+	case len(sig) == 2*n:
+		return sig
+	case len(sig) == 2*n+1 && sig[2*n] <= 3:
+		return sig[:2*n]
+	default:
+		return nil
+	}
+}
+
+// batchKey is a validated public key of VerifyBatch.
+type batchKey struct {
+	z        []byte       // Z of the key, or nil if the key is invalid
+	point    *sm2ec.Point // the key on the SM2 curve, or nil for other curves
+	unsigned int          // the number of entries in the form of r || s
+
+	tableOnce sync.Once
+	table     *sm2ec.FixedBaseTable
+}
+
+// fixedBaseTable returns the table of the multiples of the key on the SM2
+// curve, which is computed on first use.
+func (key *batchKey) fixedBaseTable() *sm2ec.FixedBaseTable {
+	key.tableOnce.Do(func() {
+		key.table = sm2ec.NewFixedBaseTable(key.point)
+	})
+	return key.table
+}
+
+// batchItem is a signature prepared for the combined check of VerifyBatch.
+type batchItem struct {
+	index  int
+	pub    *PublicKey
+	key    *batchKey
+	digest []byte
+	sig    []byte        // r || s
+	s, t   *sm2ec.Scalar // s and t = (r + s) mod n
+	r      *sm2ec.Point  // R, whose y1 is even if the sign is unknown
+	signed bool          // whether the sign of R is known
+}
+
+// newBatchItem parses the signature of the hash value e with a validated
+// public key, and recovers R. It returns nil if the signature cannot be
+// checked in a batch.
+func newBatchItem(index int, pub *PublicKey, key *batchKey, digest, sig []byte) *batchItem {
+	c, ok := pub.Curve.(*sm2Curve)
+	if !ok || key.point == nil {
+		return nil
+	}
+
+	// parse (r, s) and the optional recovery ID v
+	n := (c.N.BitLen() + 7) / 8
+	var v byte
+	switch len(sig) {
+	case 2 * n:
+	case 2*n + 1:
+		v = sig[2*n]
+		if v > 3 {
+			return nil
+		}
+	default:
+		return nil
+	}
+	r := convert.BytesToInteger(sig[:n])
+	s := convert.BytesToInteger(sig[n : 2*n])
+
+	// B1, B2: check r, s in [1, n-1]
+	if r.Sign() == 0 || r.Cmp(c.N) >= 0 || s.Sign() == 0 || s.Cmp(c.N) >= 0 {
+		return nil
+	}
+
+	// B5: compute t = (r + s) mod n
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, c.N)
+	if t.Sign() == 0 {
+		return nil
+	}
+
+	// recover R from x1 = (r - e) mod n, or x1 + n if bit 1 of v is set, and
+	// the parity of y1 in bit 0 of v, where y1 is taken even without v.
+	e := convert.BytesToInteger(digest)
+	x1 := new(big.Int).Sub(r, e)
+	x1.Mod(x1, c.N)
+	if v&2 != 0 {
+		x1.Add(x1, c.N)
+		if x1.Cmp(c.P) >= 0 {
+			return nil
+		}
+	}
+	buf := make([]byte, 1+(c.BitSize+7)/8)
+	buf[0] = 2 | v&1 // compressed point
+	x1.FillBytes(buf[1:])
+	point, err := sm2ec.NewPoint().SetBytes(buf)
+	if err != nil {
+		return nil
+	}
+
+	sScalar, err := sm2ec.NewScalar().SetBytes(c.scalarBytes(s))
+	if err != nil {
+		return nil
+	}
+	tScalar, err := sm2ec.NewScalar().SetBytes(c.scalarBytes(t))
+	if err != nil {
+		return nil
+	}
+	return &batchItem{
+		index:  index,
+		pub:    pub,
+		key:    key,
+		digest: digest,
+		sig:    sig[:2*n],
+		s:      sScalar,
+		t:      tScalar,
+		r:      point,
+		signed: len(sig) == 2*n+1,
+	}
+}
+
+// verifyBatch reports whether the signatures of items are all valid, by
+// checking Σ a_i(s_i G + t_i P_i) - Σ a_i R_i = Σ ±a_i R_i with random 128-bit
+// weights a_i, where the left sum is over R_i of known signs, and the right
+// sum is over the others.
+//
+// Except with a negligible probability over the weights, a passing check
+// implies sG + tP = ±R for each signature, which is the condition of
+// GB/T 32918.2-2016 7.1 B7, as the affine x-coordinate of ±R is x1.
+func verifyBatch(items []*batchItem) bool {
+	if len(items) == 0 {
+		return true
+	}
+
+	sumS := sm2ec.NewScalar()
+	var coeffs []*sm2ec.Scalar
+	var points, unsigned []*sm2ec.Point
+	keys := make(map[*batchKey]*sm2ec.Scalar)
+	zero := make([]byte, 32)
+	w := make([]byte, 32)
+	for _, item := range items {
+		// a_i is a random 128-bit weight, which is less than n.
+		if _, err := io.ReadFull(rand.Reader, w[16:]); err != nil {
+			return false
+		}
+		a, err := sm2ec.NewScalar().SetBytes(w)
+		if err != nil {
+			return false
+		}
+
+		// merge the terms of the generator and shared public keys.
+		sumS.Add(sumS, sm2ec.NewScalar().Mul(a, item.s))
+		at := sm2ec.NewScalar().Mul(a, item.t)
+		if coeff, ok := keys[item.key]; ok {
+			coeff.Add(coeff, at)
+		} else {
+			keys[item.key] = at
+			coeffs = append(coeffs, at)
+			points = append(points, item.key.point)
+		}
+
+		if item.signed {
+			coeffs = append(coeffs, a)
+			points = append(points, sm2ec.NewPoint().Negate(item.r))
+			continue
+		}
+		ar, err := sm2ec.NewPoint().VarTimeMultiScalarBaseMult(zero, [][]byte{a.Bytes()}, []*sm2ec.Point{item.r})
+		if err != nil {
+			return false
+		}
+		unsigned = append(unsigned, ar)
+	}
+
+	scalars := make([][]byte, len(coeffs))
+	for i, coeff := range coeffs {
+		scalars[i] = coeff.Bytes()
+	}
+	p, err := sm2ec.NewPoint().VarTimeMultiScalarBaseMult(sumS.Bytes(), scalars, points)
+	if err != nil {
+		return false
+	}
+	return p.VarTimeIsSignedSum(unsigned)
+}
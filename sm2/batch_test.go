@@ -0,0 +1,290 @@
+package sm2
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/need-being/gmcrypto/sm2/internal/sm2ec"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	keys := make([]*PrivateKey, 3)
+	for i := range keys {
+		priv, err := GenerateKey(Curve(), rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey:", err)
+		}
+		priv.ID = []byte(fmt.Sprintf("signer %d", i))
+		keys[i] = priv
+	}
+
+	// keys on the generic curve fall back to the generic computation.
+	generic := &PrivateKey{
+		PublicKey: PublicKey{
+			Curve: curve.Params(),
+			X:     keys[0].X,
+			Y:     keys[0].Y,
+			ID:    keys[0].ID,
+		},
+		D: keys[0].D,
+	}
+	keys = append(keys, generic)
+
+	var entries []BatchEntry
+	for i := 0; i < 16; i++ {
+		priv := keys[i%len(keys)]
+		message := []byte(fmt.Sprintf("message %d", i))
+		sig, err := Sign(rand.Reader, priv, message)
+		if err != nil {
+			t.Fatal("Sign:", err)
+		}
+		entries = append(entries, BatchEntry{
+			PublicKey: &priv.PublicKey,
+			Message:   message,
+			Signature: sig,
+		})
+	}
+	if got := VerifyBatch(entries); got != nil {
+		t.Fatalf("VerifyBatch() = %v, want nil", got)
+	}
+	if got := VerifyBatch(nil); got != nil {
+		t.Fatalf("VerifyBatch(nil) = %v, want nil", got)
+	}
+
+	// tamper entries
+	entries[1].Message = []byte("malicious")
+	entries[3].Signature = entries[3].Signature[1:]
+	entries[6].PublicKey = &keys[1].PublicKey
+	entries[7].Signature = append([]byte{}, entries[7].Signature...)
+	entries[7].Signature[0] ^= 1
+	entries[10].PublicKey = &PublicKey{
+		Curve: curve,
+		X:     keys[2].X,
+		Y:     new(big.Int).Add(keys[2].Y, one),
+	}
+	entries[12].PublicKey = nil
+	want := []int{1, 3, 6, 7, 10, 12}
+	if got := VerifyBatch(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("VerifyBatch() = %v, want %v", got, want)
+	}
+
+	// the results agree with Verify.
+	for i, entry := range entries {
+		if entry.PublicKey == nil {
+			continue
+		}
+		invalid := i == 1 || i == 3 || i == 6 || i == 7 || i == 10
+		if got := Verify(entry.PublicKey, entry.Message, entry.Signature); got == invalid {
+			t.Errorf("Verify(entries[%d]) = %v, want %v", i, got, !invalid)
+		}
+	}
+}
+
+func TestVerifyBatch_recoverable(t *testing.T) {
+	keys := make([]*PrivateKey, 3)
+	for i := range keys {
+		priv, err := GenerateKey(Curve(), rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey:", err)
+		}
+		keys[i] = priv
+	}
+
+	// the recovery ID is accepted on the generic curve as well.
+	generic := &PrivateKey{
+		PublicKey: PublicKey{
+			Curve: curve.Params(),
+			X:     keys[0].X,
+			Y:     keys[0].Y,
+		},
+		D: keys[0].D,
+	}
+
+	// mix signatures with and without the recovery ID.
+	entries := make([]BatchEntry, 20)
+	for i := range entries {
+		priv := keys[i%len(keys)]
+		if i%5 == 4 {
+			priv = generic
+		}
+		message := []byte(fmt.Sprintf("message %d", i))
+		opts := &SignerOpts{}
+		if i%2 == 0 {
+			opts.Format = RecoverableSignature
+		}
+		sig, err := priv.Sign(rand.Reader, message, opts)
+		if err != nil {
+			t.Fatal("PrivateKey.Sign:", err)
+		}
+		entries[i] = BatchEntry{
+			PublicKey: &priv.PublicKey,
+			Message:   message,
+			Signature: sig,
+		}
+	}
+	if got := VerifyBatch(entries); got != nil {
+		t.Fatalf("VerifyBatch() = %v, want nil", got)
+	}
+
+	// a wrong recovery ID fails the combined check only, as VerifyWithOpts
+	// ignores it.
+	entries[0].Signature = append([]byte{}, entries[0].Signature...)
+	entries[0].Signature[64] ^= 1
+	entries[4].Signature = append([]byte{}, entries[4].Signature...)
+	entries[4].Signature[64] = 4
+	entries[9].Message = []byte("malicious")
+	entries[14].Signature = append([]byte{}, entries[14].Signature...)
+	entries[14].Signature[64] ^= 1
+	entries[18].Signature = append([]byte{}, entries[18].Signature...)
+	entries[18].Signature[63] ^= 1
+	entries[19].Signature = append([]byte{}, entries[18].Signature[:64]...)
+	entries[19].Signature = append(entries[19].Signature, 4)
+	want := []int{4, 9, 18, 19}
+	if got := VerifyBatch(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("VerifyBatch() = %v, want %v", got, want)
+	}
+}
+
+func TestVerifyBatch_large(t *testing.T) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+
+	// more than batchSize recoverable signatures, and more than tableThreshold
+	// signatures in the form of r || s, which are verified with the table.
+	entries := make([]BatchEntry, batchSize+tableThreshold+8)
+	for i := range entries {
+		message := []byte(fmt.Sprintf("message %d", i))
+		opts := &SignerOpts{}
+		if i < batchSize+4 {
+			opts.Format = RecoverableSignature
+		}
+		sig, err := priv.Sign(rand.Reader, message, opts)
+		if err != nil {
+			t.Fatal("PrivateKey.Sign:", err)
+		}
+		entries[i] = BatchEntry{
+			PublicKey: &priv.PublicKey,
+			Message:   message,
+			Signature: sig,
+		}
+	}
+	if got := VerifyBatch(entries); got != nil {
+		t.Fatalf("VerifyBatch() = %v, want nil", got)
+	}
+
+	entries[5].Message = []byte("malicious")
+	entries[batchSize+1].Message = []byte("malicious")
+	entries[len(entries)-1].Message = []byte("malicious")
+	want := []int{5, batchSize + 1, len(entries) - 1}
+	if got := VerifyBatch(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("VerifyBatch() = %v, want %v", got, want)
+	}
+}
+
+func TestVerifyBatch_combined(t *testing.T) {
+	keys := make(map[*PrivateKey]*batchKey)
+	for i := 0; i < 2; i++ {
+		priv, err := GenerateKey(Curve(), rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey:", err)
+		}
+		z, err := priv.Digest()
+		if err != nil {
+			t.Fatal("PublicKey.Digest:", err)
+		}
+		point, err := curve.pointFromAffine(priv.X, priv.Y)
+		if err != nil {
+			t.Fatal("pointFromAffine:", err)
+		}
+		keys[priv] = &batchKey{z: z, point: point}
+	}
+
+	// newItems signs searchSize messages with the keys, where the signatures
+	// are recoverable if the bits of recoverable are set.
+	newItems := func(recoverable int) []*batchItem {
+		var items []*batchItem
+		for len(items) < searchSize {
+			for priv, key := range keys {
+				i := len(items)
+				message := []byte(fmt.Sprintf("message %d", i))
+				opts := &SignerOpts{}
+				if recoverable>>uint(i)&1 == 1 {
+					opts.Format = RecoverableSignature
+				}
+				sig, err := priv.Sign(rand.Reader, message, opts)
+				if err != nil {
+					t.Fatal("PrivateKey.Sign:", err)
+				}
+				h, err := priv.NewHash()
+				if err != nil {
+					t.Fatal("PublicKey.NewHash:", err)
+				}
+				h.Write(message)
+				item := newBatchItem(i, &priv.PublicKey, key, h.Sum(nil), sig)
+				if item == nil {
+					t.Fatalf("newBatchItem(%d) = nil", i)
+				}
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+
+	for _, recoverable := range []int{0, 0x55, 0xff} {
+		items := newItems(recoverable)
+		if !verifyBatch(items) {
+			t.Errorf("verifyBatch(%02x) = false, want true", recoverable)
+		}
+
+		// the sign of R matters only if it is known.
+		items[1].r.Negate(items[1].r)
+		if got, want := verifyBatch(items), !items[1].signed; got != want {
+			t.Errorf("verifyBatch(%02x) with -R = %v, want %v", recoverable, got, want)
+		}
+		items[1].r.Negate(items[1].r)
+
+		items[2].s.Add(items[2].s, sm2ec.NewScalar().One())
+		if verifyBatch(items) {
+			t.Errorf("verifyBatch(%02x) with a wrong s = true, want false", recoverable)
+		}
+	}
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		b.Fatal("GenerateKey:", err)
+	}
+	priv.ID = []byte("benchmark")
+	for _, format := range []SignatureFormat{RawSignature, RecoverableSignature} {
+		entries := make([]BatchEntry, 64)
+		for i := range entries {
+			message := []byte(fmt.Sprintf("message %d", i))
+			sig, err := priv.Sign(rand.Reader, message, &SignerOpts{Format: format})
+			if err != nil {
+				b.Fatal("PrivateKey.Sign:", err)
+			}
+			entries[i] = BatchEntry{
+				PublicKey: &priv.PublicKey,
+				Message:   message,
+				Signature: sig,
+			}
+		}
+		name := "raw"
+		if format == RecoverableSignature {
+			name = "recoverable"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if failed := VerifyBatch(entries); failed != nil {
+					b.Fatal("VerifyBatch failed:", failed)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package sm2
+
+import (
+	"errors"
+	"io"
+	"math/big"
+)
+
+// Blind signing lets a requester obtain an ordinary SM2 signature, which is
+// verified by Verify, on a message the signer never sees. The signer cannot
+// link the signature to the session in which it was issued.
+//
+// With Q = P + G = [1 + d]G, a signature (r', s') satisfies
+// [s']Q + [r']P = (x1, y1) where r' = (e + x1) mod n, and the signer computes
+// s = (1 + d)^-1 * (k + r) - r, which satisfies [s]Q = K - [r]P for K = [k]G.
+// The requester blinds the commitment as R' = [a]K + [b]Q with random a and
+// b, and the challenge as r = r' / a, so that s' = a * s + b.
+//
+//	signer:    NewBlindSignSession -> K = [k]G                   -> requester
+//	requester: Blind               -> r = (e + x(R')) / a mod n  -> signer
+//	signer:    BlindSignSession.Sign -> s                         -> requester
+//	requester: Blinder.Unblind     -> r' || s'
+//
+// Like other blind signatures of the Schnorr family, running many sessions
+// concurrently with the same key allows forging more signatures than issued
+// by the ROS attack. Signers should limit the number of open sessions.
+
+// BlindSignSession is the state of the signer for issuing a single blind
+// signature.
+type BlindSignSession struct {
+	priv *PrivateKey
+	k    *big.Int
+}
+
+// NewBlindSignSession validates the private key, generates the nonce k, and
+// returns the commitment K = [k]G to be sent to the requester.
+func NewBlindSignSession(rand io.Reader, priv *PrivateKey) (*BlindSignSession, *PublicKey, error) {
+	if err := priv.Validate(); err != nil {
+		return nil, nil, err
+	}
+	k, err := randScalar(rand, priv.Curve.Params())
+	if err != nil {
+		return nil, nil, err
+	}
+	x, y := priv.Curve.ScalarBaseMult(k.Bytes())
+	sess := &BlindSignSession{
+		priv: priv,
+		k:    k,
+	}
+	return sess, &PublicKey{Curve: priv.Curve, X: x, Y: y}, nil
+}
+
+// Sign computes s = ((1 + d)^-1 * (k + r) - r) mod n for the blinded
+// challenge r sent by the requester. A session signs at most once, since
+// signing two challenges with the same k reveals the private key.
+func (sess *BlindSignSession) Sign(r *big.Int) (*big.Int, error) {
+	if sess.k == nil {
+		return nil, errors.New("sm2: blind signing session already used")
+	}
+	k := sess.k
+	sess.k = nil // never reuse k
+
+	n := sess.priv.Curve.Params().N
+	if r == nil || r.Sign() <= 0 || r.Cmp(n) >= 0 {
+		return nil, errors.New("sm2: invalid blinded challenge")
+	}
+	dInv := new(big.Int).Add(one, sess.priv.D)
+	dInv.ModInverse(dInv, n)
+	s := new(big.Int).Add(k, r)
+	s.Mul(s, dInv)
+	s.Sub(s, r)
+	s.Mod(s, n)
+	if s.Sign() == 0 {
+		return nil, errors.New("sm2: blind signing failed")
+	}
+	return s, nil
+}
+
+// Blinder is the state of the requester for obtaining a single blind
+// signature.
+type Blinder struct {
+	pub    *PublicKey
+	digest []byte
+	r      *big.Int // r' of the unblinded signature
+	a, b   *big.Int // blinding factors
+}
+
+// Blind computes the hash value e of the message with the ID of the public key
+// of the signer, blinds the commitment K sent by the signer, and returns the
+// blinded challenge r to be sent to the signer.
+func Blind(rand io.Reader, pub *PublicKey, message []byte, commitment *PublicKey) (*Blinder, *big.Int, error) {
+	if err := pub.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if commitment == nil || commitment.Curve != pub.Curve {
+		return nil, nil, errors.New("sm2: invalid blind signing commitment")
+	}
+	if err := commitment.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	// compute hash value e
+	h, err := pub.NewHash()
+	if err != nil {
+		return nil, nil, err
+	}
+	h.Write(message) // write on sm3 never returns error
+	digest := h.Sum(nil)
+	e := new(big.Int).SetBytes(digest)
+
+	c := pub.Curve
+	params := c.Params()
+	qx, qy := c.Add(pub.X, pub.Y, params.Gx, params.Gy)
+	for {
+		a, err := randScalar(rand, params)
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := randScalar(rand, params)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// compute (x1, y1) = [a]K + [b]Q, and r' = (e + x1) mod n
+		x1, y1 := c.ScalarMult(commitment.X, commitment.Y, a.Bytes())
+		x2, y2 := c.ScalarMult(qx, qy, b.Bytes())
+		x1, y1 = c.Add(x1, y1, x2, y2)
+		if x1.Sign() == 0 && y1.Sign() == 0 {
+			continue
+		}
+		r := new(big.Int).Add(e, x1)
+		r.Mod(r, params.N)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		// blind r = r' / a mod n
+		blinded := new(big.Int).ModInverse(a, params.N)
+		blinded.Mul(blinded, r)
+		blinded.Mod(blinded, params.N)
+		return &Blinder{
+			pub:    pub,
+			digest: digest,
+			r:      r,
+			a:      a,
+			b:      b,
+		}, blinded, nil
+	}
+}
+
+// Unblind computes the signature in the form of r' || s', where
+// s' = (a * s + b) mod n, with s sent by the signer. The signature is
+// verified with the public key before it is returned, so that a misbehaving
+// signer is detected.
+func (bl *Blinder) Unblind(s *big.Int) ([]byte, error) {
+	params := bl.pub.Curve.Params()
+	if s == nil || s.Sign() <= 0 || s.Cmp(params.N) >= 0 {
+		return nil, errors.New("sm2: invalid blind signature")
+	}
+	s1 := new(big.Int).Mul(bl.a, s)
+	s1.Add(s1, bl.b)
+	s1.Mod(s1, params.N)
+
+	n := (params.BitSize + 7) / 8
+	sig := make([]byte, 2*n)
+	bl.r.FillBytes(sig[:n])
+	s1.FillBytes(sig[n:])
+	if !verifyDigest(bl.pub, nil, bl.digest, sig) {
+		return nil, errors.New("sm2: invalid blind signature")
+	}
+	return sig, nil
+}
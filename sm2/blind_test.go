@@ -0,0 +1,116 @@
+package sm2
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestBlindSign(t *testing.T) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	priv.ID = []byte("issuer")
+	pub := &priv.PublicKey
+
+	for i := 0; i < 8; i++ {
+		message := []byte("ballot")
+		sess, commitment, err := NewBlindSignSession(rand.Reader, priv)
+		if err != nil {
+			t.Fatal("NewBlindSignSession:", err)
+		}
+		blinder, r, err := Blind(rand.Reader, pub, message, commitment)
+		if err != nil {
+			t.Fatal("Blind:", err)
+		}
+		s, err := sess.Sign(r)
+		if err != nil {
+			t.Fatal("BlindSignSession.Sign:", err)
+		}
+		sig, err := blinder.Unblind(s)
+		if err != nil {
+			t.Fatal("Blinder.Unblind:", err)
+		}
+		if !Verify(pub, message, sig) {
+			t.Fatalf("Blinder.Unblind() = %x, which cannot be verified", sig)
+		}
+
+		// the signer sees neither r' nor s'.
+		if new(big.Int).SetBytes(sig[:32]).Cmp(r) == 0 || new(big.Int).SetBytes(sig[32:]).Cmp(s) == 0 {
+			t.Fatalf("Blinder.Unblind() = %x, which is not blinded", sig)
+		}
+		if _, err := sess.Sign(r); err == nil {
+			t.Fatal("BlindSignSession.Sign() signed twice")
+		}
+	}
+}
+
+func TestBlindSign_invalid(t *testing.T) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	pub := &priv.PublicKey
+	message := []byte("ballot")
+
+	t.Run("tampered signature", func(t *testing.T) {
+		sess, commitment, err := NewBlindSignSession(rand.Reader, priv)
+		if err != nil {
+			t.Fatal("NewBlindSignSession:", err)
+		}
+		blinder, r, err := Blind(rand.Reader, pub, message, commitment)
+		if err != nil {
+			t.Fatal("Blind:", err)
+		}
+		s, err := sess.Sign(r)
+		if err != nil {
+			t.Fatal("BlindSignSession.Sign:", err)
+		}
+		s.Add(s, one)
+		if sig, err := blinder.Unblind(s); err == nil {
+			t.Errorf("Blinder.Unblind() = %x, want error", sig)
+		}
+	})
+
+	t.Run("other signer", func(t *testing.T) {
+		other, err := GenerateKey(Curve(), rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey:", err)
+		}
+		sess, commitment, err := NewBlindSignSession(rand.Reader, other)
+		if err != nil {
+			t.Fatal("NewBlindSignSession:", err)
+		}
+		blinder, r, err := Blind(rand.Reader, pub, message, commitment)
+		if err != nil {
+			t.Fatal("Blind:", err)
+		}
+		s, err := sess.Sign(r)
+		if err != nil {
+			t.Fatal("BlindSignSession.Sign:", err)
+		}
+		if sig, err := blinder.Unblind(s); err == nil {
+			t.Errorf("Blinder.Unblind() = %x, want error", sig)
+		}
+	})
+
+	t.Run("invalid commitment", func(t *testing.T) {
+		commitment := &PublicKey{Curve: curve, X: curve.Gx, Y: new(big.Int).Add(curve.Gy, one)}
+		if _, _, err := Blind(rand.Reader, pub, message, commitment); err == nil {
+			t.Error("Blind() accepted a commitment not on the curve")
+		}
+	})
+
+	t.Run("invalid challenge", func(t *testing.T) {
+		for _, r := range []*big.Int{nil, new(big.Int), new(big.Int).Set(curve.N)} {
+			sess, _, err := NewBlindSignSession(rand.Reader, priv)
+			if err != nil {
+				t.Fatal("NewBlindSignSession:", err)
+			}
+			if _, err := sess.Sign(r); err == nil {
+				t.Errorf("BlindSignSession.Sign(%v) succeeded, want error", r)
+			}
+		}
+	})
+}
@@ -0,0 +1,265 @@
+package sm2
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm3"
+)
+
+// Two-party co-signing splits an SM2 private key d between a client, such as
+// a mobile device, and a server, so that neither party ever holds d. The
+// shares d1 and d2 satisfy (1 + d)^-1 = d1 * d2 mod n, and the signatures are
+// ordinary SM2 signatures, which can be verified by Verify.
+//
+// Key generation:
+//
+//	client: GenerateCoSignClientKey  -> P1 = [d1^-1]G          -> server
+//	server: GenerateCoSignServerKey  -> P = [d2^-1]P1 - G      -> client
+//	client: CoSignClientKey.SetPublicKey(P)
+//
+// Signing:
+//
+//	client: CoSignClientKey.NewSignSession -> e, Q1 = [k1]G   -> server
+//	server: CoSignServerKey.Sign           -> r, s2, s3       -> client
+//	client: CoSignSession.Finish           -> r || s
+
+// CoSignClientKey is the share of a split private key held by the client.
+type CoSignClientKey struct {
+	// PublicKey is the public key of the split private key, which is set by
+	// SetPublicKey. Its ID is used in signing.
+	PublicKey
+
+	// D is the share d1 of the client in [1, n-1].
+	D *big.Int
+}
+
+// CoSignServerKey is the share of a split private key held by the server.
+type CoSignServerKey struct {
+	// PublicKey is the public key of the split private key.
+	PublicKey
+
+	// D is the share d2 of the server in [1, n-1].
+	D *big.Int
+}
+
+// CoSignRequest is sent from the client to the server to start signing.
+type CoSignRequest struct {
+	// Digest is the hash value e of the message, computed with Z of the
+	// public key.
+	Digest []byte
+
+	// Q1 = [k1]G is the ephemeral public key of the client.
+	Q1 *PublicKey
+}
+
+// CoSignResponse is sent from the server to the client to complete signing.
+type CoSignResponse struct {
+	R  *big.Int // r = (e + x1) mod n, where (x1, y1) = [k3]Q1 + [k2]G
+	S2 *big.Int // s2 = d2 * k3 mod n
+	S3 *big.Int // s3 = d2 * (r + k2) mod n
+}
+
+// GenerateCoSignClientKey generates the share d1 of the client, and returns
+// P1 = [d1^-1]G to be sent to the server.
+func GenerateCoSignClientKey(c elliptic.Curve, rand io.Reader) (*CoSignClientKey, *PublicKey, error) {
+	params := c.Params()
+	d1, err := randScalar(rand, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	d1Inv := new(big.Int).ModInverse(d1, params.N)
+	x, y := c.ScalarBaseMult(d1Inv.Bytes())
+	key := &CoSignClientKey{
+		PublicKey: PublicKey{Curve: c},
+		D:         d1,
+	}
+	return key, &PublicKey{Curve: c, X: x, Y: y}, nil
+}
+
+// GenerateCoSignServerKey validates P1 sent by the client, generates the
+// share d2 of the server, and computes the public key P = [d2^-1]P1 - G to be
+// returned to the client.
+func GenerateCoSignServerKey(rand io.Reader, p1 *PublicKey) (*CoSignServerKey, error) {
+	if err := p1.Validate(); err != nil {
+		return nil, err
+	}
+	c := p1.Curve
+	params := c.Params()
+	negGy := new(big.Int).Sub(params.P, params.Gy)
+	for {
+		d2, err := randScalar(rand, params)
+		if err != nil {
+			return nil, err
+		}
+		d2Inv := new(big.Int).ModInverse(d2, params.N)
+		x, y := c.ScalarMult(p1.X, p1.Y, d2Inv.Bytes())
+		x, y = c.Add(x, y, params.Gx, negGy)
+		if x.Sign() == 0 && y.Sign() == 0 {
+			continue // d1 * d2 = 1 implies d = 0
+		}
+		return &CoSignServerKey{
+			PublicKey: PublicKey{Curve: c, X: x, Y: y},
+			D:         d2,
+		}, nil
+	}
+}
+
+// SetPublicKey validates the public key P returned by the server, and sets it
+// as the public key of the client, keeping the ID of the client.
+// A public key not matching the share of the server is detected when a
+// signature is finished.
+func (key *CoSignClientKey) SetPublicKey(pub *PublicKey) error {
+	if pub == nil || pub.Curve != key.Curve {
+		return errors.New("sm2: invalid co-signing public key")
+	}
+	if err := pub.Validate(); err != nil {
+		return err
+	}
+	key.X = pub.X
+	key.Y = pub.Y
+	return nil
+}
+
+// CoSignSession holds the ephemeral state of the client for a single
+// signature.
+type CoSignSession struct {
+	key    *CoSignClientKey
+	digest []byte
+	k1     *big.Int
+}
+
+// NewSignSession computes the hash value e of the message with the ID of the
+// public key, generates the ephemeral key k1, and returns the request to be
+// sent to the server. A session must be finished at most once.
+func (key *CoSignClientKey) NewSignSession(rand io.Reader, message []byte) (*CoSignSession, *CoSignRequest, error) {
+	if err := key.PublicKey.Validate(); err != nil {
+		return nil, nil, err
+	}
+	params := key.Curve.Params()
+	if key.D == nil || key.D.Sign() <= 0 || key.D.Cmp(params.N) >= 0 {
+		return nil, nil, errors.New("sm2: invalid co-signing key share")
+	}
+
+	// A1, A2: compute hash value e
+	h, err := key.NewHash()
+	if err != nil {
+		return nil, nil, err
+	}
+	h.Write(message) // write on sm3 never returns error
+	digest := h.Sum(nil)
+
+	k1, err := randScalar(rand, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	x, y := key.Curve.ScalarBaseMult(k1.Bytes())
+	sess := &CoSignSession{
+		key:    key,
+		digest: digest,
+		k1:     k1,
+	}
+	req := &CoSignRequest{
+		Digest: digest,
+		Q1:     &PublicKey{Curve: key.Curve, X: x, Y: y},
+	}
+	return sess, req, nil
+}
+
+// Sign validates the request of the client, and computes the partial
+// signature with the share of the server.
+func (key *CoSignServerKey) Sign(rand io.Reader, req *CoSignRequest) (*CoSignResponse, error) {
+	params := key.Curve.Params()
+	if key.D == nil || key.D.Sign() <= 0 || key.D.Cmp(params.N) >= 0 {
+		return nil, errors.New("sm2: invalid co-signing key share")
+	}
+	if req == nil || len(req.Digest) != sm3.Size {
+		return nil, errors.New("sm2: invalid co-signing request")
+	}
+	if req.Q1 == nil || req.Q1.Curve != key.Curve {
+		return nil, errors.New("sm2: invalid co-signing request")
+	}
+	if err := req.Q1.Validate(); err != nil {
+		return nil, err
+	}
+
+	e := new(big.Int).SetBytes(req.Digest)
+	for {
+		k2, err := randScalar(rand, params)
+		if err != nil {
+			return nil, err
+		}
+		k3, err := randScalar(rand, params)
+		if err != nil {
+			return nil, err
+		}
+
+		// compute (x1, y1) = [k3]Q1 + [k2]G, which is kG with k = k1 * k3 + k2
+		x1, y1 := key.Curve.ScalarMult(req.Q1.X, req.Q1.Y, k3.Bytes())
+		x2, y2 := key.Curve.ScalarBaseMult(k2.Bytes())
+		x1, _ = key.Curve.Add(x1, y1, x2, y2)
+
+		// compute r = (e + x1) mod n
+		r := new(big.Int).Add(e, x1)
+		r.Mod(r, params.N)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		// compute s2 = d2 * k3 mod n, and s3 = d2 * (r + k2) mod n
+		s2 := new(big.Int).Mul(key.D, k3)
+		s2.Mod(s2, params.N)
+		s3 := new(big.Int).Add(r, k2)
+		s3.Mul(s3, key.D)
+		s3.Mod(s3, params.N)
+		if s3.Sign() == 0 {
+			continue
+		}
+		return &CoSignResponse{R: r, S2: s2, S3: s3}, nil
+	}
+}
+
+// Finish validates the response of the server, and computes the signature in
+// the form of r || s, where s = (d1 * k1 * s2 + d1 * s3 - r) mod n.
+// The signature is verified with the public key before it is returned, so
+// that a misbehaving server is detected.
+func (sess *CoSignSession) Finish(resp *CoSignResponse) ([]byte, error) {
+	if sess.k1 == nil {
+		return nil, errors.New("sm2: co-signing session already finished")
+	}
+	k1 := sess.k1
+	sess.k1 = nil // never reuse k1
+
+	key := sess.key
+	params := key.Curve.Params()
+	if resp == nil {
+		return nil, errors.New("sm2: invalid co-signing response")
+	}
+	for _, v := range []*big.Int{resp.R, resp.S2, resp.S3} {
+		if v == nil || v.Sign() <= 0 || v.Cmp(params.N) >= 0 {
+			return nil, errors.New("sm2: invalid co-signing response")
+		}
+	}
+
+	// compute s = (d1 * k1 * s2 + d1 * s3 - r) mod n
+	s := new(big.Int).Mul(k1, resp.S2)
+	s.Add(s, resp.S3)
+	s.Mul(s, key.D)
+	s.Sub(s, resp.R)
+	s.Mod(s, params.N)
+	t := new(big.Int).Add(s, resp.R)
+	if s.Sign() == 0 || t.Cmp(params.N) == 0 {
+		return nil, errors.New("sm2: co-signing failed")
+	}
+
+	n := (params.BitSize + 7) / 8
+	sig := make([]byte, 2*n)
+	resp.R.FillBytes(sig[:n])
+	s.FillBytes(sig[n:])
+	if !verifyDigest(&key.PublicKey, nil, sess.digest, sig) {
+		return nil, errors.New("sm2: invalid co-signing response")
+	}
+	return sig, nil
+}
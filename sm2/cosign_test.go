@@ -0,0 +1,147 @@
+package sm2
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+// generateCoSignKeys runs the key generation of two-party co-signing.
+func generateCoSignKeys(t *testing.T) (*CoSignClientKey, *CoSignServerKey) {
+	t.Helper()
+	client, p1, err := GenerateCoSignClientKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateCoSignClientKey:", err)
+	}
+	client.ID = []byte("client")
+	server, err := GenerateCoSignServerKey(rand.Reader, p1)
+	if err != nil {
+		t.Fatal("GenerateCoSignServerKey:", err)
+	}
+	if err := client.SetPublicKey(&server.PublicKey); err != nil {
+		t.Fatal("CoSignClientKey.SetPublicKey:", err)
+	}
+	server.ID = client.ID
+	return client, server
+}
+
+func TestCoSign(t *testing.T) {
+	client, server := generateCoSignKeys(t)
+
+	// the shares satisfy (1 + d)^-1 = d1 * d2 mod n.
+	n := curve.Params().N
+	d := new(big.Int).Mul(client.D, server.D)
+	d.ModInverse(d, n)
+	d.Sub(d, one)
+	priv := &PrivateKey{PublicKey: client.PublicKey, D: d}
+	if err := priv.Validate(); err != nil {
+		t.Fatal("PrivateKey.Validate:", err)
+	}
+
+	for i := 0; i < 8; i++ {
+		message := []byte("message")
+		sess, req, err := client.NewSignSession(rand.Reader, message)
+		if err != nil {
+			t.Fatal("CoSignClientKey.NewSignSession:", err)
+		}
+		resp, err := server.Sign(rand.Reader, req)
+		if err != nil {
+			t.Fatal("CoSignServerKey.Sign:", err)
+		}
+		sig, err := sess.Finish(resp)
+		if err != nil {
+			t.Fatal("CoSignSession.Finish:", err)
+		}
+		if !Verify(&client.PublicKey, message, sig) {
+			t.Fatalf("CoSignSession.Finish() = %x, which cannot be verified", sig)
+		}
+		if _, err := sess.Finish(resp); err == nil {
+			t.Fatal("CoSignSession.Finish() finished twice")
+		}
+	}
+}
+
+func TestCoSign_invalid(t *testing.T) {
+	client, server := generateCoSignKeys(t)
+	_, other := generateCoSignKeys(t)
+	message := []byte("message")
+
+	tests := []struct {
+		name   string
+		server *CoSignServerKey
+		modify func(*CoSignResponse)
+	}{
+		{
+			name:   "other server",
+			server: other,
+		},
+		{
+			name:   "tampered r",
+			server: server,
+			modify: func(resp *CoSignResponse) {
+				resp.R.Add(resp.R, one)
+			},
+		},
+		{
+			name:   "tampered s3",
+			server: server,
+			modify: func(resp *CoSignResponse) {
+				resp.S3.Add(resp.S3, one)
+			},
+		},
+		{
+			name:   "zero s2",
+			server: server,
+			modify: func(resp *CoSignResponse) {
+				resp.S2.SetInt64(0)
+			},
+		},
+		{
+			name:   "s3 out of range",
+			server: server,
+			modify: func(resp *CoSignResponse) {
+				resp.S3.Add(resp.S3, curve.Params().N)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess, req, err := client.NewSignSession(rand.Reader, message)
+			if err != nil {
+				t.Fatal("CoSignClientKey.NewSignSession:", err)
+			}
+			resp, err := tt.server.Sign(rand.Reader, req)
+			if err != nil {
+				t.Fatal("CoSignServerKey.Sign:", err)
+			}
+			if tt.modify != nil {
+				tt.modify(resp)
+			}
+			if sig, err := sess.Finish(resp); err == nil {
+				t.Errorf("CoSignSession.Finish() = %x, want error", sig)
+			}
+		})
+	}
+
+	t.Run("invalid request", func(t *testing.T) {
+		_, req, err := client.NewSignSession(rand.Reader, message)
+		if err != nil {
+			t.Fatal("CoSignClientKey.NewSignSession:", err)
+		}
+		req.Q1.Y = new(big.Int).Add(req.Q1.Y, one)
+		if _, err := server.Sign(rand.Reader, req); err == nil {
+			t.Error("CoSignServerKey.Sign() accepted Q1 not on the curve")
+		}
+		req.Q1 = nil
+		if _, err := server.Sign(rand.Reader, req); err == nil {
+			t.Error("CoSignServerKey.Sign() accepted nil Q1")
+		}
+	})
+
+	t.Run("invalid key share", func(t *testing.T) {
+		p1 := &PublicKey{Curve: curve, X: curve.Gx, Y: new(big.Int).Add(curve.Gy, one)}
+		if _, err := GenerateCoSignServerKey(rand.Reader, p1); err == nil {
+			t.Error("GenerateCoSignServerKey() accepted P1 not on the curve")
+		}
+	})
+}
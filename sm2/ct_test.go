@@ -0,0 +1,98 @@
+package sm2
+
+import (
+	"testing"
+
+	"github.com/need-being/gmcrypto/sm2/internal/ctcheck"
+)
+
+// constantTimeFuncs lists the functions which handle the private key d and the
+// nonce k on the SM2 curve. The generic implementation on other curves uses
+// math/big, and is not constant time.
+var constantTimeFuncs = map[string]ctcheck.Spec{
+	// curve.go
+	"privateScalar": {
+		// whether d is in range is revealed by the returned error anyway.
+		Declassify: []string{"Sign", "BitLen"},
+	},
+	"sm2Curve.sign": {
+		Public: []string{"c", "digest", "nextK"},
+		// rejected candidates of k are discarded.
+		Declassify: []string{"IsZero"},
+	},
+
+	// sm2.go
+	"newSigningKey": {
+		Public:       []string{"priv"},
+		SecretFields: []string{"D"},
+	},
+	"signDigest": {
+		Public:       []string{"rand", "key", "digest", "mode"},
+		SecretFields: []string{"D", "d", "dInv"},
+		Pure:         []string{"sign"},
+	},
+
+	"randScalarBytes": {
+		Public: []string{"rand", "c"},
+	},
+
+	// exchange.go
+	"newKeyExchange": {
+		Public: []string{"rand", "priv", "peer"},
+	},
+	"sm2Curve.sharedPoint": {
+		Public: []string{"c", "r1", "peer", "r2"},
+		// the shared point at infinity is revealed by the returned error.
+		Declassify: []string{"IsInfinity"},
+	},
+
+	// nonce.go
+	"nonceGenerator": {
+		Public:       []string{"rand", "priv", "e", "mode"},
+		SecretFields: []string{"D"},
+		Pure:         []string{"newRFC6979"},
+	},
+	"newRFC6979": {
+		Public:       []string{"newHash", "q", "h1"},
+		SecretFields: []string{"k", "v"},
+		Pure:         []string{"mac"},
+	},
+	"rfc6979.next": {
+		Public:       []string{"g"},
+		SecretFields: []string{"k", "v"},
+		Pure:         []string{"mac", "bits2int", "inRange"},
+		// rejected candidates of k are discarded.
+		Declassify: []string{"inRange"},
+	},
+	"rfc6979.mac": {
+		Public:       []string{"g"},
+		SecretFields: []string{"k", "v"},
+	},
+	"rfc6979.bits2int": {
+		Public: []string{"g"},
+	},
+	"inRange": {
+		Public: []string{"q"},
+	},
+
+	// validate.go
+	"sm2Curve.validatePrivateKey": {
+		Public:       []string{"c", "priv"},
+		SecretFields: []string{"D"},
+		// the validity of d is revealed by the returned error anyway, and dG
+		// is compared with the public key.
+		Declassify: []string{"IsZero", "pointToAffine"},
+	},
+}
+
+// TestConstantTime checks that the functions handling secret values have
+// neither branches nor memory accesses depending on secret values.
+func TestConstantTime(t *testing.T) {
+	msgs, err := ctcheck.CheckFiles([]string{"curve.go", "sm2.go", "exchange.go", "nonce.go", "validate.go"}, constantTimeFuncs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range msgs {
+		t.Error(msg)
+	}
+}
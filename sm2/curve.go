@@ -2,12 +2,23 @@ package sm2
 
 import (
 	"crypto/elliptic"
+	"crypto/subtle"
+	"errors"
 	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2/internal/sm2ec"
 )
 
+// sm2Curve is a dedicated implementation of the SM2 curve backed by
+// sm2ec, which is much faster than the generic implementation of
+// elliptic.CurveParams.
+type sm2Curve struct {
+	*elliptic.CurveParams
+}
+
 // curve represents an SM2 curve.
 // The curve for SM2 is also a short-form Weierstrass curve with a=-3.
-var curve = &elliptic.CurveParams{
+var curve = &sm2Curve{&elliptic.CurveParams{
 	P: new(big.Int).SetBytes([]byte{
 		0xff, 0xff, 0xff, 0xfe, 0xff, 0xff, 0xff, 0xff,
 		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
@@ -40,9 +51,241 @@ var curve = &elliptic.CurveParams{
 	}),
 	BitSize: 256,
 	Name:    "SM2", // name from ISO 14888-3
-}
+}}
 
 // Curve returns an elliptic curve for SM2.
 func Curve() elliptic.Curve {
 	return curve
 }
+
+func (c *sm2Curve) Params() *elliptic.CurveParams {
+	return c.CurveParams
+}
+
+func (c *sm2Curve) IsOnCurve(x, y *big.Int) bool {
+	// IsOnCurve is documented to reject (0, 0), the conventional point at
+	// infinity, which however is accepted by pointFromAffine.
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return false
+	}
+	_, err := c.pointFromAffine(x, y)
+	return err == nil
+}
+
+func (c *sm2Curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	p1, err := c.pointFromAffine(x1, y1)
+	if err != nil {
+		panic("sm2: Add was called on an invalid point")
+	}
+	p2, err := c.pointFromAffine(x2, y2)
+	if err != nil {
+		panic("sm2: Add was called on an invalid point")
+	}
+	return c.pointToAffine(p1.Add(p1, p2))
+}
+
+func (c *sm2Curve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	p, err := c.pointFromAffine(x1, y1)
+	if err != nil {
+		panic("sm2: Double was called on an invalid point")
+	}
+	return c.pointToAffine(p.Double(p))
+}
+
+func (c *sm2Curve) ScalarMult(Bx, By *big.Int, scalar []byte) (*big.Int, *big.Int) {
+	p, err := c.pointFromAffine(Bx, By)
+	if err != nil {
+		panic("sm2: ScalarMult was called on an invalid point")
+	}
+	p, err = p.ScalarMult(p, c.normalizeScalar(scalar))
+	if err != nil {
+		panic("sm2: ScalarMult was called with an invalid scalar")
+	}
+	return c.pointToAffine(p)
+}
+
+func (c *sm2Curve) ScalarBaseMult(scalar []byte) (*big.Int, *big.Int) {
+	p, err := sm2ec.NewPoint().ScalarBaseMult(c.normalizeScalar(scalar))
+	if err != nil {
+		panic("sm2: ScalarBaseMult was called with an invalid scalar")
+	}
+	return c.pointToAffine(p)
+}
+
+// normalizeScalar brings the scalar within the byte size of the order of the
+// curve, as expected by sm2ec.
+func (c *sm2Curve) normalizeScalar(scalar []byte) []byte {
+	byteSize := (c.N.BitLen() + 7) / 8
+	if len(scalar) == byteSize {
+		return scalar
+	}
+	s := new(big.Int).SetBytes(scalar)
+	if len(scalar) > byteSize {
+		s.Mod(s, c.N)
+	}
+	out := make([]byte, byteSize)
+	return s.FillBytes(out)
+}
+
+// privateScalar loads a private key d in [1, n-1] into a fixed-width scalar.
+// The key is filled into 32 bytes as is, so that it is never encoded in a
+// length depending on its value.
+func privateScalar(d *big.Int) (*sm2ec.Scalar, error) {
+	if d.Sign() <= 0 || d.BitLen() > 256 {
+		return nil, errors.New("sm2: private key out of range")
+	}
+	s, err := sm2ec.NewScalar().SetBytes(d.FillBytes(make([]byte, 32)))
+	if err != nil {
+		return nil, errors.New("sm2: private key out of range")
+	}
+	return s, nil
+}
+
+// pointFromAffine converts affine coordinates to a point on the curve.
+// (0, 0) is by convention the point at infinity.
+func (c *sm2Curve) pointFromAffine(x, y *big.Int) (*sm2ec.Point, error) {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return sm2ec.NewPoint(), nil
+	}
+
+	// reject values that would not get correctly encoded.
+	if x.Sign() < 0 || y.Sign() < 0 {
+		return nil, errors.New("negative coordinate")
+	}
+	if x.BitLen() > c.BitSize || y.BitLen() > c.BitSize {
+		return nil, errors.New("overflowing coordinate")
+	}
+
+	// encode the coordinates and let SetBytes reject invalid points.
+	byteLen := (c.BitSize + 7) / 8
+	buf := make([]byte, 1+2*byteLen)
+	buf[0] = 4 // uncompressed point
+	x.FillBytes(buf[1 : 1+byteLen])
+	y.FillBytes(buf[1+byteLen:])
+	return sm2ec.NewPoint().SetBytes(buf)
+}
+
+// pointToAffine converts a point to affine coordinates, where the point at
+// infinity is represented as (0, 0).
+func (c *sm2Curve) pointToAffine(p *sm2ec.Point) (x, y *big.Int) {
+	out := p.Bytes()
+	if len(out) == 1 && out[0] == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	byteLen := (c.BitSize + 7) / 8
+	x = new(big.Int).SetBytes(out[1 : 1+byteLen])
+	y = new(big.Int).SetBytes(out[1+byteLen:])
+	return x, y
+}
+
+// verify reports whether (e + x1) mod n = r, where (x1, y1) = [s]G + [t]P
+// and P = (x, y), as specified in GB/T 32918.2-2016 7.1 B6 and B7.
+// The point is computed by a variable-time double scalar multiplication, and
+// is never converted to affine coordinates. r, s and t must be in [1, n-1].
+func (c *sm2Curve) verify(x, y, e, r, s, t *big.Int) bool {
+	q, err := c.pointFromAffine(x, y)
+	if err != nil {
+		return false
+	}
+	p, err := sm2ec.NewPoint().VarTimeDoubleScalarBaseMult(c.scalarBytes(t), q, c.scalarBytes(s))
+	if err != nil {
+		return false
+	}
+	return c.checkR(p, e, r)
+}
+
+// verifyWithTable is the same as verify, where table holds the multiples of
+// the public key.
+func (c *sm2Curve) verifyWithTable(table *sm2ec.FixedBaseTable, e, r, s, t *big.Int) bool {
+	p, err := sm2ec.NewPoint().VarTimeDoubleScalarFixedBaseMult(c.scalarBytes(t), table, c.scalarBytes(s))
+	if err != nil {
+		return false
+	}
+	return c.checkR(p, e, r)
+}
+
+// scalarBytes encodes a scalar in [0, n-1] to the byte size of n.
+func (c *sm2Curve) scalarBytes(k *big.Int) []byte {
+	return k.FillBytes(make([]byte, (c.N.BitLen()+7)/8))
+}
+
+// checkR reports whether (e + x1) mod n = r, where x1 is the affine
+// x-coordinate of p.
+func (c *sm2Curve) checkR(p *sm2ec.Point, e, r *big.Int) bool {
+	// x1 is in [0, p-1] and p < 2n, thus x1 is either (r - e) mod n or
+	// (r - e) mod n + n.
+	buf := make([]byte, (c.BitSize+7)/8)
+	x1 := new(big.Int).Sub(r, e)
+	x1.Mod(x1, c.N)
+	if p.HasAffineX(x1.FillBytes(buf)) == 1 {
+		return true
+	}
+	x1.Add(x1, c.N)
+	return x1.Cmp(c.P) < 0 && p.HasAffineX(x1.FillBytes(buf)) == 1
+}
+
+// sign computes the signature r || s || v of the hash value e with the private
+// key d and dInv = (1 + d)^-1 mod n, as specified in GB/T 32918.2-2016 6.1 A4
+// to A7, where nextK generates the candidates of k, and v is the recovery ID.
+// The scalar arithmetic and the scalar multiplication run in constant time,
+// and only whether a candidate of k is rejected depends on secret values.
+func (c *sm2Curve) sign(d, dInv *sm2ec.Scalar, digest []byte, nextK func() ([]byte, error)) ([]byte, error) {
+	e, err := sm2ec.NewScalar().SetReducedBytes(digest)
+	if err != nil {
+		return nil, errors.New("sm2: invalid digest length")
+	}
+	k := sm2ec.NewScalar()
+	x := sm2ec.NewScalar()
+	r := sm2ec.NewScalar()
+	s := sm2ec.NewScalar()
+	t := sm2ec.NewScalar()
+	p := sm2ec.NewPoint()
+	byteLen := (c.BitSize + 7) / 8
+	var out []byte
+	for {
+		kBytes, err := nextK()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := k.SetBytes(kBytes); err != nil {
+			return nil, err
+		}
+
+		// A4: compute (x1, y1) = kG where y1 is dropped
+		if _, err := p.ScalarBaseMult(kBytes); err != nil {
+			return nil, err
+		}
+		out = p.Bytes()
+		if len(out) != 1+2*byteLen {
+			return nil, errors.New("sm2: invalid nonce") // k is never zero
+		}
+		if _, err := x.SetReducedBytes(out[1 : 1+byteLen]); err != nil {
+			return nil, err
+		}
+
+		// A5: compute r = (e + x1) mod n, and retry if r = 0 or r + k = n
+		r.Add(e, x)
+		t.Add(r, k)
+		if r.IsZero()|t.IsZero() == 1 {
+			continue // goto A3
+		}
+
+		// A6: compute s = ((1 + d)^-1 * (k - rd)) mod n
+		s.Mul(r, d)
+		s.Sub(k, s)
+		s.Mul(dInv, s)
+		if s.IsZero() == 0 {
+			break
+		}
+	}
+
+	// A7: convert r, s to byte strings, and append the recovery ID, which
+	// is public as (x1, y1) can be recovered from the signature.
+	x1 := out[1 : 1+byteLen]
+	v := out[len(out)-1] & 1
+	v |= byte(subtle.ConstantTimeCompare(x.Bytes(), x1)^1) << 1 // x1 >= n
+	sig := make([]byte, 0, 2*byteLen+1)
+	sig = append(sig, r.Bytes()...)
+	sig = append(sig, s.Bytes()...)
+	return append(sig, v), nil
+}
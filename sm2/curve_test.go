@@ -1,9 +1,140 @@
 package sm2
 
-import "testing"
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
 
 func Test_Curve(t *testing.T) {
 	if !curve.IsOnCurve(curve.Gx, curve.Gy) {
 		t.Errorf("curve.IsOnCurve() = %v, want %v", false, true)
 	}
+	if curve.IsOnCurve(curve.Gx, new(big.Int).Add(curve.Gy, one)) {
+		t.Errorf("curve.IsOnCurve() = %v, want %v", true, false)
+	}
+	if curve.IsOnCurve(new(big.Int), new(big.Int)) {
+		t.Errorf("curve.IsOnCurve(0, 0) = %v, want %v", true, false)
+	}
+}
+
+// Tests that the dedicated curve implementation matches the generic
+// implementation of elliptic.CurveParams.
+func Test_CurveGeneric(t *testing.T) {
+	generic := curve.Params()
+	for i := 0; i < 16; i++ {
+		k := make([]byte, 32)
+		if _, err := rand.Read(k); err != nil {
+			t.Fatal(err)
+		}
+
+		x1, y1 := curve.ScalarBaseMult(k)
+		wantX1, wantY1 := generic.ScalarBaseMult(k)
+		if x1.Cmp(wantX1) != 0 || y1.Cmp(wantY1) != 0 {
+			t.Fatalf("ScalarBaseMult(%x) = (%x, %x), want (%x, %x)", k, x1, y1, wantX1, wantY1)
+		}
+
+		x2, y2 := curve.ScalarMult(x1, y1, k[:20])
+		wantX2, wantY2 := generic.ScalarMult(x1, y1, k[:20])
+		if x2.Cmp(wantX2) != 0 || y2.Cmp(wantY2) != 0 {
+			t.Fatalf("ScalarMult(%x) = (%x, %x), want (%x, %x)", k[:20], x2, y2, wantX2, wantY2)
+		}
+
+		x3, y3 := curve.Add(x1, y1, x2, y2)
+		wantX3, wantY3 := generic.Add(x1, y1, x2, y2)
+		if x3.Cmp(wantX3) != 0 || y3.Cmp(wantY3) != 0 {
+			t.Fatalf("Add() = (%x, %x), want (%x, %x)", x3, y3, wantX3, wantY3)
+		}
+
+		x4, y4 := curve.Double(x3, y3)
+		wantX4, wantY4 := generic.Double(x3, y3)
+		if x4.Cmp(wantX4) != 0 || y4.Cmp(wantY4) != 0 {
+			t.Fatalf("Double() = (%x, %x), want (%x, %x)", x4, y4, wantX4, wantY4)
+		}
+	}
+}
+
+// Tests that the constant-time signing on the SM2 curve matches the generic
+// implementation with the same k.
+func Test_CurveSignGeneric(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		priv, err := GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey:", err)
+		}
+		generic := &PrivateKey{
+			PublicKey: PublicKey{
+				Curve: curve.Params(),
+				X:     priv.X,
+				Y:     priv.Y,
+			},
+			D: priv.D,
+		}
+		message := make([]byte, i*8)
+		if _, err := rand.Read(message); err != nil {
+			t.Fatal(err)
+		}
+		entropy := make([]byte, 40)
+		if _, err := rand.Read(entropy); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, mode := range []NonceMode{RandomNonce, DeterministicNonce, HedgedNonce} {
+			opts := &SignerOpts{Nonce: mode}
+			sig, err := sign(bytes.NewReader(entropy), priv, message, opts)
+			if err != nil {
+				t.Fatal("sign:", err)
+			}
+			want, err := sign(bytes.NewReader(entropy), generic, message, opts)
+			if err != nil {
+				t.Fatal("sign:", err)
+			}
+			if !bytes.Equal(sig, want) {
+				t.Fatalf("sign(mode %d) = %x, want %x", mode, sig, want)
+			}
+		}
+	}
+}
+
+func Test_CurveInfinity(t *testing.T) {
+	// n * G is the point at infinity.
+	x, y := curve.ScalarBaseMult(curve.N.Bytes())
+	if x.Sign() != 0 || y.Sign() != 0 {
+		t.Errorf("ScalarBaseMult(N) = (%x, %x), want (0, 0)", x, y)
+	}
+
+	// G + (-G) is the point at infinity.
+	negY := new(big.Int).Sub(curve.P, curve.Gy)
+	x, y = curve.Add(curve.Gx, curve.Gy, curve.Gx, negY)
+	if x.Sign() != 0 || y.Sign() != 0 {
+		t.Errorf("Add(G, -G) = (%x, %x), want (0, 0)", x, y)
+	}
+
+	// G + G equals 2 * G.
+	x, y = curve.Add(curve.Gx, curve.Gy, curve.Gx, curve.Gy)
+	wantX, wantY := curve.Double(curve.Gx, curve.Gy)
+	if x.Cmp(wantX) != 0 || y.Cmp(wantY) != 0 {
+		t.Errorf("Add(G, G) = (%x, %x), want (%x, %x)", x, y, wantX, wantY)
+	}
+}
+
+func BenchmarkScalarBaseMult(b *testing.B) {
+	k := make([]byte, 32)
+	rand.Read(k)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		curve.ScalarBaseMult(k)
+	}
+}
+
+func BenchmarkScalarMult(b *testing.B) {
+	k := make([]byte, 32)
+	rand.Read(k)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		curve.ScalarMult(curve.Gx, curve.Gy, k)
+	}
 }
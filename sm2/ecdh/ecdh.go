@@ -0,0 +1,146 @@
+// Package ecdh implements the plain Elliptic Curve Diffie-Hellman key
+// agreement over the SM2 curve, with an API modeled on crypto/ecdh.
+//
+// Unlike the key exchange protocol defined in GB/T 32918.3-2016, which is
+// implemented by sm2.KeyExchangeInitiator and sm2.KeyExchangeResponder, the
+// shared secret is the raw x-coordinate of the shared point, without identity
+// binding or key confirmation. It is meant for protocols which derive keys
+// from the shared secret themselves, such as ECDHE in TLCP or
+// ephemeral-static envelopes, and should be passed through a KDF before use.
+package ecdh
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"errors"
+	"io"
+
+	"github.com/need-being/gmcrypto/sm2/internal/sm2ec"
+)
+
+// scalarSize is the size of an encoded private key in bytes.
+const scalarSize = 32
+
+// PublicKey is an ECDH public key on the SM2 curve.
+type PublicKey struct {
+	point []byte // uncompressed encoding
+}
+
+// NewPublicKey parses a public key encoded in the uncompressed form
+// 04 || x || y, or the compressed form 02 || x or 03 || x, as specified in
+// GB/T 32918.1-2016 4.2.9.
+// Points not on the curve and the point at infinity are rejected.
+func NewPublicKey(key []byte) (*PublicKey, error) {
+	if len(key) == 0 || key[0] == 0 {
+		return nil, errors.New("sm2/ecdh: invalid public key")
+	}
+	p, err := sm2ec.NewPoint().SetBytes(key)
+	if err != nil {
+		return nil, errors.New("sm2/ecdh: invalid public key")
+	}
+	return &PublicKey{point: p.Bytes()}, nil
+}
+
+// Bytes returns a copy of the encoding of the public key in the uncompressed
+// form 04 || x || y.
+func (k *PublicKey) Bytes() []byte {
+	return append([]byte(nil), k.point...)
+}
+
+// Equal reports whether k and x have the same value.
+func (k *PublicKey) Equal(x crypto.PublicKey) bool {
+	xx, ok := x.(*PublicKey)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(k.point, xx.point) == 1
+}
+
+// PrivateKey is an ECDH private key on the SM2 curve.
+type PrivateKey struct {
+	d         []byte // 32-byte big-endian scalar in [1, n-1]
+	publicKey *PublicKey
+}
+
+// GenerateKey generates a random private key.
+func GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	b := make([]byte, scalarSize+8) // 64 more bits to reduce bias from mod.
+	if _, err := io.ReadFull(rand, b); err != nil {
+		return nil, err
+	}
+	d := sm2ec.NewScalar().SetUniformBytes(b)
+	return newPrivateKey(d.Bytes())
+}
+
+// NewPrivateKey parses a private key encoded as a 32-byte big-endian integer
+// in [1, n-1].
+func NewPrivateKey(key []byte) (*PrivateKey, error) {
+	d, err := sm2ec.NewScalar().SetBytes(key)
+	if err != nil || d.IsZero() == 1 {
+		return nil, errors.New("sm2/ecdh: invalid private key")
+	}
+	return newPrivateKey(append([]byte(nil), key...))
+}
+
+// newPrivateKey computes the public key of the valid scalar d.
+func newPrivateKey(d []byte) (*PrivateKey, error) {
+	p, err := sm2ec.NewPoint().ScalarBaseMult(d)
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{
+		d:         d,
+		publicKey: &PublicKey{point: p.Bytes()},
+	}, nil
+}
+
+// Bytes returns a copy of the encoding of the private key as a 32-byte
+// big-endian integer.
+func (k *PrivateKey) Bytes() []byte {
+	return append([]byte(nil), k.d...)
+}
+
+// PublicKey returns the public key corresponding to k.
+func (k *PrivateKey) PublicKey() *PublicKey {
+	return k.publicKey
+}
+
+// Public implements the implicit interface of all standard library private
+// keys. See the docs of crypto.PrivateKey.
+func (k *PrivateKey) Public() crypto.PublicKey {
+	return k.PublicKey()
+}
+
+// Equal reports whether k and x have the same value.
+func (k *PrivateKey) Equal(x crypto.PrivateKey) bool {
+	xx, ok := x.(*PrivateKey)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(k.d, xx.d) == 1
+}
+
+// ECDH performs an ECDH exchange and returns the shared secret, which is the
+// 32-byte x-coordinate of the shared point [d]P, where d is the private key
+// and P is the remote public key. An all-zero shared secret is rejected.
+//
+// The remote public key is validated by NewPublicKey, and since the cofactor
+// of the SM2 curve is 1, no further check is needed.
+func (k *PrivateKey) ECDH(remote *PublicKey) ([]byte, error) {
+	p, err := sm2ec.NewPoint().SetBytes(remote.point)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.ScalarMult(p, k.d); err != nil {
+		return nil, err
+	}
+	if p.IsInfinity() == 1 {
+		return nil, errors.New("sm2/ecdh: invalid shared secret")
+	}
+	secret := p.Bytes()[1 : 1+scalarSize]
+	var zero [scalarSize]byte
+	if subtle.ConstantTimeCompare(secret, zero[:]) == 1 {
+		return nil, errors.New("sm2/ecdh: invalid shared secret")
+	}
+	return secret, nil
+}
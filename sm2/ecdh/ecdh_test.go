@@ -0,0 +1,219 @@
+package ecdh
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestECDH(t *testing.T) {
+	tests := []struct {
+		name       string
+		privA      string
+		pubA       string
+		privB      string
+		pubB       string
+		wantSecret string
+	}{
+		{
+			name:       "fixed keys",
+			privA:      "70726976617465206b6579206f6620616c6963652c2033322062797465732121",
+			pubA:       "042c8a8c835e7dbe2f434dcb62952cabde694ceb8fa0f049e6ec8dfdb425aa24c561788a4182dbde45a2c887138ea0e65db86008b13ae22ed7ebaf69dfaebafe5d",
+			privB:      "70726976617465206b6579206f6620626f622c20333220627974657321212121",
+			pubB:       "040b65d5228948df51fc1b79b288f11aede4fdee6c5c71062f9a659176faadec8d29e290eee646b4b9e0debfb4cc46e3c44190cb673c9b4f49eef7dd49edcfdb4d",
+			wantSecret: "4bb0a2c240d516d321e40d4362f9dd0b0edf821d33e740cedb4e348204ffbce2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privA, err := NewPrivateKey(decodeHex(t, tt.privA))
+			if err != nil {
+				t.Fatal("NewPrivateKey:", err)
+			}
+			privB, err := NewPrivateKey(decodeHex(t, tt.privB))
+			if err != nil {
+				t.Fatal("NewPrivateKey:", err)
+			}
+			if got := hex.EncodeToString(privA.PublicKey().Bytes()); got != tt.pubA {
+				t.Errorf("PrivateKey.PublicKey().Bytes() = %s, want %s", got, tt.pubA)
+			}
+			if got := hex.EncodeToString(privB.PublicKey().Bytes()); got != tt.pubB {
+				t.Errorf("PrivateKey.PublicKey().Bytes() = %s, want %s", got, tt.pubB)
+			}
+
+			secret, err := privA.ECDH(privB.PublicKey())
+			if err != nil {
+				t.Fatal("PrivateKey.ECDH:", err)
+			}
+			if got := hex.EncodeToString(secret); got != tt.wantSecret {
+				t.Errorf("PrivateKey.ECDH() = %s, want %s", got, tt.wantSecret)
+			}
+			secret, err = privB.ECDH(privA.PublicKey())
+			if err != nil {
+				t.Fatal("PrivateKey.ECDH:", err)
+			}
+			if got := hex.EncodeToString(secret); got != tt.wantSecret {
+				t.Errorf("PrivateKey.ECDH() = %s, want %s", got, tt.wantSecret)
+			}
+		})
+	}
+}
+
+func TestECDH_random(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		privA, err := GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey:", err)
+		}
+		privB, err := GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey:", err)
+		}
+		secretA, err := privA.ECDH(privB.PublicKey())
+		if err != nil {
+			t.Fatal("PrivateKey.ECDH:", err)
+		}
+		secretB, err := privB.ECDH(privA.PublicKey())
+		if err != nil {
+			t.Fatal("PrivateKey.ECDH:", err)
+		}
+		if !bytes.Equal(secretA, secretB) {
+			t.Fatalf("PrivateKey.ECDH() = %x and %x, want equal", secretA, secretB)
+		}
+
+		// round trip of the encodings
+		priv, err := NewPrivateKey(privA.Bytes())
+		if err != nil {
+			t.Fatal("NewPrivateKey:", err)
+		}
+		if !priv.Equal(privA) {
+			t.Error("NewPrivateKey(PrivateKey.Bytes()) differs from the private key")
+		}
+		pub, err := NewPublicKey(privA.PublicKey().Bytes())
+		if err != nil {
+			t.Fatal("NewPublicKey:", err)
+		}
+		if !pub.Equal(privA.Public()) {
+			t.Error("NewPublicKey(PublicKey.Bytes()) differs from the public key")
+		}
+	}
+}
+
+func TestECDH_zeroSecret(t *testing.T) {
+	// (0, sqrt(b)) is on the curve, so that the private key 1 yields the
+	// all-zero shared secret.
+	remote, err := NewPublicKey(decodeHex(t, "040000000000000000000000000000000000000000000000000000000000000000fd4511e81736a60f07e88a83d6cf5a167fae6d1a9c9330e76e232e00f5cdc154"))
+	if err != nil {
+		t.Fatal("NewPublicKey:", err)
+	}
+	priv, err := NewPrivateKey(decodeHex(t, "0000000000000000000000000000000000000000000000000000000000000001"))
+	if err != nil {
+		t.Fatal("NewPrivateKey:", err)
+	}
+	if secret, err := priv.ECDH(remote); err == nil {
+		t.Errorf("PrivateKey.ECDH() = %x, want error", secret)
+	}
+}
+
+func TestNewPublicKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "uncompressed",
+			key:  "042c8a8c835e7dbe2f434dcb62952cabde694ceb8fa0f049e6ec8dfdb425aa24c561788a4182dbde45a2c887138ea0e65db86008b13ae22ed7ebaf69dfaebafe5d",
+			want: "042c8a8c835e7dbe2f434dcb62952cabde694ceb8fa0f049e6ec8dfdb425aa24c561788a4182dbde45a2c887138ea0e65db86008b13ae22ed7ebaf69dfaebafe5d",
+		},
+		{
+			name: "compressed",
+			key:  "032c8a8c835e7dbe2f434dcb62952cabde694ceb8fa0f049e6ec8dfdb425aa24c5",
+			want: "042c8a8c835e7dbe2f434dcb62952cabde694ceb8fa0f049e6ec8dfdb425aa24c561788a4182dbde45a2c887138ea0e65db86008b13ae22ed7ebaf69dfaebafe5d",
+		},
+		{
+			name:    "not on curve",
+			key:     "042c8a8c835e7dbe2f434dcb62952cabde694ceb8fa0f049e6ec8dfdb425aa24c561788a4182dbde45a2c887138ea0e65db86008b13ae22ed7ebaf69dfaebafe5e",
+			wantErr: true,
+		},
+		{
+			name:    "point at infinity",
+			key:     "00",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			key:     "",
+			wantErr: true,
+		},
+		{
+			name:    "hybrid",
+			key:     "072c8a8c835e7dbe2f434dcb62952cabde694ceb8fa0f049e6ec8dfdb425aa24c561788a4182dbde45a2c887138ea0e65db86008b13ae22ed7ebaf69dfaebafe5d",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub, err := NewPublicKey(decodeHex(t, tt.key))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPublicKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := hex.EncodeToString(pub.Bytes()); got != tt.want {
+				t.Errorf("PublicKey.Bytes() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPrivateKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{
+			name: "one",
+			key:  "0000000000000000000000000000000000000000000000000000000000000001",
+		},
+		{
+			name: "n - 1",
+			key:  "fffffffeffffffffffffffffffffffff7203df6b21c6052b53bbf40939d54122",
+		},
+		{
+			name:    "zero",
+			key:     "0000000000000000000000000000000000000000000000000000000000000000",
+			wantErr: true,
+		},
+		{
+			name:    "n",
+			key:     "fffffffeffffffffffffffffffffffff7203df6b21c6052b53bbf40939d54123",
+			wantErr: true,
+		},
+		{
+			name:    "short",
+			key:     "01",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPrivateKey(decodeHex(t, tt.key)); (err != nil) != tt.wantErr {
+				t.Errorf("NewPrivateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package sm2
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2/internal/convert"
+	"github.com/need-being/gmcrypto/sm2/internal/sm2ec"
+	"github.com/need-being/gmcrypto/sm3"
+	"github.com/need-being/gmcrypto/sm3/kdf"
+)
+
+// CiphertextFormat specifies the layout of an SM2 ciphertext.
+type CiphertextFormat int
+
+const (
+	// C1C3C2 is the layout C1 || C3 || C2 defined in GB/T 32918.4-2016.
+	C1C3C2 CiphertextFormat = iota
+
+	// C1C2C3 is the layout C1 || C2 || C3 used by the draft standard and
+	// many legacy implementations.
+	C1C2C3
+
+	// ASN1Ciphertext is the ASN.1 DER encoding defined in GM/T 0009-2012,
+	// which is used by OpenSSL and GmSSL.
+	ASN1Ciphertext
+)
+
+// ciphertextASN1 is the ASN.1 structure of an SM2 ciphertext defined in
+// GM/T 0009-2012, where C1 = (x, y), C3 = hash and C2 = ciphertext.
+//
+//	SM2Cipher ::= SEQUENCE {
+//	    XCoordinate INTEGER,
+//	    YCoordinate INTEGER,
+//	    HASH        OCTET STRING (SIZE(32)),
+//	    CipherText  OCTET STRING
+//	}
+type ciphertextASN1 struct {
+	X, Y       *big.Int
+	Hash       []byte
+	CipherText []byte
+}
+
+// DecrypterOpts contains options for decrypting with an SM2 private key.
+type DecrypterOpts struct {
+	// Format is the layout of the ciphertext.
+	Format CiphertextFormat
+}
+
+// errDecryption represents a failure to decrypt a message.
+// It is deliberately vague to avoid adaptive attacks.
+var errDecryption = errors.New("sm2: decryption error")
+
+// Encrypt encrypts the message with a public key as specified in
+// GB/T 32918.4-2016, and returns the ciphertext in the C1C3C2 format.
+// SM3 is used for hash algorithm and key derivation.
+func Encrypt(rand io.Reader, pub *PublicKey, message []byte) ([]byte, error) {
+	return EncryptWithFormat(rand, pub, message, C1C3C2)
+}
+
+// EncryptWithFormat encrypts the message with a public key as specified in
+// GB/T 32918.4-2016, and returns the ciphertext in the given format.
+func EncryptWithFormat(rand io.Reader, pub *PublicKey, message []byte, format CiphertextFormat) ([]byte, error) {
+	if format != C1C3C2 && format != C1C2C3 && format != ASN1Ciphertext {
+		return nil, errors.New("sm2: unknown ciphertext format")
+	}
+
+	// A3: check S = hP is not at infinity where h = 1, which is covered by
+	// public key validation.
+	if err := pub.Validate(); err != nil {
+		return nil, err
+	}
+
+	params := pub.Curve.Params()
+	n := (params.BitSize + 7) / 8
+	buf := make([]byte, 2*n)
+	var c1, c2 []byte
+	for {
+		// A1: generate random k, encoded in a fixed width
+		k, err := randScalarBytes(rand, pub.Curve)
+		if err != nil {
+			return nil, err
+		}
+
+		// A2: compute C1 = kG
+		x1, y1 := pub.Curve.ScalarBaseMult(k)
+		if c1, err = marshalPoint(pub.Curve, x1, y1, UncompressedPoint); err != nil {
+			return nil, err
+		}
+
+		// A4: compute (x2, y2) = kP
+		x2, y2 := pub.Curve.ScalarMult(pub.X, pub.Y, k)
+		if err = convert.FieldToBytes(x2, params.P, buf[:n]); err != nil {
+			return nil, err
+		}
+		if err = convert.FieldToBytes(y2, params.P, buf[n:]); err != nil {
+			return nil, err
+		}
+
+		// A5: compute t = KDF(x2 || y2, klen)
+		c2 = kdf.Key(buf, len(message))
+		if len(message) == 0 || !isZero(c2) {
+			break // goto A1
+		}
+	}
+
+	// A6: compute C2 = M xor t
+	xorBytes(c2, message)
+
+	// A7: compute C3 = Hash(x2 || M || y2)
+	c3 := hashC3(buf[:n], message, buf[n:])
+
+	// A8: output the ciphertext
+	if format == ASN1Ciphertext {
+		return asn1.Marshal(ciphertextASN1{
+			X:          convert.BytesToInteger(c1[1 : 1+n]),
+			Y:          convert.BytesToInteger(c1[1+n:]),
+			Hash:       c3,
+			CipherText: c2,
+		})
+	}
+	ciphertext := make([]byte, 0, len(c1)+len(c2)+len(c3))
+	ciphertext = append(ciphertext, c1...)
+	if format == C1C3C2 {
+		ciphertext = append(ciphertext, c3...)
+		ciphertext = append(ciphertext, c2...)
+	} else {
+		ciphertext = append(ciphertext, c2...)
+		ciphertext = append(ciphertext, c3...)
+	}
+	return ciphertext, nil
+}
+
+// Decrypt decrypts the ciphertext in the C1C3C2 format with a private key as
+// specified in GB/T 32918.4-2016.
+func Decrypt(priv *PrivateKey, ciphertext []byte) ([]byte, error) {
+	return DecryptWithFormat(priv, ciphertext, C1C3C2)
+}
+
+// DecryptWithFormat decrypts the ciphertext in the given format with a private
+// key as specified in GB/T 32918.4-2016.
+func DecryptWithFormat(priv *PrivateKey, ciphertext []byte, format CiphertextFormat) ([]byte, error) {
+	if err := priv.Validate(); err != nil {
+		return nil, err
+	}
+
+	params := priv.Curve.Params()
+	n := (params.BitSize + 7) / 8
+	if format == ASN1Ciphertext {
+		var err error
+		if ciphertext, err = asn1ToC1C3C2(ciphertext, params.P, n); err != nil {
+			return nil, errDecryption
+		}
+		format = C1C3C2
+	}
+	c1Len := 1 + 2*n
+	if len(ciphertext) < c1Len+sm3.Size {
+		return nil, errDecryption
+	}
+
+	// split the ciphertext
+	c1 := ciphertext[:c1Len]
+	var c2, c3 []byte
+	switch format {
+	case C1C3C2:
+		c3 = ciphertext[c1Len : c1Len+sm3.Size]
+		c2 = ciphertext[c1Len+sm3.Size:]
+	case C1C2C3:
+		c2 = ciphertext[c1Len : len(ciphertext)-sm3.Size]
+		c3 = ciphertext[len(ciphertext)-sm3.Size:]
+	default:
+		return nil, errors.New("sm2: unknown ciphertext format")
+	}
+
+	// B1, B2: parse C1 and check it is on the curve and not at infinity
+	x1, y1 := pointFromBytes(c1, params.P, n)
+	if x1 == nil || !priv.Curve.IsOnCurve(x1, y1) {
+		return nil, errDecryption
+	}
+
+	// B3: compute (x2, y2) = dC1
+	buf, err := decryptionPoint(priv, c1, x1, y1, n)
+	if err != nil {
+		return nil, errDecryption
+	}
+
+	// B4: compute t = KDF(x2 || y2, klen)
+	message := kdf.Key(buf, len(c2))
+	if len(c2) > 0 && isZero(message) {
+		return nil, errDecryption
+	}
+
+	// B5: compute M = C2 xor t
+	xorBytes(message, c2)
+
+	// B6: check C3 = Hash(x2 || M || y2)
+	if subtle.ConstantTimeCompare(hashC3(buf[:n], message, buf[n:]), c3) != 1 {
+		return nil, errDecryption
+	}
+	return message, nil
+}
+
+// decryptionPoint computes (x2, y2) = dC1, and returns x2 || y2 in n bytes
+// each, where C1 = (x1, y1) is on the curve. On the SM2 curve, d is multiplied
+// as a fixed-width scalar, as the decryption can be triggered by any
+// ciphertext.
+func decryptionPoint(priv *PrivateKey, c1 []byte, x1, y1 *big.Int, n int) ([]byte, error) {
+	if _, ok := priv.Curve.(*sm2Curve); ok {
+		d, err := privateScalar(priv.D)
+		if err != nil {
+			return nil, err
+		}
+		p, err := sm2ec.NewPoint().SetBytes(c1)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.ScalarMult(p, d.Bytes()); err != nil {
+			return nil, err
+		}
+		if p.IsInfinity() == 1 {
+			return nil, errDecryption
+		}
+		return p.Bytes()[1:], nil
+	}
+
+	params := priv.Curve.Params()
+	x2, y2 := priv.Curve.ScalarMult(x1, y1, priv.D.Bytes())
+	buf := make([]byte, 2*n)
+	if err := convert.FieldToBytes(x2, params.P, buf[:n]); err != nil {
+		return nil, err
+	}
+	if err := convert.FieldToBytes(y2, params.P, buf[n:]); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Decrypt decrypts the ciphertext with a private key as specified in
+// GB/T 32918.4-2016. It implements crypto.Decrypter, where rand is not used.
+// The ciphertext is in the C1C3C2 format, unless opts is a *DecrypterOpts
+// selecting otherwise.
+func (priv *PrivateKey) Decrypt(rand io.Reader, ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	format := C1C3C2
+	switch opts := opts.(type) {
+	case nil:
+	case *DecrypterOpts:
+		if opts != nil {
+			format = opts.Format
+		}
+	default:
+		return nil, errors.New("sm2: invalid decrypter options")
+	}
+	return DecryptWithFormat(priv, ciphertext, format)
+}
+
+// asn1ToC1C3C2 converts a ciphertext encoded in ASN.1 DER to the C1C3C2 format,
+// where C1 is uncompressed with coordinates of n bytes.
+func asn1ToC1C3C2(ciphertext []byte, p *big.Int, n int) ([]byte, error) {
+	var v ciphertextASN1
+	rest, err := asn1.Unmarshal(ciphertext, &v)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("sm2: trailing data after ciphertext")
+	}
+	if len(v.Hash) != sm3.Size {
+		return nil, errors.New("sm2: invalid ciphertext hash length")
+	}
+	out := make([]byte, 1+2*n, 1+2*n+len(v.Hash)+len(v.CipherText))
+	out[0] = 4 // uncompressed point
+	if err = convert.FieldToBytes(v.X, p, out[1:1+n]); err != nil {
+		return nil, err
+	}
+	if err = convert.FieldToBytes(v.Y, p, out[1+n:]); err != nil {
+		return nil, err
+	}
+	out = append(out, v.Hash...)
+	return append(out, v.CipherText...), nil
+}
+
+// hashC3 returns C3 = Hash(x2 || M || y2). SM3 is used for hash algorithm.
+func hashC3(x2, message, y2 []byte) []byte {
+	h := sm3.New() // write on sm3 never returns error
+	h.Write(x2)
+	h.Write(message)
+	h.Write(y2)
+	return h.Sum(nil)
+}
+
+// xorBytes sets dst[i] ^= src[i] for each i < len(dst).
+func xorBytes(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+// isZero reports whether all bytes of b are zero.
+func isZero(b []byte) bool {
+	var v byte
+	for _, x := range b {
+		v |= x
+	}
+	return v == 0
+}
+
+// pointFromBytes decodes a point in the uncompressed form defined in
+// GB/T 32918.1-2016 4.2.9. It returns nil if the encoding is malformed.
+// The caller is responsible for checking whether the point is on the curve.
+func pointFromBytes(b []byte, p *big.Int, n int) (x, y *big.Int) {
+	if len(b) != 1+2*n || b[0] != 4 {
+		return nil, nil
+	}
+	x = convert.BytesToInteger(b[1 : 1+n])
+	y = convert.BytesToInteger(b[1+n:])
+	if x.Cmp(p) >= 0 || y.Cmp(p) >= 0 {
+		return nil, nil
+	}
+	return x, y
+}
@@ -0,0 +1,325 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"math/big"
+	"testing"
+)
+
+// testKey is the key pair from GB/T 32918.5-2017 A.2.
+var testKey = &PrivateKey{
+	PublicKey: PublicKey{
+		Curve: curve,
+		X: new(big.Int).SetBytes([]byte{
+			0x09, 0xf9, 0xdf, 0x31, 0x1e, 0x54, 0x21, 0xa1,
+			0x50, 0xdd, 0x7d, 0x16, 0x1e, 0x4b, 0xc5, 0xc6,
+			0x72, 0x17, 0x9f, 0xad, 0x18, 0x33, 0xfc, 0x07,
+			0x6b, 0xb0, 0x8f, 0xf3, 0x56, 0xf3, 0x50, 0x20,
+		}),
+		Y: new(big.Int).SetBytes([]byte{
+			0xcc, 0xea, 0x49, 0x0c, 0xe2, 0x67, 0x75, 0xa5,
+			0x2d, 0xc6, 0xea, 0x71, 0x8c, 0xc1, 0xaa, 0x60,
+			0x0a, 0xed, 0x05, 0xfb, 0xf3, 0x5e, 0x08, 0x4a,
+			0x66, 0x32, 0xf6, 0x07, 0x2d, 0xa9, 0xad, 0x13,
+		}),
+	},
+	D: new(big.Int).SetBytes([]byte{
+		0x39, 0x45, 0x20, 0x8f, 0x7b, 0x21, 0x44, 0xb1,
+		0x3f, 0x36, 0xe3, 0x8a, 0xc6, 0xd3, 0x9f, 0x95,
+		0x88, 0x93, 0x93, 0x69, 0x28, 0x60, 0xb5, 0x1a,
+		0x42, 0xfb, 0x81, 0xef, 0x4d, 0xf7, 0xc5, 0xb8,
+	}),
+}
+
+// GB/T 32918.5-2017 C.2
+var (
+	testEncryptionRand = []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // zeros
+		0x59, 0x27, 0x6e, 0x27, 0xd5, 0x06, 0x86, 0x1a,
+		0x16, 0x68, 0x0f, 0x3a, 0xd9, 0xc0, 0x2d, 0xcc,
+		0xef, 0x3c, 0xc1, 0xfa, 0x3c, 0xdb, 0xe4, 0xce,
+		0x6d, 0x54, 0xb8, 0x0d, 0xea, 0xc1, 0xbc, 0x20, // minus 1
+	}
+	testEncryptionMessage = []byte("encryption standard")
+	testEncryptionC1      = []byte{
+		0x04,
+		0x04, 0xeb, 0xfc, 0x71, 0x8e, 0x8d, 0x17, 0x98,
+		0x62, 0x04, 0x32, 0x26, 0x8e, 0x77, 0xfe, 0xb6,
+		0x41, 0x5e, 0x2e, 0xde, 0x0e, 0x07, 0x3c, 0x0f,
+		0x4f, 0x64, 0x0e, 0xcd, 0x2e, 0x14, 0x9a, 0x73,
+		0xe8, 0x58, 0xf9, 0xd8, 0x1e, 0x54, 0x30, 0xa5,
+		0x7b, 0x36, 0xda, 0xab, 0x8f, 0x95, 0x0a, 0x3c,
+		0x64, 0xe6, 0xee, 0x6a, 0x63, 0x09, 0x4d, 0x99,
+		0x28, 0x3a, 0xff, 0x76, 0x7e, 0x12, 0x4d, 0xf0,
+	}
+	testEncryptionC2 = []byte{
+		0x21, 0x88, 0x6c, 0xa9, 0x89, 0xca, 0x9c, 0x7d,
+		0x58, 0x08, 0x73, 0x07, 0xca, 0x93, 0x09, 0x2d,
+		0x65, 0x1e, 0xfa,
+	}
+	testEncryptionC3 = []byte{
+		0x59, 0x98, 0x3c, 0x18, 0xf8, 0x09, 0xe2, 0x62,
+		0x92, 0x3c, 0x53, 0xae, 0xc2, 0x95, 0xd3, 0x03,
+		0x83, 0xb5, 0x4e, 0x39, 0xd6, 0x09, 0xd1, 0x60,
+		0xaf, 0xcb, 0x19, 0x08, 0xd0, 0xbd, 0x87, 0x66,
+	}
+)
+
+// testEncryptionASN1 is the ciphertext of GB/T 32918.5-2017 C.2 encoded in
+// ASN.1 DER, where y has a leading zero byte as its leftmost bit is set.
+var testEncryptionASN1 = concat(
+	[]byte{0x30, 0x7c, 0x02, 0x20}, testEncryptionC1[1:33],
+	[]byte{0x02, 0x21, 0x00}, testEncryptionC1[33:],
+	[]byte{0x04, 0x20}, testEncryptionC3,
+	[]byte{0x04, 0x13}, testEncryptionC2,
+)
+
+func concat(b ...[]byte) []byte {
+	var res []byte
+	for _, v := range b {
+		res = append(res, v...)
+	}
+	return res
+}
+
+func TestEncryptWithFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  CiphertextFormat
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:   "GB/T 32918.5-2017 C.2: C1C3C2",
+			format: C1C3C2,
+			want:   concat(testEncryptionC1, testEncryptionC3, testEncryptionC2),
+		},
+		{
+			name:   "GB/T 32918.5-2017 C.2: C1C2C3",
+			format: C1C2C3,
+			want:   concat(testEncryptionC1, testEncryptionC2, testEncryptionC3),
+		},
+		{
+			name:   "GB/T 32918.5-2017 C.2: ASN.1",
+			format: ASN1Ciphertext,
+			want:   testEncryptionASN1,
+		},
+		{
+			name:    "unknown format",
+			format:  CiphertextFormat(-1),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rand := io.MultiReader(bytes.NewReader(testEncryptionRand), rand.Reader)
+			got, err := EncryptWithFormat(rand, &testKey.PublicKey, testEncryptionMessage, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EncryptWithFormat() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("EncryptWithFormat() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecryptWithFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     CiphertextFormat
+		ciphertext []byte
+		want       []byte
+		wantErr    bool
+	}{
+		{
+			name:       "GB/T 32918.5-2017 C.2: C1C3C2",
+			format:     C1C3C2,
+			ciphertext: concat(testEncryptionC1, testEncryptionC3, testEncryptionC2),
+			want:       testEncryptionMessage,
+		},
+		{
+			name:       "GB/T 32918.5-2017 C.2: C1C2C3",
+			format:     C1C2C3,
+			ciphertext: concat(testEncryptionC1, testEncryptionC2, testEncryptionC3),
+			want:       testEncryptionMessage,
+		},
+		{
+			name:       "GB/T 32918.5-2017 C.2: ASN.1",
+			format:     ASN1Ciphertext,
+			ciphertext: testEncryptionASN1,
+			want:       testEncryptionMessage,
+		},
+		{
+			name:       "GB/T 32918.5-2017 C.2: ASN.1 with trailing data",
+			format:     ASN1Ciphertext,
+			ciphertext: concat(testEncryptionASN1, []byte{0}),
+			wantErr:    true,
+		},
+		{
+			name:       "GB/T 32918.5-2017 C.2: ASN.1 with short hash",
+			format:     ASN1Ciphertext,
+			ciphertext: concat([]byte{0x30, 0x7b, 0x02, 0x20}, testEncryptionC1[1:33], []byte{0x02, 0x21, 0x00}, testEncryptionC1[33:], []byte{0x04, 0x1f}, testEncryptionC3[1:], []byte{0x04, 0x13}, testEncryptionC2),
+			wantErr:    true,
+		},
+		{
+			name:       "GB/T 32918.5-2017 C.2: wrong format",
+			format:     C1C2C3,
+			ciphertext: concat(testEncryptionC1, testEncryptionC3, testEncryptionC2),
+			wantErr:    true,
+		},
+		{
+			name:       "GB/T 32918.5-2017 C.2: C2 tampered",
+			format:     C1C3C2,
+			ciphertext: concat(testEncryptionC1, testEncryptionC3, testEncryptionC2[1:]),
+			wantErr:    true,
+		},
+		{
+			name:       "GB/T 32918.5-2017 C.2: C1 not on curve",
+			format:     C1C3C2,
+			ciphertext: concat(testEncryptionC1[:64], []byte{0xff}, testEncryptionC3, testEncryptionC2),
+			wantErr:    true,
+		},
+		{
+			name:       "ciphertext too short",
+			format:     C1C3C2,
+			ciphertext: testEncryptionC1,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptWithFormat(testKey, tt.ciphertext, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DecryptWithFormat() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("DecryptWithFormat() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrivateKey_Decrypt(t *testing.T) {
+	var decrypter crypto.Decrypter = testKey
+	tests := []struct {
+		name       string
+		opts       crypto.DecrypterOpts
+		ciphertext []byte
+		wantErr    bool
+	}{
+		{
+			name:       "no options",
+			opts:       nil,
+			ciphertext: concat(testEncryptionC1, testEncryptionC3, testEncryptionC2),
+		},
+		{
+			name:       "nil options",
+			opts:       (*DecrypterOpts)(nil),
+			ciphertext: concat(testEncryptionC1, testEncryptionC3, testEncryptionC2),
+		},
+		{
+			name:       "C1C2C3",
+			opts:       &DecrypterOpts{Format: C1C2C3},
+			ciphertext: concat(testEncryptionC1, testEncryptionC2, testEncryptionC3),
+		},
+		{
+			name:       "ASN.1",
+			opts:       &DecrypterOpts{Format: ASN1Ciphertext},
+			ciphertext: testEncryptionASN1,
+		},
+		{
+			name:       "wrong format",
+			opts:       &DecrypterOpts{Format: ASN1Ciphertext},
+			ciphertext: concat(testEncryptionC1, testEncryptionC3, testEncryptionC2),
+			wantErr:    true,
+		},
+		{
+			name:       "options of another algorithm",
+			opts:       &rsa.OAEPOptions{Hash: crypto.SHA256},
+			ciphertext: concat(testEncryptionC1, testEncryptionC3, testEncryptionC2),
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decrypter.Decrypt(nil, tt.ciphertext, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PrivateKey.Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !bytes.Equal(got, testEncryptionMessage) {
+				t.Errorf("PrivateKey.Decrypt() = %x, want %x", got, testEncryptionMessage)
+			}
+		})
+	}
+}
+
+func TestDecryptOpenSSL(t *testing.T) {
+	priv, err := ParsePKCS8PrivateKey(decodePEM(t, testPKCS8PrivateKeyPEM))
+	if err != nil {
+		t.Fatal("ParsePKCS8PrivateKey:", err)
+	}
+
+	// ciphertext generated by OpenSSL 3.0 with
+	//
+	//	openssl pkeyutl -encrypt -inkey key.pem -in message
+	ciphertext := decodeHex(t, "3075022100e2bc5efc3407c16eeadf04c886da7556425e0f5e7beadf7d3564b86c78ef7dfc022100c4b8b47816a0a8b503c850352eaa9cd81ac057f2d1f7a910afac95550d2fac2f042072d80bb51c19c52c4ea4f9ab0dc4700d4a778f6d27672707569ca7e07a09933e040b7179cfd2df7b62d438c658")
+	got, err := priv.Decrypt(nil, ciphertext, &DecrypterOpts{Format: ASN1Ciphertext})
+	if err != nil {
+		t.Fatal("PrivateKey.Decrypt:", err)
+	}
+	if want := []byte("hello world"); !bytes.Equal(got, want) {
+		t.Errorf("PrivateKey.Decrypt() = %q, want %q", got, want)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	for _, size := range []int{0, 1, 31, 32, 33, 100} {
+		message := make([]byte, size)
+		rand.Read(message)
+		ciphertext, err := Encrypt(rand.Reader, &priv.PublicKey, message)
+		if err != nil {
+			t.Fatalf("Encrypt(%d bytes) error = %v", size, err)
+		}
+		got, err := Decrypt(priv, ciphertext)
+		if err != nil {
+			t.Fatalf("Decrypt(%d bytes) error = %v", size, err)
+		}
+		if !bytes.Equal(got, message) {
+			t.Fatalf("Decrypt() = %x, want %x", got, message)
+		}
+	}
+}
+
+// Tests that private keys with leading zero bytes decrypt correctly with the
+// fixed-width scalar multiplication.
+func TestDecrypt_shortKey(t *testing.T) {
+	for _, d := range []int64{1, 2, 0x1234} {
+		priv := &PrivateKey{D: big.NewInt(d)}
+		priv.Curve = Curve()
+		priv.X, priv.Y = priv.Curve.ScalarBaseMult(priv.D.Bytes())
+		message := []byte("encryption standard")
+		ciphertext, err := Encrypt(rand.Reader, &priv.PublicKey, message)
+		if err != nil {
+			t.Fatal("Encrypt:", err)
+		}
+		got, err := Decrypt(priv, ciphertext)
+		if err != nil {
+			t.Fatalf("Decrypt() with d = %d error = %v", d, err)
+		}
+		if !bytes.Equal(got, message) {
+			t.Errorf("Decrypt() with d = %d = %q, want %q", d, got, message)
+		}
+	}
+}
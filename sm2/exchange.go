@@ -0,0 +1,311 @@
+package sm2
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2/internal/convert"
+	"github.com/need-being/gmcrypto/sm2/internal/sm2ec"
+	"github.com/need-being/gmcrypto/sm3"
+	"github.com/need-being/gmcrypto/sm3/kdf"
+)
+
+// prefixes of the confirmation hashes defined in GB/T 32918.3-2016.
+const (
+	confirmationResponder = 0x02 // prefix of S1 and SB
+	confirmationInitiator = 0x03 // prefix of S2 and SA
+)
+
+// errKeyExchange is returned if the shared point is at infinity.
+var errKeyExchange = errors.New("sm2: key exchange failed")
+
+// keyExchange holds the state shared by both parties of the key exchange
+// protocol defined in GB/T 32918.3-2016.
+type keyExchange struct {
+	priv *PrivateKey // static key of this party
+	peer *PublicKey  // static public key of the other party
+	r    []byte      // ephemeral private key, encoded to the byte size of n
+	pub  *PublicKey  // ephemeral public key
+}
+
+// newKeyExchange generates an ephemeral key pair for the key exchange.
+func newKeyExchange(rand io.Reader, priv *PrivateKey, peer *PublicKey) (*keyExchange, error) {
+	if err := priv.Validate(); err != nil {
+		return nil, err
+	}
+	if err := peer.Validate(); err != nil {
+		return nil, err
+	}
+
+	// A1, B1: generate random r in [1, n-1]
+	r, err := randScalarBytes(rand, priv.Curve)
+	if err != nil {
+		return nil, err
+	}
+
+	// A2, B2: compute R = rG
+	x, y := priv.Curve.ScalarBaseMult(r)
+	return &keyExchange{
+		priv: priv,
+		peer: peer,
+		r:    r,
+		pub: &PublicKey{
+			Curve: priv.Curve,
+			X:     x,
+			Y:     y,
+		},
+	}, nil
+}
+
+// reduce computes x' = 2^w + (x & (2^w - 1)) where w = ceil(ceil(log2(n))/2) - 1.
+func reduce(x, n *big.Int) *big.Int {
+	w := uint((n.BitLen()+1)/2 - 1)
+	mask := new(big.Int).Lsh(one, w)
+	mask.Sub(mask, one)
+	res := new(big.Int).And(x, mask)
+	return res.SetBit(res, int(w), 1)
+}
+
+// sharedPoint computes the shared point
+// (x, y) = [h * t](P + [x']R), where t = (d + x'r) mod n and h = 1,
+// with the ephemeral public key R of the peer.
+// The result is encoded as the byte strings of x and y.
+func (kx *keyExchange) sharedPoint(peerPub *PublicKey) (x, y []byte, err error) {
+	c := kx.priv.Curve
+	params := c.Params()
+	if peerPub == nil || peerPub.Curve != c {
+		return nil, nil, errors.New("sm2: invalid peer ephemeral public key")
+	}
+	if err := peerPub.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if c, ok := c.(*sm2Curve); ok {
+		return c.sharedPoint(kx.priv.D, kx.r, kx.pub, kx.peer, peerPub)
+	}
+
+	// A4, B3: compute t = (d + x'r) mod n
+	t := reduce(kx.pub.X, params.N)
+	t.Mul(t, new(big.Int).SetBytes(kx.r))
+	t.Add(t, kx.priv.D)
+	t.Mod(t, params.N)
+
+	// A5, A6, B4, B5: compute (x, y) = [t](P + [x']R)
+	size := (params.N.BitLen() + 7) / 8
+	px, py := c.ScalarMult(peerPub.X, peerPub.Y, reduce(peerPub.X, params.N).Bytes())
+	px, py = c.Add(kx.peer.X, kx.peer.Y, px, py)
+	px, py = c.ScalarMult(px, py, t.FillBytes(make([]byte, size)))
+	if px.Sign() == 0 && py.Sign() == 0 {
+		return nil, nil, errKeyExchange
+	}
+
+	n := (params.BitSize + 7) / 8
+	x = make([]byte, n)
+	y = make([]byte, n)
+	if err = convert.FieldToBytes(px, params.P, x); err != nil {
+		return nil, nil, err
+	}
+	if err = convert.FieldToBytes(py, params.P, y); err != nil {
+		return nil, nil, err
+	}
+	return x, y, nil
+}
+
+// sharedPoint computes the shared point (x, y) = [t](P + [x2']R2) on the SM2
+// curve, where t = (d + x1'r) mod n with the static private key d and the
+// ephemeral key pair r and R1 = (x1, y1) of this party, and P and
+// R2 = (x2, y2) are the static and the ephemeral public keys of the peer. The
+// scalar arithmetic on d and r, and the scalar multiplication by t run in
+// constant time.
+func (c *sm2Curve) sharedPoint(d *big.Int, r []byte, r1, peer, r2 *PublicKey) (x, y []byte, err error) {
+	dScalar, err := privateScalar(d)
+	if err != nil {
+		return nil, nil, err
+	}
+	w, err := sm2ec.NewScalar().SetBytes(c.scalarBytes(reduce(r1.X, c.N)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A4, B3: compute t = (d + x1'r) mod n
+	t, err := sm2ec.NewScalar().SetBytes(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.Mul(w, t)
+	t.Add(dScalar, t)
+
+	// A5, A6, B4, B5: compute (x, y) = [t](P + [x2']R2)
+	p, err := c.pointFromAffine(peer.X, peer.Y)
+	if err != nil {
+		return nil, nil, err
+	}
+	q, err := c.pointFromAffine(r2.X, r2.Y)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := q.ScalarMult(q, c.scalarBytes(reduce(r2.X, c.N))); err != nil {
+		return nil, nil, err
+	}
+	q.Add(p, q)
+	if _, err := q.ScalarMult(q, t.Bytes()); err != nil {
+		return nil, nil, err
+	}
+	if q.IsInfinity() == 1 {
+		return nil, nil, errKeyExchange
+	}
+
+	n := (c.BitSize + 7) / 8
+	out := q.Bytes()
+	return out[1 : 1+n], out[1+n:], nil
+}
+
+// exchangeKey implements the common part of the key exchange, and returns
+// the shared key K of keyLen bytes and the confirmation hashes of the
+// initiator and the responder.
+// The static and ephemeral public keys are ordered as (initiator, responder).
+func exchangeKey(x, y []byte, pubA, pubB, ephemeralA, ephemeralB *PublicKey, keyLen int) (key, confirmA, confirmB []byte, err error) {
+	if keyLen < 0 || uint64(keyLen) > kdf.MaxLength {
+		return nil, nil, nil, errors.New("sm2: invalid key length")
+	}
+	za, err := pubA.Digest()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	zb, err := pubB.Digest()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// A7, B6: compute K = KDF(x || y || ZA || ZB, klen)
+	z := make([]byte, 0, len(x)+len(y)+len(za)+len(zb))
+	z = append(z, x...)
+	z = append(z, y...)
+	z = append(z, za...)
+	z = append(z, zb...)
+	key = kdf.Key(z, keyLen)
+
+	// A8, A10, B7, B10: compute
+	// S = Hash(prefix || y || Hash(x || ZA || ZB || x1 || y1 || x2 || y2))
+	params := pubA.Curve.Params()
+	buf := make([]byte, (params.BitSize+7)/8)
+	h := sm3.New() // write on sm3 never returns error
+	h.Write(x)
+	h.Write(za)
+	h.Write(zb)
+	for _, v := range []*big.Int{ephemeralA.X, ephemeralA.Y, ephemeralB.X, ephemeralB.Y} {
+		if err = convert.FieldToBytes(v, params.P, buf); err != nil {
+			return nil, nil, nil, err
+		}
+		h.Write(buf)
+	}
+	inner := h.Sum(nil)
+
+	confirm := func(prefix byte) []byte {
+		h.Reset()
+		h.Write([]byte{prefix})
+		h.Write(y)
+		h.Write(inner)
+		return h.Sum(nil)
+	}
+	return key, confirm(confirmationInitiator), confirm(confirmationResponder), nil
+}
+
+// KeyExchangeInitiator represents the initiator A of the key exchange protocol
+// defined in GB/T 32918.3-2016.
+// The ID of each party is taken from its public key.
+type KeyExchangeInitiator struct {
+	keyExchange
+}
+
+// NewKeyExchangeInitiator creates the initiator with its static private key
+// and the static public key of the responder, and generates an ephemeral key
+// pair.
+func NewKeyExchangeInitiator(rand io.Reader, priv *PrivateKey, peer *PublicKey) (*KeyExchangeInitiator, error) {
+	kx, err := newKeyExchange(rand, priv, peer)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyExchangeInitiator{*kx}, nil
+}
+
+// PublicKey returns the ephemeral public key RA to be sent to the responder.
+func (a *KeyExchangeInitiator) PublicKey() *PublicKey {
+	return a.pub
+}
+
+// Agree computes the shared key of keyLen bytes with the ephemeral public key
+// RB of the responder.
+// If sb is not nil, it is checked against S1 to confirm the key of the
+// responder. The returned sa can be sent to the responder for confirmation.
+func (a *KeyExchangeInitiator) Agree(rb *PublicKey, sb []byte, keyLen int) (key, sa []byte, err error) {
+	x, y, err := a.sharedPoint(rb)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, sa, s1, err := exchangeKey(x, y, &a.priv.PublicKey, a.peer, a.pub, rb, keyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A9: check S1 = SB
+	if sb != nil && subtle.ConstantTimeCompare(s1, sb) != 1 {
+		return nil, nil, errors.New("sm2: key confirmation failed")
+	}
+	return key, sa, nil
+}
+
+// KeyExchangeResponder represents the responder B of the key exchange protocol
+// defined in GB/T 32918.3-2016.
+// The ID of each party is taken from its public key.
+type KeyExchangeResponder struct {
+	keyExchange
+	s2 []byte
+}
+
+// NewKeyExchangeResponder creates the responder with its static private key
+// and the static public key of the initiator, and generates an ephemeral key
+// pair.
+func NewKeyExchangeResponder(rand io.Reader, priv *PrivateKey, peer *PublicKey) (*KeyExchangeResponder, error) {
+	kx, err := newKeyExchange(rand, priv, peer)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyExchangeResponder{keyExchange: *kx}, nil
+}
+
+// PublicKey returns the ephemeral public key RB to be sent to the initiator.
+func (b *KeyExchangeResponder) PublicKey() *PublicKey {
+	return b.pub
+}
+
+// Agree computes the shared key of keyLen bytes with the ephemeral public key
+// RA of the initiator.
+// The returned sb can be sent to the initiator for confirmation.
+func (b *KeyExchangeResponder) Agree(ra *PublicKey, keyLen int) (key, sb []byte, err error) {
+	x, y, err := b.sharedPoint(ra)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, s2, sb, err := exchangeKey(x, y, b.peer, &b.priv.PublicKey, ra, b.pub, keyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	b.s2 = s2
+	return key, sb, nil
+}
+
+// Confirm checks SA sent by the initiator against S2 to confirm the key of
+// the initiator. It must be called after Agree.
+func (b *KeyExchangeResponder) Confirm(sa []byte) error {
+	// B10: check S2 = SA
+	if b.s2 == nil {
+		return errors.New("sm2: key not agreed")
+	}
+	if subtle.ConstantTimeCompare(b.s2, sa) != 1 {
+		return errors.New("sm2: key confirmation failed")
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"math/big"
+	"testing"
+)
+
+// GB/T 32918.5-2017 B.2
+var (
+	testExchangeKeyA = &PrivateKey{
+		PublicKey: PublicKey{
+			Curve: curve,
+			X: new(big.Int).SetBytes([]byte{
+				0x16, 0x0e, 0x12, 0x89, 0x7d, 0xf4, 0xed, 0xb6,
+				0x1d, 0xd8, 0x12, 0xfe, 0xb9, 0x67, 0x48, 0xfb,
+				0xd3, 0xcc, 0xf4, 0xff, 0xe2, 0x6a, 0xa6, 0xf6,
+				0xdb, 0x95, 0x40, 0xaf, 0x49, 0xc9, 0x42, 0x32,
+			}),
+			Y: new(big.Int).SetBytes([]byte{
+				0x4a, 0x7d, 0xad, 0x08, 0xbb, 0x9a, 0x45, 0x95,
+				0x31, 0x69, 0x4b, 0xeb, 0x20, 0xaa, 0x48, 0x9d,
+				0x66, 0x49, 0x97, 0x5e, 0x1b, 0xfc, 0xf8, 0xc4,
+				0x74, 0x1b, 0x78, 0xb4, 0xb2, 0x23, 0x00, 0x7f,
+			}),
+			ID: []byte("1234567812345678"),
+		},
+		D: new(big.Int).SetBytes([]byte{
+			0x81, 0xeb, 0x26, 0xe9, 0x41, 0xbb, 0x5a, 0xf1,
+			0x6d, 0xf1, 0x16, 0x49, 0x5f, 0x90, 0x69, 0x52,
+			0x72, 0xae, 0x2c, 0xd6, 0x3d, 0x6c, 0x4a, 0xe1,
+			0x67, 0x84, 0x18, 0xbe, 0x48, 0x23, 0x00, 0x29,
+		}),
+	}
+	testExchangeKeyB = &PrivateKey{
+		PublicKey: PublicKey{
+			Curve: curve,
+			X: new(big.Int).SetBytes([]byte{
+				0x6a, 0xe8, 0x48, 0xc5, 0x7c, 0x53, 0xc7, 0xb1,
+				0xb5, 0xfa, 0x99, 0xeb, 0x22, 0x86, 0xaf, 0x07,
+				0x8b, 0xa6, 0x4c, 0x64, 0x59, 0x1b, 0x8b, 0x56,
+				0x6f, 0x73, 0x57, 0xd5, 0x76, 0xf1, 0x6d, 0xfb,
+			}),
+			Y: new(big.Int).SetBytes([]byte{
+				0xee, 0x48, 0x9d, 0x77, 0x16, 0x21, 0xa2, 0x7b,
+				0x36, 0xc5, 0xc7, 0x99, 0x20, 0x62, 0xe9, 0xcd,
+				0x09, 0xa9, 0x26, 0x43, 0x86, 0xf3, 0xfb, 0xea,
+				0x54, 0xdf, 0xf6, 0x93, 0x05, 0x62, 0x1c, 0x4d,
+			}),
+			ID: []byte("1234567812345678"),
+		},
+		D: new(big.Int).SetBytes([]byte{
+			0x78, 0x51, 0x29, 0x91, 0x7d, 0x45, 0xa9, 0xea,
+			0x54, 0x37, 0xa5, 0x93, 0x56, 0xb8, 0x23, 0x38,
+			0xea, 0xad, 0xda, 0x6c, 0xeb, 0x19, 0x90, 0x88,
+			0xf1, 0x4a, 0xe1, 0x0d, 0xef, 0xa2, 0x29, 0xb5,
+		}),
+	}
+	testExchangeRandA = []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // zeros
+		0xd4, 0xde, 0x15, 0x47, 0x4d, 0xb7, 0x4d, 0x06,
+		0x49, 0x1c, 0x44, 0x0d, 0x30, 0x5e, 0x01, 0x24,
+		0x00, 0x99, 0x0f, 0x3e, 0x39, 0x0c, 0x7e, 0x87,
+		0x15, 0x3c, 0x12, 0xdb, 0x2e, 0xa6, 0x0b, 0xb2, // minus 1
+	}
+	testExchangeRandB = []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // zeros
+		0x7e, 0x07, 0x12, 0x48, 0x14, 0xb3, 0x09, 0x48,
+		0x91, 0x25, 0xea, 0xed, 0x10, 0x11, 0x13, 0x16,
+		0x4e, 0xbf, 0x0f, 0x34, 0x58, 0xc5, 0xbd, 0x88,
+		0x33, 0x5c, 0x1f, 0x9d, 0x59, 0x62, 0x43, 0xd5, // minus 1
+	}
+	testExchangeKey = []byte{
+		0x6c, 0x89, 0x34, 0x73, 0x54, 0xde, 0x24, 0x84,
+		0xc6, 0x0b, 0x4a, 0xb1, 0xfd, 0xe4, 0xc6, 0xe5,
+	}
+	testExchangeSB = []byte{
+		0xd3, 0xa0, 0xfe, 0x15, 0xde, 0xe1, 0x85, 0xce,
+		0xae, 0x90, 0x7a, 0x6b, 0x59, 0x5c, 0xc3, 0x2a,
+		0x26, 0x6e, 0xd7, 0xb3, 0x36, 0x7e, 0x99, 0x83,
+		0xa8, 0x96, 0xdc, 0x32, 0xfa, 0x20, 0xf8, 0xeb,
+	}
+	testExchangeSA = []byte{
+		0x18, 0xc7, 0x89, 0x4b, 0x38, 0x16, 0xdf, 0x16,
+		0xcf, 0x07, 0xb0, 0x5c, 0x5e, 0xc0, 0xbe, 0xf5,
+		0xd6, 0x55, 0xd5, 0x8f, 0x77, 0x9c, 0xc1, 0xb4,
+		0x00, 0xa4, 0xf3, 0x88, 0x46, 0x44, 0xdb, 0x88,
+	}
+)
+
+func TestKeyExchange(t *testing.T) {
+	// the generic implementation on other curves gives the same results.
+	withCurve := func(priv *PrivateKey, c elliptic.Curve) *PrivateKey {
+		key := *priv
+		key.Curve = c
+		return &key
+	}
+	for _, tt := range []struct {
+		name  string
+		curve elliptic.Curve
+	}{
+		{name: "sm2", curve: curve},
+		{name: "generic", curve: curve.Params()},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			testKeyExchange(t, withCurve(testExchangeKeyA, tt.curve), withCurve(testExchangeKeyB, tt.curve))
+		})
+	}
+}
+
+func testKeyExchange(t *testing.T, keyA, keyB *PrivateKey) {
+	// A1 - A3: initiator generates RA
+	a, err := NewKeyExchangeInitiator(
+		io.MultiReader(bytes.NewReader(testExchangeRandA), rand.Reader),
+		keyA,
+		&keyB.PublicKey,
+	)
+	if err != nil {
+		t.Fatal("NewKeyExchangeInitiator:", err)
+	}
+
+	// B1 - B9: responder generates RB and computes KB and SB
+	b, err := NewKeyExchangeResponder(
+		io.MultiReader(bytes.NewReader(testExchangeRandB), rand.Reader),
+		keyB,
+		&keyA.PublicKey,
+	)
+	if err != nil {
+		t.Fatal("NewKeyExchangeResponder:", err)
+	}
+	kb, sb, err := b.Agree(a.PublicKey(), len(testExchangeKey))
+	if err != nil {
+		t.Fatal("KeyExchangeResponder.Agree:", err)
+	}
+	if !bytes.Equal(kb, testExchangeKey) {
+		t.Errorf("KeyExchangeResponder.Agree() key = %x, want %x", kb, testExchangeKey)
+	}
+	if !bytes.Equal(sb, testExchangeSB) {
+		t.Errorf("KeyExchangeResponder.Agree() sb = %x, want %x", sb, testExchangeSB)
+	}
+
+	// A4 - A10: initiator computes KA, checks SB and computes SA
+	ka, sa, err := a.Agree(b.PublicKey(), sb, len(testExchangeKey))
+	if err != nil {
+		t.Fatal("KeyExchangeInitiator.Agree:", err)
+	}
+	if !bytes.Equal(ka, testExchangeKey) {
+		t.Errorf("KeyExchangeInitiator.Agree() key = %x, want %x", ka, testExchangeKey)
+	}
+	if !bytes.Equal(sa, testExchangeSA) {
+		t.Errorf("KeyExchangeInitiator.Agree() sa = %x, want %x", sa, testExchangeSA)
+	}
+
+	// B10: responder checks SA
+	if err := b.Confirm(sa); err != nil {
+		t.Errorf("KeyExchangeResponder.Confirm() error = %v", err)
+	}
+}
+
+func TestKeyExchangeConfirmationFailed(t *testing.T) {
+	privA, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	privA.ID = []byte("alice")
+	privB, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	privB.ID = []byte("bob")
+
+	a, err := NewKeyExchangeInitiator(rand.Reader, privA, &privB.PublicKey)
+	if err != nil {
+		t.Fatal("NewKeyExchangeInitiator:", err)
+	}
+	b, err := NewKeyExchangeResponder(rand.Reader, privB, &privA.PublicKey)
+	if err != nil {
+		t.Fatal("NewKeyExchangeResponder:", err)
+	}
+	if err := b.Confirm(make([]byte, 32)); err == nil {
+		t.Error("KeyExchangeResponder.Confirm() before Agree succeeded, want error")
+	}
+	_, sb, err := b.Agree(a.PublicKey(), 16)
+	if err != nil {
+		t.Fatal("KeyExchangeResponder.Agree:", err)
+	}
+
+	// tamper SB
+	sb[0] ^= 0xff
+	if _, _, err := a.Agree(b.PublicKey(), sb, 16); err == nil {
+		t.Error("KeyExchangeInitiator.Agree() with tampered SB succeeded, want error")
+	}
+
+	// skip optional confirmation
+	_, sa, err := a.Agree(b.PublicKey(), nil, 16)
+	if err != nil {
+		t.Fatal("KeyExchangeInitiator.Agree:", err)
+	}
+
+	// tamper SA
+	sa[0] ^= 0xff
+	if err := b.Confirm(sa); err == nil {
+		t.Error("KeyExchangeResponder.Confirm() with tampered SA succeeded, want error")
+	}
+
+	// invalid ephemeral key
+	invalid := &PublicKey{Curve: curve, X: curve.Gx, Y: new(big.Int).Add(curve.Gy, one)}
+	if _, _, err := b.Agree(invalid, 16); err == nil {
+		t.Error("KeyExchangeResponder.Agree() with invalid RA succeeded, want error")
+	}
+
+	// invalid key length
+	if _, _, err := b.Agree(a.PublicKey(), -1); err == nil {
+		t.Error("KeyExchangeResponder.Agree() with negative key length succeeded, want error")
+	}
+	if _, _, err := a.Agree(b.PublicKey(), nil, -1); err == nil {
+		t.Error("KeyExchangeInitiator.Agree() with negative key length succeeded, want error")
+	}
+}
@@ -0,0 +1,170 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestDefaultID(t *testing.T) {
+	priv, err := ParsePKCS8PrivateKey(decodePEM(t, testPKCS8PrivateKeyPEM))
+	if err != nil {
+		t.Fatal("ParsePKCS8PrivateKey:", err)
+	}
+	pub := &priv.PublicKey
+	message := []byte("hello world")
+
+	// signatures generated by OpenSSL 3.0 with
+	//
+	//	openssl pkeyutl -sign -rawin -digest sm3 -inkey key.pem -in message -pkeyopt distid:1234567812345678
+	//	openssl pkeyutl -sign -rawin -digest sm3 -inkey key.pem -in message
+	//	openssl pkeyutl -sign -rawin -digest sm3 -inkey key.pem -in message -pkeyopt distid:alice
+	//
+	// where the empty ID is used by pkeyutl if distid is not specified.
+	sigDefault := decodeHex(t, "3046022100d972c4a2d3339a8d45ac1d6593b7131c39db47bf71bb0b097cc084367c953bf9022100fded821c89c11c8298b0036d8f2e8dcf9fd31ab6820395fa6a804916e3233f4f")
+	sigEmpty := decodeHex(t, "3045022100be0b25da4cd9a09886c6ecd89e702088ded7972c8987573deb29a87803277429022015cf80228a1b8459c56d5c625ad4ef64b336b4cb8afbedde01b6b055a7029208")
+	sigAlice := decodeHex(t, "3046022100d8ef187a28a4aa011661a797e630e2906d46c16f14e64a931ad6fb181e3df0ee022100c2043a232510c766c6c6933a154f3d6b2b7dd25a50a4247b62533d93fac15d52")
+
+	tests := []struct {
+		name string
+		id   []byte
+		opts *SignerOpts
+		sig  []byte
+		want bool
+	}{
+		{
+			name: "default ID",
+			opts: &SignerOpts{Format: ASN1Signature},
+			sig:  sigDefault,
+			want: true,
+		},
+		{
+			name: "explicit default ID",
+			opts: &SignerOpts{Format: ASN1Signature, UID: []byte(DefaultID)},
+			sig:  sigDefault,
+			want: true,
+		},
+		{
+			name: "default ID against empty ID",
+			opts: &SignerOpts{Format: ASN1Signature},
+			sig:  sigEmpty,
+			want: false,
+		},
+		{
+			name: "empty ID",
+			opts: &SignerOpts{Format: ASN1Signature, UID: []byte{}},
+			sig:  sigEmpty,
+			want: true,
+		},
+		{
+			name: "empty ID of key",
+			id:   []byte{},
+			opts: &SignerOpts{Format: ASN1Signature},
+			sig:  sigEmpty,
+			want: true,
+		},
+		{
+			name: "empty ID against default ID",
+			id:   []byte{},
+			opts: &SignerOpts{Format: ASN1Signature},
+			sig:  sigDefault,
+			want: false,
+		},
+		{
+			name: "UID",
+			opts: &SignerOpts{Format: ASN1Signature, UID: []byte("alice")},
+			sig:  sigAlice,
+			want: true,
+		},
+		{
+			name: "UID overriding ID of key",
+			id:   []byte("bob"),
+			opts: &SignerOpts{Format: ASN1Signature, UID: []byte("alice")},
+			sig:  sigAlice,
+			want: true,
+		},
+		{
+			name: "ID of key",
+			id:   []byte("alice"),
+			opts: &SignerOpts{Format: ASN1Signature},
+			sig:  sigAlice,
+			want: true,
+		},
+		{
+			name: "wrong ID",
+			opts: &SignerOpts{Format: ASN1Signature, UID: []byte("bob")},
+			sig:  sigAlice,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := *pub
+			key.ID = tt.id
+			if got := VerifyWithOpts(&key, message, tt.sig, tt.opts); got != tt.want {
+				t.Errorf("VerifyWithOpts() = %v, want %v", got, tt.want)
+			}
+			if tt.opts.UID == nil {
+				if got := VerifyASN1(&key, message, tt.sig); got != tt.want {
+					t.Errorf("VerifyASN1() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSignerOpts_UID(t *testing.T) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	priv.ID = []byte("bob")
+	message := []byte("message")
+	opts := &SignerOpts{UID: []byte("alice")}
+
+	sig, err := priv.Sign(rand.Reader, message, opts)
+	if err != nil {
+		t.Fatal("PrivateKey.Sign:", err)
+	}
+	if !VerifyWithOpts(&priv.PublicKey, message, sig, opts) {
+		t.Error("VerifyWithOpts() = false, want true")
+	}
+	if Verify(&priv.PublicKey, message, sig) {
+		t.Error("Verify() with ID of key = true, want false")
+	}
+
+	// the deterministic signature depends on the ID.
+	opts = &SignerOpts{Nonce: DeterministicNonce, UID: []byte("alice")}
+	sig1, err := priv.Sign(nil, message, opts)
+	if err != nil {
+		t.Fatal("PrivateKey.Sign:", err)
+	}
+	priv.ID = []byte("alice")
+	sig2, err := SignDeterministic(priv, message)
+	if err != nil {
+		t.Fatal("SignDeterministic:", err)
+	}
+	if !bytes.Equal(sig1, sig2) {
+		t.Errorf("PrivateKey.Sign() = %x, want %x", sig1, sig2)
+	}
+}
+
+func TestSignerOpts_nil(t *testing.T) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	message := []byte("message")
+
+	// a nil *SignerOpts selects the defaults.
+	sig, err := priv.Sign(rand.Reader, message, (*SignerOpts)(nil))
+	if err != nil {
+		t.Fatal("PrivateKey.Sign:", err)
+	}
+	if !Verify(&priv.PublicKey, message, sig) {
+		t.Error("Verify() = false, want true")
+	}
+	if !VerifyWithOpts(&priv.PublicKey, message, sig, nil) {
+		t.Error("VerifyWithOpts() with nil opts = false, want true")
+	}
+}
@@ -0,0 +1,313 @@
+// Package ctcheck implements a syntactic check of the functions handling secret
+// values for branches, memory accesses and other constructs whose timing may
+// depend on the secrets. It is used by the tests of the packages of the SM2
+// signing path.
+package ctcheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// Spec specifies how a function handling secret values is checked by Check.
+type Spec struct {
+	// Public lists the parameters holding public values.
+	Public []string
+
+	// Declassify lists the functions whose results are public, e.g. the
+	// validity of an encoding, which is revealed by the returned error anyway.
+	Declassify []string
+
+	// SecretFields lists the fields holding secret values in the public
+	// parameters, e.g. D of a private key.
+	SecretFields []string
+
+	// Pure lists the functions which do not write secret values into their
+	// arguments, so that only their results become secret.
+	Pure []string
+}
+
+// CheckFiles parses the files, and checks the functions listed in funcs, where
+// methods are named by the receiver type and the method name, e.g.
+// "Point.Add". It returns the violations prefixed by their positions, and the
+// functions not found in the files.
+func CheckFiles(filenames []string, funcs map[string]Spec) ([]string, error) {
+	fset := token.NewFileSet()
+	decls := make(map[string]*ast.FuncDecl)
+	for _, filename := range filenames {
+		f, err := parser.ParseFile(fset, filename, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok {
+				decls[funcKey(fd)] = fd
+			}
+		}
+	}
+
+	var msgs []string
+	for name, spec := range funcs {
+		fd, ok := decls[name]
+		if !ok {
+			msgs = append(msgs, fmt.Sprintf("function %s not found", name))
+			continue
+		}
+		for _, v := range Check(fd, spec) {
+			msgs = append(msgs, fmt.Sprintf("%s: %s: %s", fset.Position(v.Pos), name, v.Msg))
+		}
+	}
+	return msgs, nil
+}
+
+// funcKey returns the name of a function, prefixed by the receiver type for
+// methods.
+func funcKey(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name
+	}
+	typ := fd.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	return fmt.Sprintf("%s.%s", typ.(*ast.Ident).Name, fd.Name.Name)
+}
+
+// Violation is a construct depending on secret values.
+type Violation struct {
+	Pos token.Pos // position of the construct
+	Msg string    // description of the construct
+}
+
+func (v Violation) String() string {
+	return v.Msg
+}
+
+// Check reports the branches, loop conditions, short-circuit operators,
+// divisions and memory accesses depending on secret values in fd.
+//
+// Parameters are secret unless listed in spec.Public, and the taint is
+// propagated through assignments and calls, where all the arguments and the
+// receiver of a call taking any secret value become secret unless the callee
+// is listed in spec.Pure. Package-level values and errors, i.e. the variables
+// named err, are public, as errors are returned to the caller anyway. It is a
+// conservative syntactic check, which relies on the callees being checked as
+// well.
+func Check(fd *ast.FuncDecl, spec Spec) []Violation {
+	local := func(id *ast.Ident) bool {
+		return id.Obj != nil && id.Obj.Kind == ast.Var &&
+			id.Obj.Pos() >= fd.Pos() && id.Obj.Pos() < fd.End()
+	}
+	declassified := make(map[string]bool)
+	for _, name := range spec.Declassify {
+		declassified[name] = true
+	}
+	declassified["len"] = true
+	declassified["cap"] = true
+	secretField := make(map[string]bool)
+	for _, name := range spec.SecretFields {
+		secretField[name] = true
+	}
+	pure := make(map[string]bool)
+	for _, name := range spec.Pure {
+		pure[name] = true
+	}
+
+	// parameters and the receiver are secret unless listed as public.
+	secret := make(map[*ast.Object]bool)
+	public := make(map[string]bool)
+	for _, name := range spec.Public {
+		public[name] = true
+	}
+	var fields []*ast.Field
+	if fd.Recv != nil {
+		fields = append(fields, fd.Recv.List...)
+	}
+	fields = append(fields, fd.Type.Params.List...)
+	for _, field := range fields {
+		for _, name := range field.Names {
+			if !public[name.Name] {
+				secret[name.Obj] = true
+			}
+		}
+	}
+
+	calleeName := func(call *ast.CallExpr) string {
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			return fun.Name
+		case *ast.SelectorExpr:
+			return fun.Sel.Name
+		}
+		return ""
+	}
+	// root returns the variable of an expression such as &x[i].f.
+	var root func(expr ast.Expr) *ast.Ident
+	root = func(expr ast.Expr) *ast.Ident {
+		switch expr := expr.(type) {
+		case *ast.Ident:
+			if local(expr) {
+				return expr
+			}
+		case *ast.ParenExpr:
+			return root(expr.X)
+		case *ast.UnaryExpr:
+			return root(expr.X)
+		case *ast.StarExpr:
+			return root(expr.X)
+		case *ast.IndexExpr:
+			return root(expr.X)
+		case *ast.SliceExpr:
+			return root(expr.X)
+		case *ast.SelectorExpr:
+			return root(expr.X)
+		}
+		return nil
+	}
+	isSecret := func(expr ast.Expr) bool {
+		found := false
+		ast.Inspect(expr, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.CallExpr:
+				if declassified[calleeName(node)] {
+					return false
+				}
+			case *ast.SelectorExpr:
+				// only the operand matters, e.g. x of x.f, unless f is a
+				// secret field.
+				if secretField[node.Sel.Name] && root(node.X) != nil {
+					found = true
+					return false
+				}
+				ast.Inspect(node.X, func(n ast.Node) bool {
+					if id, ok := n.(*ast.Ident); ok && local(id) && secret[id.Obj] {
+						found = true
+					}
+					return !found
+				})
+				return false
+			case *ast.Ident:
+				if local(node) && secret[node.Obj] {
+					found = true
+				}
+			}
+			return !found
+		})
+		return found
+	}
+
+	changed := false
+	taint := func(exprs ...ast.Expr) {
+		for _, expr := range exprs {
+			// a secret field taints no other field.
+			if sel, ok := expr.(*ast.SelectorExpr); ok && secretField[sel.Sel.Name] {
+				continue
+			}
+			if id := root(expr); id != nil && id.Name != "err" && !secret[id.Obj] {
+				secret[id.Obj] = true
+				changed = true
+			}
+		}
+	}
+
+	// propagate the taint until no more variable becomes secret.
+	for changed = true; changed; {
+		changed = false
+		ast.Inspect(fd.Body, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.AssignStmt:
+				for _, rhs := range node.Rhs {
+					if isSecret(rhs) {
+						taint(node.Lhs...)
+						break
+					}
+				}
+			case *ast.ValueSpec:
+				for _, value := range node.Values {
+					if isSecret(value) {
+						for _, name := range node.Names {
+							taint(name)
+						}
+						break
+					}
+				}
+			case *ast.RangeStmt:
+				if node.Value != nil && isSecret(node.X) {
+					taint(node.Value)
+				}
+			case *ast.CallExpr:
+				// arguments may still be tainted by declassified callees.
+				if name := calleeName(node); name == "len" || name == "cap" || pure[name] {
+					return true
+				}
+				args := append([]ast.Expr{}, node.Args...)
+				if sel, ok := node.Fun.(*ast.SelectorExpr); ok {
+					args = append(args, sel.X)
+				}
+				for _, arg := range args {
+					if isSecret(arg) {
+						taint(args...)
+						break
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	var violations []Violation
+	report := func(pos token.Pos, format string, args ...interface{}) {
+		violations = append(violations, Violation{pos, fmt.Sprintf(format, args...)})
+	}
+	ast.Inspect(fd.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.IfStmt:
+			if isSecret(node.Cond) {
+				report(node.Pos(), "branch on secret value")
+			}
+		case *ast.ForStmt:
+			if node.Cond != nil && isSecret(node.Cond) {
+				report(node.Pos(), "loop condition on secret value")
+			}
+		case *ast.SwitchStmt:
+			if node.Tag != nil && isSecret(node.Tag) {
+				report(node.Pos(), "switch on secret value")
+			}
+		case *ast.CaseClause:
+			for _, expr := range node.List {
+				if isSecret(expr) {
+					report(expr.Pos(), "switch case on secret value")
+				}
+			}
+		case *ast.BinaryExpr:
+			switch node.Op {
+			case token.LAND, token.LOR:
+				if isSecret(node.X) || isSecret(node.Y) {
+					report(node.Pos(), "short-circuit operator on secret value")
+				}
+			case token.QUO, token.REM:
+				if isSecret(node.X) || isSecret(node.Y) {
+					report(node.Pos(), "division of secret value")
+				}
+			}
+		case *ast.IndexExpr:
+			if isSecret(node.Index) {
+				report(node.Pos(), "memory access indexed by secret value")
+			}
+		case *ast.SliceExpr:
+			for _, index := range []ast.Expr{node.Low, node.High, node.Max} {
+				if index != nil && isSecret(index) {
+					report(node.Pos(), "memory access sliced by secret value")
+				}
+			}
+		case *ast.SelectorExpr:
+			if id, ok := node.X.(*ast.Ident); ok && id.Name == "big" && id.Obj == nil {
+				report(node.Pos(), "variable-time math/big")
+			}
+		}
+		return true
+	})
+	return violations
+}
@@ -0,0 +1,121 @@
+package ctcheck
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+// TestCheck checks that Check catches the common
+// patterns of variable-time code.
+func TestCheck(t *testing.T) {
+	const src = `package p
+
+func branch(x uint64) uint64 {
+	y := x >> 1
+	if y == 0 {
+		return 1
+	}
+	return 0
+}
+
+func lookup(table *[16]uint64, x uint64) uint64 {
+	var i uint64
+	i = x & 0xf
+	return table[i]
+}
+
+func loop(x uint64) (n int) {
+	for x != 0 {
+		x >>= 1
+		n++
+	}
+	return n
+}
+
+func shortCircuit(x, y uint64) bool {
+	return x == 0 || y == 0
+}
+
+func division(x uint64) uint64 {
+	return x % 7
+}
+
+func taintedByCall(x *[4]uint64) uint64 {
+	var t [4]uint64
+	copy(t[:], x[:])
+	return t[0] / 3
+}
+
+type key struct {
+	d, n uint64
+}
+
+func secretField(k *key) uint64 {
+	if k.n == 0 {
+		return 0
+	}
+	if k.d == 0 {
+		return 1
+	}
+	return 2
+}
+
+func pureCall(x uint64, public []byte) uint64 {
+	y := hash(x, public)
+	if len(public) == 0 || public[0] == 0 {
+		return y
+	}
+	return 0
+}
+
+func clean(x, y uint64, public []byte) uint64 {
+	var r uint64
+	for i, b := range public {
+		if b != 0 && i < len(public) {
+			r ^= x & y
+		}
+	}
+	mask := -(x & 1)
+	return (x & mask) | (y &^ mask)
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		"branch":        1,
+		"lookup":        1,
+		"loop":          1,
+		"shortCircuit":  1,
+		"division":      1,
+		"taintedByCall": 1,
+		"secretField":   1,
+		"pureCall":      0,
+		"clean":         0,
+	}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		spec := Spec{}
+		switch fd.Name.Name {
+		case "clean":
+			spec.Public = []string{"public"}
+		case "secretField":
+			spec.Public = []string{"k"}
+			spec.SecretFields = []string{"d"}
+		case "pureCall":
+			spec.Public = []string{"public"}
+			spec.Pure = []string{"hash"}
+		}
+		got := Check(fd, spec)
+		if len(got) != want[fd.Name.Name] {
+			t.Errorf("Check(%s) = %v, want %d violations", fd.Name.Name, got, want[fd.Name.Name])
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package paillier implements the Paillier cryptosystem, which is additively
+// homomorphic, for the multiplicative-to-additive conversion in threshold
+// signing.
+//
+// The implementation uses math/big, and is not constant time.
+package paillier
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+)
+
+var one = big.NewInt(1)
+
+// PublicKey is a Paillier public key with the generator g = N + 1.
+type PublicKey struct {
+	N  *big.Int
+	n2 *big.Int // N^2
+}
+
+// PrivateKey is a Paillier private key.
+type PrivateKey struct {
+	PublicKey
+	lambda *big.Int // lcm(p - 1, q - 1)
+	mu     *big.Int // lambda^-1 mod N
+}
+
+// NewPublicKey returns the public key with the modulus N.
+func NewPublicKey(n *big.Int) *PublicKey {
+	return &PublicKey{
+		N:  n,
+		n2: new(big.Int).Mul(n, n),
+	}
+}
+
+// GenerateKey generates a private key with a modulus of the given bit size.
+func GenerateKey(random io.Reader, bits int) (*PrivateKey, error) {
+	if bits < 16 || bits%2 != 0 {
+		return nil, errors.New("invalid key size")
+	}
+	for {
+		p, err := rand.Prime(random, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := rand.Prime(random, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		if p.Cmp(q) == 0 {
+			continue
+		}
+		n := new(big.Int).Mul(p, q)
+		if n.BitLen() != bits {
+			continue
+		}
+
+		// lambda = lcm(p - 1, q - 1) = (p - 1)(q - 1) / gcd(p - 1, q - 1)
+		p1 := new(big.Int).Sub(p, one)
+		q1 := new(big.Int).Sub(q, one)
+		gcd := new(big.Int).GCD(nil, nil, p1, q1)
+		lambda := new(big.Int).Mul(p1, q1)
+		lambda.Quo(lambda, gcd)
+
+		// with g = N + 1, mu = lambda^-1 mod N
+		mu := new(big.Int).ModInverse(lambda, n)
+		if mu == nil {
+			continue
+		}
+		return &PrivateKey{
+			PublicKey: *NewPublicKey(n),
+			lambda:    lambda,
+			mu:        mu,
+		}, nil
+	}
+}
+
+// NewPrivateKey returns the private key with the modulus N and
+// lambda = lcm(p - 1, q - 1), as returned by Lambda.
+func NewPrivateKey(n, lambda *big.Int) (*PrivateKey, error) {
+	if n.Sign() <= 0 || lambda.Sign() <= 0 || lambda.Cmp(n) >= 0 {
+		return nil, errors.New("invalid private key")
+	}
+	mu := new(big.Int).ModInverse(lambda, n)
+	if mu == nil {
+		return nil, errors.New("invalid private key")
+	}
+
+	// w^lambda = 1 mod N for any w coprime to N, which catches a lambda not
+	// matching N.
+	if new(big.Int).Exp(big.NewInt(2), lambda, n).Cmp(one) != 0 {
+		return nil, errors.New("invalid private key")
+	}
+	return &PrivateKey{
+		PublicKey: *NewPublicKey(n),
+		lambda:    lambda,
+		mu:        mu,
+	}, nil
+}
+
+// Lambda returns lambda = lcm(p - 1, q - 1), which restores the private key
+// with the modulus N by NewPrivateKey.
+func (priv *PrivateKey) Lambda() *big.Int {
+	return new(big.Int).Set(priv.lambda)
+}
+
+// Encrypt encrypts m in [0, N) as c = (1 + N)^m * r^N mod N^2.
+func (pub *PublicKey) Encrypt(random io.Reader, m *big.Int) (*big.Int, error) {
+	if m.Sign() < 0 || m.Cmp(pub.N) >= 0 {
+		return nil, errors.New("message out of range")
+	}
+	r, err := pub.randUnit(random)
+	if err != nil {
+		return nil, err
+	}
+
+	// (1 + N)^m = 1 + mN mod N^2
+	c := new(big.Int).Mul(m, pub.N)
+	c.Add(c, one)
+	r.Exp(r, pub.N, pub.n2)
+	c.Mul(c, r)
+	return c.Mod(c, pub.n2), nil
+}
+
+// randUnit returns a random integer in [1, N) coprime to N.
+func (pub *PublicKey) randUnit(random io.Reader) (*big.Int, error) {
+	gcd := new(big.Int)
+	for {
+		r, err := rand.Int(random, pub.N)
+		if err != nil {
+			return nil, err
+		}
+		if r.Sign() > 0 && gcd.GCD(nil, nil, r, pub.N).Cmp(one) == 0 {
+			return r, nil
+		}
+	}
+}
+
+// ValidateCiphertext checks if c is in [1, N^2) and coprime to N.
+func (pub *PublicKey) ValidateCiphertext(c *big.Int) error {
+	if c == nil || c.Sign() <= 0 || c.Cmp(pub.n2) >= 0 {
+		return errors.New("ciphertext out of range")
+	}
+	if new(big.Int).GCD(nil, nil, c, pub.N).Cmp(one) != 0 {
+		return errors.New("invalid ciphertext")
+	}
+	return nil
+}
+
+// Add returns the encryption of m1 + m2 mod N, where c1 and c2 are the
+// encryptions of m1 and m2.
+func (pub *PublicKey) Add(c1, c2 *big.Int) *big.Int {
+	c := new(big.Int).Mul(c1, c2)
+	return c.Mod(c, pub.n2)
+}
+
+// Mul returns the encryption of k * m mod N, where c is the encryption of m.
+func (pub *PublicKey) Mul(c, k *big.Int) *big.Int {
+	return new(big.Int).Exp(c, k, pub.n2)
+}
+
+// Decrypt decrypts c as m = L(c^lambda mod N^2) * mu mod N, where
+// L(x) = (x - 1) / N.
+func (priv *PrivateKey) Decrypt(c *big.Int) (*big.Int, error) {
+	if err := priv.ValidateCiphertext(c); err != nil {
+		return nil, err
+	}
+	m := new(big.Int).Exp(c, priv.lambda, priv.n2)
+	m.Sub(m, one)
+	m.Quo(m, priv.N)
+	m.Mul(m, priv.mu)
+	return m.Mod(m, priv.N), nil
+}
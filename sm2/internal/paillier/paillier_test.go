@@ -0,0 +1,99 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestPaillier(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader, 512)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	if got := priv.N.BitLen(); got != 512 {
+		t.Fatalf("GenerateKey() N.BitLen() = %d, want 512", got)
+	}
+	pub := NewPublicKey(priv.N)
+
+	for i := 0; i < 16; i++ {
+		m1, _ := rand.Int(rand.Reader, pub.N)
+		m2, _ := rand.Int(rand.Reader, pub.N)
+		k, _ := rand.Int(rand.Reader, pub.N)
+		c1, err := pub.Encrypt(rand.Reader, m1)
+		if err != nil {
+			t.Fatal("PublicKey.Encrypt:", err)
+		}
+		c2, err := pub.Encrypt(rand.Reader, m2)
+		if err != nil {
+			t.Fatal("PublicKey.Encrypt:", err)
+		}
+
+		got, err := priv.Decrypt(c1)
+		if err != nil {
+			t.Fatal("PrivateKey.Decrypt:", err)
+		}
+		if got.Cmp(m1) != 0 {
+			t.Fatalf("Decrypt(Encrypt(%x)) = %x", m1, got)
+		}
+
+		// homomorphic addition and multiplication by a constant.
+		want := new(big.Int).Mul(m1, k)
+		want.Add(want, m2)
+		want.Mod(want, pub.N)
+		got, err = priv.Decrypt(pub.Add(pub.Mul(c1, k), c2))
+		if err != nil {
+			t.Fatal("PrivateKey.Decrypt:", err)
+		}
+		if got.Cmp(want) != 0 {
+			t.Fatalf("Decrypt(%x * k + %x) = %x, want %x", m1, m2, got, want)
+		}
+	}
+}
+
+func TestPaillier_invalid(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader, 512)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	if _, err := priv.Encrypt(rand.Reader, priv.N); err == nil {
+		t.Error("PublicKey.Encrypt() accepted message N")
+	}
+	if _, err := priv.Encrypt(rand.Reader, big.NewInt(-1)); err == nil {
+		t.Error("PublicKey.Encrypt() accepted negative message")
+	}
+	for _, c := range []*big.Int{nil, new(big.Int), new(big.Int).Mul(priv.N, priv.N), new(big.Int).Set(priv.N)} {
+		if _, err := priv.Decrypt(c); err == nil {
+			t.Errorf("PrivateKey.Decrypt(%v) succeeded, want error", c)
+		}
+	}
+}
+
+func TestNewPrivateKey(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader, 512)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	restored, err := NewPrivateKey(priv.N, priv.Lambda())
+	if err != nil {
+		t.Fatal("NewPrivateKey:", err)
+	}
+	m, _ := rand.Int(rand.Reader, priv.N)
+	c, err := priv.Encrypt(rand.Reader, m)
+	if err != nil {
+		t.Fatal("PublicKey.Encrypt:", err)
+	}
+	got, err := restored.Decrypt(c)
+	if err != nil {
+		t.Fatal("PrivateKey.Decrypt:", err)
+	}
+	if got.Cmp(m) != 0 {
+		t.Errorf("Decrypt(Encrypt(%x)) with restored key = %x", m, got)
+	}
+
+	for _, lambda := range []*big.Int{new(big.Int), priv.N, new(big.Int).Add(priv.Lambda(), one)} {
+		if _, err := NewPrivateKey(priv.N, lambda); err == nil {
+			t.Errorf("NewPrivateKey(N, %x) succeeded, want error", lambda)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package sm2ec
+
+import (
+	"testing"
+
+	"github.com/need-being/gmcrypto/sm2/internal/ctcheck"
+)
+
+// constantTimeFuncs lists the functions which may handle secret values, such
+// as private keys, nonces, and the intermediate values derived from them.
+var constantTimeFuncs = map[string]ctcheck.Spec{
+	// field.go
+	"fieldElement.Set":       {},
+	"fieldElement.One":       {},
+	"fieldElement.Add":       {},
+	"fieldElement.Sub":       {},
+	"fieldElement.Mul":       {},
+	"fieldElement.Square":    {},
+	"fieldElement.Invert":    {},
+	"fieldElement.Sqrt":      {},
+	"fieldElement.exp":       {Public: []string{"exponent"}},
+	"fieldElement.Select":    {},
+	"fieldElement.IsZero":    {},
+	"fieldElement.Equal":     {},
+	"fieldElement.SetBytes":  {Declassify: []string{"bytesToLimbs"}},
+	"fieldElement.Bytes":     {},
+	"fieldElement.fillBytes": {},
+	"fieldElement.IsOdd":     {},
+	"bytesToLimbs":           {},
+	"limbsToBytes":           {},
+	"modAdd":                 {},
+	"modSub":                 {},
+	"fieldMul":               {},
+	"montMul":                {},
+	"mac":                    {},
+	"fromMont":               {},
+
+	// point.go
+	"Point.Set":            {},
+	"Point.SetInfinity":    {},
+	"Point.Bytes":          {},
+	"Point.bytes":          {Declassify: []string{"IsZero"}}, // the encoding of infinity is shorter
+	"Point.affine":         {},
+	"Point.IsInfinity":     {},
+	"Point.Add":            {},
+	"Point.Double":         {},
+	"Point.Negate":         {},
+	"Point.Select":         {},
+	"pointTable.init":      {},
+	"pointTable.Select":    {},
+	"Point.ScalarMult":     {},
+	"Point.ScalarBaseMult": {},
+
+	// scalar.go
+	"Scalar.Set":             {},
+	"Scalar.One":             {},
+	"Scalar.SetBytes":        {Declassify: []string{"bytesToLimbs"}},
+	"Scalar.SetReducedBytes": {},
+	"Scalar.SetUniformBytes": {},
+	"reduceBytes":            {},
+	"Scalar.Bytes":           {},
+	"Scalar.Add":             {},
+	"Scalar.Sub":             {},
+	"Scalar.Mul":             {},
+	"Scalar.Invert":          {},
+	"Scalar.IsZero":          {},
+	"Scalar.Equal":           {},
+}
+
+// TestConstantTime checks that the functions handling secret values have
+// neither branches nor memory accesses depending on secret values.
+func TestConstantTime(t *testing.T) {
+	msgs, err := ctcheck.CheckFiles([]string{"field.go", "point.go", "scalar.go"}, constantTimeFuncs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range msgs {
+		t.Error(msg)
+	}
+}
@@ -0,0 +1,463 @@
+package sm2ec
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// fieldElement is an integer modulo p in the Montgomery domain with R = 2^256.
+// It is stored as four 64-bit limbs in little-endian order.
+//
+// The zero value is a valid zero element.
+type fieldElement [4]uint64
+
+// p is the SM2 prime
+// p = 2^256 - 2^224 - 2^96 + 2^64 - 1.
+var p = [4]uint64{0xffffffffffffffff, 0xffffffff00000000, 0xffffffffffffffff, 0xfffffffeffffffff}
+
+// pInv is -p^-1 mod 2^64, which is 1 since p = -1 mod 2^64.
+const pInv = 1
+
+// precomputed field elements.
+var (
+	// fieldRR is R^2 mod p, used for converting into the Montgomery domain.
+	fieldRR = [4]uint64{0x0000000200000003, 0x00000002ffffffff, 0x0000000100000001, 0x0000000400000002}
+
+	// fieldOne is 1 in the Montgomery domain.
+	fieldOne = fieldElement{0x0000000000000001, 0x00000000ffffffff, 0x0000000000000000, 0x0000000100000000}
+
+	// fieldB is the curve coefficient b in the Montgomery domain.
+	fieldB = fieldElement{0x90d230632bc0dd42, 0x71cf379ae9b537ab, 0x527981505ea51c3c, 0x240fe188ba20e2c8}
+)
+
+// exponents used by field inversion and square root.
+var (
+	// pMinus2 is p-2 in big-endian.
+	pMinus2 = []byte{
+		0xff, 0xff, 0xff, 0xfe, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfd,
+	}
+
+	// pPlus1Over4 is (p+1)/4 in big-endian.
+	pPlus1Over4 = []byte{
+		0x3f, 0xff, 0xff, 0xff, 0xbf, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xc0, 0x00, 0x00, 0x00,
+		0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+)
+
+// Set sets e = x, and returns e.
+func (e *fieldElement) Set(x *fieldElement) *fieldElement {
+	*e = *x
+	return e
+}
+
+// One sets e = 1, and returns e.
+func (e *fieldElement) One() *fieldElement {
+	*e = fieldOne
+	return e
+}
+
+// Add sets e = x + y mod p, and returns e.
+func (e *fieldElement) Add(x, y *fieldElement) *fieldElement {
+	modAdd((*[4]uint64)(e), (*[4]uint64)(x), (*[4]uint64)(y), &p)
+	return e
+}
+
+// Sub sets e = x - y mod p, and returns e.
+func (e *fieldElement) Sub(x, y *fieldElement) *fieldElement {
+	modSub((*[4]uint64)(e), (*[4]uint64)(x), (*[4]uint64)(y), &p)
+	return e
+}
+
+// Mul sets e = x * y mod p, and returns e.
+func (e *fieldElement) Mul(x, y *fieldElement) *fieldElement {
+	fieldMul((*[4]uint64)(e), (*[4]uint64)(x), (*[4]uint64)(y))
+	return e
+}
+
+// Square sets e = x * x mod p, and returns e.
+func (e *fieldElement) Square(x *fieldElement) *fieldElement {
+	return e.Mul(x, x)
+}
+
+// Invert sets e = 1/x mod p, and returns e.
+// If x is zero, e is set to zero.
+func (e *fieldElement) Invert(x *fieldElement) *fieldElement {
+	return e.exp(x, pMinus2)
+}
+
+// Sqrt sets e to a square root of x, and returns e and 1 if x is a square.
+// Otherwise, it returns e with an unspecified value and 0.
+// Since p = 3 mod 4, the root is computed as x^((p+1)/4).
+func (e *fieldElement) Sqrt(x *fieldElement) (*fieldElement, int) {
+	var r, check fieldElement
+	r.exp(x, pPlus1Over4)
+	check.Square(&r)
+	ok := check.Equal(x)
+	return e.Set(&r), ok
+}
+
+// exp sets e = x^exponent mod p, and returns e.
+// The exponent is a public big-endian value, so the running time may depend
+// on it.
+func (e *fieldElement) exp(x *fieldElement, exponent []byte) *fieldElement {
+	// fixed 4-bit window with table[i] = x^(i+1).
+	var table [15]fieldElement
+	table[0].Set(x)
+	for i := 1; i < 15; i++ {
+		table[i].Mul(&table[i-1], x)
+	}
+
+	var r fieldElement
+	r.One()
+	for _, b := range exponent {
+		r.Square(&r)
+		r.Square(&r)
+		r.Square(&r)
+		r.Square(&r)
+		if w := b >> 4; w != 0 {
+			r.Mul(&r, &table[w-1])
+		}
+		r.Square(&r)
+		r.Square(&r)
+		r.Square(&r)
+		r.Square(&r)
+		if w := b & 0xf; w != 0 {
+			r.Mul(&r, &table[w-1])
+		}
+	}
+	return e.Set(&r)
+}
+
+// Select sets e to a if cond == 1, and to b if cond == 0.
+func (e *fieldElement) Select(a, b *fieldElement, cond int) *fieldElement {
+	mask := -uint64(cond)
+	e[0] = (a[0] & mask) | (b[0] &^ mask)
+	e[1] = (a[1] & mask) | (b[1] &^ mask)
+	e[2] = (a[2] & mask) | (b[2] &^ mask)
+	e[3] = (a[3] & mask) | (b[3] &^ mask)
+	return e
+}
+
+// IsZero returns 1 if e == 0, and zero otherwise.
+func (e *fieldElement) IsZero() int {
+	x := e[0] | e[1] | e[2] | e[3]
+	// (x | -x) has the top bit set iff x != 0.
+	return int(1 ^ (x|-x)>>63)
+}
+
+// Equal returns 1 if e and x are equal, and zero otherwise.
+func (e *fieldElement) Equal(x *fieldElement) int {
+	var d fieldElement
+	d[0] = e[0] ^ x[0]
+	d[1] = e[1] ^ x[1]
+	d[2] = e[2] ^ x[2]
+	d[3] = e[3] ^ x[3]
+	return d.IsZero()
+}
+
+// SetBytes sets e = v, where v is a 32-byte big-endian encoding of an integer
+// in [0, p), and returns e. If v is not 32 bytes or encodes a value not less
+// than p, SetBytes returns nil and an error, and e is unchanged.
+func (e *fieldElement) SetBytes(v []byte) (*fieldElement, error) {
+	var x [4]uint64
+	if !bytesToLimbs(&x, v, &p) {
+		return nil, errors.New("invalid field element encoding")
+	}
+	montMul((*[4]uint64)(e), &x, &fieldRR, &p, pInv)
+	return e, nil
+}
+
+// Bytes returns the 32-byte big-endian encoding of e.
+func (e *fieldElement) Bytes() []byte {
+	var out [32]byte
+	return e.fillBytes(&out)
+}
+
+func (e *fieldElement) fillBytes(out *[32]byte) []byte {
+	var x [4]uint64
+	fromMont(&x, (*[4]uint64)(e), &p, pInv)
+	limbsToBytes(out, &x)
+	return out[:]
+}
+
+// IsOdd returns 1 if the canonical value of e is odd, and zero otherwise.
+func (e *fieldElement) IsOdd() int {
+	var x [4]uint64
+	fromMont(&x, (*[4]uint64)(e), &p, pInv)
+	return int(x[0] & 1)
+}
+
+// bytesToLimbs decodes a 32-byte big-endian value into little-endian limbs,
+// and reports whether the value is less than m.
+func bytesToLimbs(x *[4]uint64, v []byte, m *[4]uint64) bool {
+	if len(v) != 32 {
+		return false
+	}
+	x[3] = binary.BigEndian.Uint64(v[0:])
+	x[2] = binary.BigEndian.Uint64(v[8:])
+	x[1] = binary.BigEndian.Uint64(v[16:])
+	x[0] = binary.BigEndian.Uint64(v[24:])
+
+	// x < m iff x - m borrows.
+	var b uint64
+	_, b = bits.Sub64(x[0], m[0], 0)
+	_, b = bits.Sub64(x[1], m[1], b)
+	_, b = bits.Sub64(x[2], m[2], b)
+	_, b = bits.Sub64(x[3], m[3], b)
+	return b == 1
+}
+
+// limbsToBytes encodes little-endian limbs into a 32-byte big-endian value.
+func limbsToBytes(out *[32]byte, x *[4]uint64) {
+	binary.BigEndian.PutUint64(out[0:], x[3])
+	binary.BigEndian.PutUint64(out[8:], x[2])
+	binary.BigEndian.PutUint64(out[16:], x[1])
+	binary.BigEndian.PutUint64(out[24:], x[0])
+}
+
+// modAdd sets z = x + y mod m, where x, y < m.
+func modAdd(z, x, y, m *[4]uint64) {
+	var t, r [4]uint64
+	var c, b uint64
+	t[0], c = bits.Add64(x[0], y[0], 0)
+	t[1], c = bits.Add64(x[1], y[1], c)
+	t[2], c = bits.Add64(x[2], y[2], c)
+	t[3], c = bits.Add64(x[3], y[3], c)
+
+	r[0], b = bits.Sub64(t[0], m[0], 0)
+	r[1], b = bits.Sub64(t[1], m[1], b)
+	r[2], b = bits.Sub64(t[2], m[2], b)
+	r[3], b = bits.Sub64(t[3], m[3], b)
+	_, b = bits.Sub64(c, 0, b)
+
+	// keep t if t - m borrows, i.e. t < m.
+	mask := -b
+	z[0] = (t[0] & mask) | (r[0] &^ mask)
+	z[1] = (t[1] & mask) | (r[1] &^ mask)
+	z[2] = (t[2] & mask) | (r[2] &^ mask)
+	z[3] = (t[3] & mask) | (r[3] &^ mask)
+}
+
+// modSub sets z = x - y mod m, where x, y < m.
+func modSub(z, x, y, m *[4]uint64) {
+	var t [4]uint64
+	var b, c uint64
+	t[0], b = bits.Sub64(x[0], y[0], 0)
+	t[1], b = bits.Sub64(x[1], y[1], b)
+	t[2], b = bits.Sub64(x[2], y[2], b)
+	t[3], b = bits.Sub64(x[3], y[3], b)
+
+	// add m back if x - y borrows.
+	mask := -b
+	z[0], c = bits.Add64(t[0], m[0]&mask, 0)
+	z[1], c = bits.Add64(t[1], m[1]&mask, c)
+	z[2], c = bits.Add64(t[2], m[2]&mask, c)
+	z[3], _ = bits.Add64(t[3], m[3]&mask, c)
+}
+
+// fieldMul sets z = x * y * 2^-256 mod p, where x, y < p.
+// It is montMul specialized for the SM2 prime. Since pInv = 1, the reduction
+// factor u of each round equals t[0], and
+//
+//	(t + u * p) / 2^64 = t / 2^64 + u * (2^192 + 1) - (u << 32) * (2^128 + 1)
+//
+// which replaces the multiplications by p with additions and subtractions.
+func fieldMul(z, x, y *[4]uint64) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	var t0, t1, t2, t3, t4, t5, c, b, u, uLo, uHi, yi uint64
+
+	// round 0
+	yi = y[0]
+	c, t0 = mac(x0, yi, t0, 0)
+	c, t1 = mac(x1, yi, t1, c)
+	c, t2 = mac(x2, yi, t2, c)
+	c, t3 = mac(x3, yi, t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	u, uLo, uHi = t0, t0<<32, t0>>32
+	t0, c = bits.Add64(t1, u, 0)
+	t1, c = bits.Add64(t2, 0, c)
+	t2, c = bits.Add64(t3, 0, c)
+	t3, c = bits.Add64(t4, u, c)
+	t4 = t5 + c
+	t0, b = bits.Sub64(t0, uLo, 0)
+	t1, b = bits.Sub64(t1, uHi, b)
+	t2, b = bits.Sub64(t2, uLo, b)
+	t3, b = bits.Sub64(t3, uHi, b)
+	t4 -= b
+
+	// round 1
+	yi = y[1]
+	c, t0 = mac(x0, yi, t0, 0)
+	c, t1 = mac(x1, yi, t1, c)
+	c, t2 = mac(x2, yi, t2, c)
+	c, t3 = mac(x3, yi, t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	u, uLo, uHi = t0, t0<<32, t0>>32
+	t0, c = bits.Add64(t1, u, 0)
+	t1, c = bits.Add64(t2, 0, c)
+	t2, c = bits.Add64(t3, 0, c)
+	t3, c = bits.Add64(t4, u, c)
+	t4 = t5 + c
+	t0, b = bits.Sub64(t0, uLo, 0)
+	t1, b = bits.Sub64(t1, uHi, b)
+	t2, b = bits.Sub64(t2, uLo, b)
+	t3, b = bits.Sub64(t3, uHi, b)
+	t4 -= b
+
+	// round 2
+	yi = y[2]
+	c, t0 = mac(x0, yi, t0, 0)
+	c, t1 = mac(x1, yi, t1, c)
+	c, t2 = mac(x2, yi, t2, c)
+	c, t3 = mac(x3, yi, t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	u, uLo, uHi = t0, t0<<32, t0>>32
+	t0, c = bits.Add64(t1, u, 0)
+	t1, c = bits.Add64(t2, 0, c)
+	t2, c = bits.Add64(t3, 0, c)
+	t3, c = bits.Add64(t4, u, c)
+	t4 = t5 + c
+	t0, b = bits.Sub64(t0, uLo, 0)
+	t1, b = bits.Sub64(t1, uHi, b)
+	t2, b = bits.Sub64(t2, uLo, b)
+	t3, b = bits.Sub64(t3, uHi, b)
+	t4 -= b
+
+	// round 3
+	yi = y[3]
+	c, t0 = mac(x0, yi, t0, 0)
+	c, t1 = mac(x1, yi, t1, c)
+	c, t2 = mac(x2, yi, t2, c)
+	c, t3 = mac(x3, yi, t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	u, uLo, uHi = t0, t0<<32, t0>>32
+	t0, c = bits.Add64(t1, u, 0)
+	t1, c = bits.Add64(t2, 0, c)
+	t2, c = bits.Add64(t3, 0, c)
+	t3, c = bits.Add64(t4, u, c)
+	t4 = t5 + c
+	t0, b = bits.Sub64(t0, uLo, 0)
+	t1, b = bits.Sub64(t1, uHi, b)
+	t2, b = bits.Sub64(t2, uLo, b)
+	t3, b = bits.Sub64(t3, uHi, b)
+	t4 -= b
+
+	// t < 2p, subtract p once if t >= p.
+	var r0, r1, r2, r3 uint64
+	r0, b = bits.Sub64(t0, p[0], 0)
+	r1, b = bits.Sub64(t1, p[1], b)
+	r2, b = bits.Sub64(t2, p[2], b)
+	r3, b = bits.Sub64(t3, p[3], b)
+	_, b = bits.Sub64(t4, 0, b)
+
+	mask := -b
+	z[0] = (t0 & mask) | (r0 &^ mask)
+	z[1] = (t1 & mask) | (r1 &^ mask)
+	z[2] = (t2 & mask) | (r2 &^ mask)
+	z[3] = (t3 & mask) | (r3 &^ mask)
+}
+
+// montMul sets z = x * y * 2^-256 mod m using the CIOS method, where x, y < m
+// and mInv = -m^-1 mod 2^64. The loops are unrolled for performance.
+func montMul(z, x, y, m *[4]uint64, mInv uint64) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	m0, m1, m2, m3 := m[0], m[1], m[2], m[3]
+	var t0, t1, t2, t3, t4, t5, c, u, yi uint64
+
+	// round 0: t += x * y[0], then t = (t + u * m) / 2^64
+	yi = y[0]
+	c, t0 = mac(x0, yi, t0, 0)
+	c, t1 = mac(x1, yi, t1, c)
+	c, t2 = mac(x2, yi, t2, c)
+	c, t3 = mac(x3, yi, t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	u = t0 * mInv
+	c, _ = mac(u, m0, t0, 0)
+	c, t0 = mac(u, m1, t1, c)
+	c, t1 = mac(u, m2, t2, c)
+	c, t2 = mac(u, m3, t3, c)
+	t3, c = bits.Add64(t4, c, 0)
+	t4 = t5 + c
+
+	// round 1: t += x * y[1], then t = (t + u * m) / 2^64
+	yi = y[1]
+	c, t0 = mac(x0, yi, t0, 0)
+	c, t1 = mac(x1, yi, t1, c)
+	c, t2 = mac(x2, yi, t2, c)
+	c, t3 = mac(x3, yi, t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	u = t0 * mInv
+	c, _ = mac(u, m0, t0, 0)
+	c, t0 = mac(u, m1, t1, c)
+	c, t1 = mac(u, m2, t2, c)
+	c, t2 = mac(u, m3, t3, c)
+	t3, c = bits.Add64(t4, c, 0)
+	t4 = t5 + c
+
+	// round 2: t += x * y[2], then t = (t + u * m) / 2^64
+	yi = y[2]
+	c, t0 = mac(x0, yi, t0, 0)
+	c, t1 = mac(x1, yi, t1, c)
+	c, t2 = mac(x2, yi, t2, c)
+	c, t3 = mac(x3, yi, t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	u = t0 * mInv
+	c, _ = mac(u, m0, t0, 0)
+	c, t0 = mac(u, m1, t1, c)
+	c, t1 = mac(u, m2, t2, c)
+	c, t2 = mac(u, m3, t3, c)
+	t3, c = bits.Add64(t4, c, 0)
+	t4 = t5 + c
+
+	// round 3: t += x * y[3], then t = (t + u * m) / 2^64
+	yi = y[3]
+	c, t0 = mac(x0, yi, t0, 0)
+	c, t1 = mac(x1, yi, t1, c)
+	c, t2 = mac(x2, yi, t2, c)
+	c, t3 = mac(x3, yi, t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	u = t0 * mInv
+	c, _ = mac(u, m0, t0, 0)
+	c, t0 = mac(u, m1, t1, c)
+	c, t1 = mac(u, m2, t2, c)
+	c, t2 = mac(u, m3, t3, c)
+	t3, c = bits.Add64(t4, c, 0)
+	t4 = t5 + c
+
+	// t < 2m, subtract m once if t >= m.
+	var r0, r1, r2, r3, b uint64
+	r0, b = bits.Sub64(t0, m0, 0)
+	r1, b = bits.Sub64(t1, m1, b)
+	r2, b = bits.Sub64(t2, m2, b)
+	r3, b = bits.Sub64(t3, m3, b)
+	_, b = bits.Sub64(t4, 0, b)
+
+	mask := -b
+	z[0] = (t0 & mask) | (r0 &^ mask)
+	z[1] = (t1 & mask) | (r1 &^ mask)
+	z[2] = (t2 & mask) | (r2 &^ mask)
+	z[3] = (t3 & mask) | (r3 &^ mask)
+}
+
+// mac returns (hi, lo) = a * b + c + d.
+func mac(a, b, c, d uint64) (hi, lo uint64) {
+	var cc uint64
+	hi, lo = bits.Mul64(a, b)
+	lo, cc = bits.Add64(lo, c, 0)
+	hi += cc
+	lo, cc = bits.Add64(lo, d, 0)
+	hi += cc
+	return hi, lo
+}
+
+// fromMont sets z = x * 2^-256 mod m, converting x out of the Montgomery domain.
+func fromMont(z, x, m *[4]uint64, mInv uint64) {
+	one := [4]uint64{1}
+	montMul(z, x, &one, m, mInv)
+}
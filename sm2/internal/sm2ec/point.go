@@ -0,0 +1,656 @@
+// Package sm2ec implements the SM2 elliptic curve defined in GB/T 32918.5-2017
+// with fixed-size, allocation-free field arithmetic.
+//
+// All operations are constant time unless documented otherwise.
+package sm2ec
+
+import (
+	"errors"
+	"math/bits"
+	"sync"
+)
+
+// fieldSize is the size of an encoded field element in bytes.
+const fieldSize = 32
+
+// generator in the Montgomery domain.
+var (
+	generatorX = fieldElement{0x61328990f418029e, 0x3e7981eddca6c050, 0xd6a1ed99ac24c3c3, 0x91167a5ee1c13b05}
+	generatorY = fieldElement{0xc1354e593c2d0ddd, 0xc1f5e5788d3295fa, 0x8d4cfb066e2a48f8, 0x63cd65d481d735bd}
+)
+
+// Point is an SM2 curve point in homogeneous projective coordinates
+// (X:Y:Z), which represents the affine point (X/Z, Y/Z).
+//
+// The zero value is NOT valid, and may be used only as a receiver.
+type Point struct {
+	x, y, z fieldElement
+}
+
+// NewPoint returns a new Point representing the point at infinity.
+func NewPoint() *Point {
+	p := new(Point)
+	p.y.One()
+	return p
+}
+
+// NewGenerator returns a new Point set to the canonical generator.
+func NewGenerator() *Point {
+	p := new(Point)
+	p.x.Set(&generatorX)
+	p.y.Set(&generatorY)
+	p.z.One()
+	return p
+}
+
+// Set sets p = q and returns p.
+func (p *Point) Set(q *Point) *Point {
+	*p = *q
+	return p
+}
+
+// SetInfinity sets p to the point at infinity and returns p.
+func (p *Point) SetInfinity() *Point {
+	p.x = fieldElement{}
+	p.y.One()
+	p.z = fieldElement{}
+	return p
+}
+
+// SetGenerator sets p to the canonical generator and returns p.
+func (p *Point) SetGenerator() *Point {
+	p.x.Set(&generatorX)
+	p.y.Set(&generatorY)
+	p.z.One()
+	return p
+}
+
+// SetBytes sets p to the compressed, uncompressed, or infinity value encoded
+// in b, as specified in GB/T 32918.1-2016 4.2.9. If the point is not on the
+// curve, it returns nil and an error, and the receiver is unchanged.
+// Otherwise, it returns p.
+func (p *Point) SetBytes(b []byte) (*Point, error) {
+	switch {
+	// point at infinity
+	case len(b) == 1 && b[0] == 0:
+		return p.SetInfinity(), nil
+
+	// uncompressed form
+	case len(b) == 1+2*fieldSize && b[0] == 4:
+		var x, y fieldElement
+		if _, err := x.SetBytes(b[1 : 1+fieldSize]); err != nil {
+			return nil, err
+		}
+		if _, err := y.SetBytes(b[1+fieldSize:]); err != nil {
+			return nil, err
+		}
+		if err := checkOnCurve(&x, &y); err != nil {
+			return nil, err
+		}
+		p.x.Set(&x)
+		p.y.Set(&y)
+		p.z.One()
+		return p, nil
+
+	// compressed form
+	case len(b) == 1+fieldSize && (b[0] == 2 || b[0] == 3):
+		var x, y fieldElement
+		if _, err := x.SetBytes(b[1:]); err != nil {
+			return nil, err
+		}
+
+		// y^2 = x^3 - 3x + b
+		polynomial(&y, &x)
+		if _, ok := y.Sqrt(&y); ok != 1 {
+			return nil, errors.New("invalid point compression")
+		}
+
+		// select the root with the requested parity.
+		var negY fieldElement
+		negY.Sub(&negY, &y)
+		cond := y.IsOdd() ^ int(b[0]&1)
+		y.Select(&negY, &y, cond)
+
+		p.x.Set(&x)
+		p.y.Set(&y)
+		p.z.One()
+		return p, nil
+
+	default:
+		return nil, errors.New("invalid point encoding")
+	}
+}
+
+// polynomial sets y2 to x^3 - 3x + b, and returns y2.
+func polynomial(y2, x *fieldElement) *fieldElement {
+	var threeX fieldElement
+	y2.Square(x)
+	y2.Mul(y2, x)
+
+	threeX.Add(x, x)
+	threeX.Add(&threeX, x)
+	y2.Sub(y2, &threeX)
+
+	return y2.Add(y2, &fieldB)
+}
+
+// checkOnCurve returns an error if (x, y) is not on the curve.
+func checkOnCurve(x, y *fieldElement) error {
+	// y^2 = x^3 - 3x + b
+	var lhs, rhs fieldElement
+	rhs.Set(polynomial(&rhs, x))
+	lhs.Square(y)
+	if lhs.Equal(&rhs) != 1 {
+		return errors.New("point not on SM2 curve")
+	}
+	return nil
+}
+
+// Bytes returns the uncompressed or infinity encoding of p, as specified in
+// GB/T 32918.1-2016 4.2.9. Note that the encoding of the point at infinity is
+// shorter than all other encodings.
+func (p *Point) Bytes() []byte {
+	var out [1 + 2*fieldSize]byte
+	return p.bytes(&out)
+}
+
+func (p *Point) bytes(out *[1 + 2*fieldSize]byte) []byte {
+	if p.z.IsZero() == 1 {
+		return append(out[:0], 0)
+	}
+
+	var x, y [fieldSize]byte
+	p.affine(&x, &y)
+	out[0] = 4
+	copy(out[1:], x[:])
+	copy(out[1+fieldSize:], y[:])
+	return out[:]
+}
+
+// BytesCompressed returns the compressed or infinity encoding of p, as
+// specified in GB/T 32918.1-2016 4.2.9. Note that the encoding of the point at
+// infinity is shorter than all other encodings.
+func (p *Point) BytesCompressed() []byte {
+	var out [1 + fieldSize]byte
+	return p.bytesCompressed(&out)
+}
+
+func (p *Point) bytesCompressed(out *[1 + fieldSize]byte) []byte {
+	if p.z.IsZero() == 1 {
+		return append(out[:0], 0)
+	}
+
+	var zinv, x, y fieldElement
+	zinv.Invert(&p.z)
+	x.Mul(&p.x, &zinv)
+	y.Mul(&p.y, &zinv)
+
+	var buf [fieldSize]byte
+	out[0] = 2 | byte(y.IsOdd())
+	copy(out[1:], x.fillBytes(&buf))
+	return out[:]
+}
+
+// affine encodes the affine coordinates of p into x and y.
+// p must not be the point at infinity.
+func (p *Point) affine(x, y *[fieldSize]byte) {
+	var zinv, ax, ay fieldElement
+	zinv.Invert(&p.z)
+	ax.Mul(&p.x, &zinv)
+	ay.Mul(&p.y, &zinv)
+	ax.fillBytes(x)
+	ay.fillBytes(y)
+}
+
+// IsInfinity returns 1 if p is the point at infinity, and zero otherwise.
+func (p *Point) IsInfinity() int {
+	return p.z.IsZero()
+}
+
+// Add sets q = p1 + p2, and returns q. The points may overlap.
+func (q *Point) Add(p1, p2 *Point) *Point {
+	// Complete addition formula for a = -3 from "Complete addition formulas for
+	// prime order elliptic curves" (https://eprint.iacr.org/2015/1060), §A.2.
+
+	var t0, t1, t2, t3, t4, x3, y3, z3 fieldElement
+	t0.Mul(&p1.x, &p2.x) // t0 := X1 * X2
+	t1.Mul(&p1.y, &p2.y) // t1 := Y1 * Y2
+	t2.Mul(&p1.z, &p2.z) // t2 := Z1 * Z2
+	t3.Add(&p1.x, &p1.y) // t3 := X1 + Y1
+	t4.Add(&p2.x, &p2.y) // t4 := X2 + Y2
+	t3.Mul(&t3, &t4)     // t3 := t3 * t4
+	t4.Add(&t0, &t1)     // t4 := t0 + t1
+	t3.Sub(&t3, &t4)     // t3 := t3 - t4
+	t4.Add(&p1.y, &p1.z) // t4 := Y1 + Z1
+	x3.Add(&p2.y, &p2.z) // X3 := Y2 + Z2
+	t4.Mul(&t4, &x3)     // t4 := t4 * X3
+	x3.Add(&t1, &t2)     // X3 := t1 + t2
+	t4.Sub(&t4, &x3)     // t4 := t4 - X3
+	x3.Add(&p1.x, &p1.z) // X3 := X1 + Z1
+	y3.Add(&p2.x, &p2.z) // Y3 := X2 + Z2
+	x3.Mul(&x3, &y3)     // X3 := X3 * Y3
+	y3.Add(&t0, &t2)     // Y3 := t0 + t2
+	y3.Sub(&x3, &y3)     // Y3 := X3 - Y3
+	z3.Mul(&fieldB, &t2) // Z3 := b * t2
+	x3.Sub(&y3, &z3)     // X3 := Y3 - Z3
+	z3.Add(&x3, &x3)     // Z3 := X3 + X3
+	x3.Add(&x3, &z3)     // X3 := X3 + Z3
+	z3.Sub(&t1, &x3)     // Z3 := t1 - X3
+	x3.Add(&t1, &x3)     // X3 := t1 + X3
+	y3.Mul(&fieldB, &y3) // Y3 := b * Y3
+	t1.Add(&t2, &t2)     // t1 := t2 + t2
+	t2.Add(&t1, &t2)     // t2 := t1 + t2
+	y3.Sub(&y3, &t2)     // Y3 := Y3 - t2
+	y3.Sub(&y3, &t0)     // Y3 := Y3 - t0
+	t1.Add(&y3, &y3)     // t1 := Y3 + Y3
+	y3.Add(&t1, &y3)     // Y3 := t1 + Y3
+	t1.Add(&t0, &t0)     // t1 := t0 + t0
+	t0.Add(&t1, &t0)     // t0 := t1 + t0
+	t0.Sub(&t0, &t2)     // t0 := t0 - t2
+	t1.Mul(&t4, &y3)     // t1 := t4 * Y3
+	t2.Mul(&t0, &y3)     // t2 := t0 * Y3
+	y3.Mul(&x3, &z3)     // Y3 := X3 * Z3
+	y3.Add(&y3, &t2)     // Y3 := Y3 + t2
+	x3.Mul(&t3, &x3)     // X3 := t3 * X3
+	x3.Sub(&x3, &t1)     // X3 := X3 - t1
+	z3.Mul(&t4, &z3)     // Z3 := t4 * Z3
+	t1.Mul(&t3, &t0)     // t1 := t3 * t0
+	z3.Add(&z3, &t1)     // Z3 := Z3 + t1
+
+	q.x.Set(&x3)
+	q.y.Set(&y3)
+	q.z.Set(&z3)
+	return q
+}
+
+// Double sets q = p + p, and returns q. The points may overlap.
+func (q *Point) Double(p *Point) *Point {
+	// Complete addition formula for a = -3 from "Complete addition formulas for
+	// prime order elliptic curves" (https://eprint.iacr.org/2015/1060), §A.2.
+
+	var t0, t1, t2, t3, x3, y3, z3 fieldElement
+	t0.Square(&p.x)      // t0 := X ^ 2
+	t1.Square(&p.y)      // t1 := Y ^ 2
+	t2.Square(&p.z)      // t2 := Z ^ 2
+	t3.Mul(&p.x, &p.y)   // t3 := X * Y
+	t3.Add(&t3, &t3)     // t3 := t3 + t3
+	z3.Mul(&p.x, &p.z)   // Z3 := X * Z
+	z3.Add(&z3, &z3)     // Z3 := Z3 + Z3
+	y3.Mul(&fieldB, &t2) // Y3 := b * t2
+	y3.Sub(&y3, &z3)     // Y3 := Y3 - Z3
+	x3.Add(&y3, &y3)     // X3 := Y3 + Y3
+	y3.Add(&x3, &y3)     // Y3 := X3 + Y3
+	x3.Sub(&t1, &y3)     // X3 := t1 - Y3
+	y3.Add(&t1, &y3)     // Y3 := t1 + Y3
+	y3.Mul(&x3, &y3)     // Y3 := X3 * Y3
+	x3.Mul(&x3, &t3)     // X3 := X3 * t3
+	t3.Add(&t2, &t2)     // t3 := t2 + t2
+	t2.Add(&t2, &t3)     // t2 := t2 + t3
+	z3.Mul(&fieldB, &z3) // Z3 := b * Z3
+	z3.Sub(&z3, &t2)     // Z3 := Z3 - t2
+	z3.Sub(&z3, &t0)     // Z3 := Z3 - t0
+	t3.Add(&z3, &z3)     // t3 := Z3 + Z3
+	z3.Add(&z3, &t3)     // Z3 := Z3 + t3
+	t3.Add(&t0, &t0)     // t3 := t0 + t0
+	t0.Add(&t3, &t0)     // t0 := t3 + t0
+	t0.Sub(&t0, &t2)     // t0 := t0 - t2
+	t0.Mul(&t0, &z3)     // t0 := t0 * Z3
+	y3.Add(&y3, &t0)     // Y3 := Y3 + t0
+	t0.Mul(&p.y, &p.z)   // t0 := Y * Z
+	t0.Add(&t0, &t0)     // t0 := t0 + t0
+	z3.Mul(&t0, &z3)     // Z3 := t0 * Z3
+	x3.Sub(&x3, &z3)     // X3 := X3 - Z3
+	z3.Mul(&t0, &t1)     // Z3 := t0 * t1
+	z3.Add(&z3, &z3)     // Z3 := Z3 + Z3
+	z3.Add(&z3, &z3)     // Z3 := Z3 + Z3
+
+	q.x.Set(&x3)
+	q.y.Set(&y3)
+	q.z.Set(&z3)
+	return q
+}
+
+// Negate sets q = -p, and returns q.
+func (q *Point) Negate(p *Point) *Point {
+	q.x.Set(&p.x)
+	q.y.Sub(new(fieldElement), &p.y)
+	q.z.Set(&p.z)
+	return q
+}
+
+// Select sets q to p1 if cond == 1, and to p2 if cond == 0.
+func (q *Point) Select(p1, p2 *Point, cond int) *Point {
+	q.x.Select(&p1.x, &p2.x, cond)
+	q.y.Select(&p1.y, &p2.y, cond)
+	q.z.Select(&p1.z, &p2.z, cond)
+	return q
+}
+
+// pointTable is a table of the multiples 1*P, 2*P, ..., 15*P of a point P.
+type pointTable [15]Point
+
+// init fills t with the multiples of p.
+func (t *pointTable) init(p *Point) {
+	t[0].Set(p)
+	for i := 1; i < 15; i += 2 {
+		t[i].Double(&t[i/2])
+		t[i+1].Add(&t[i], p)
+	}
+}
+
+// Select selects the n-th multiple of the table base point into p. It works in
+// constant time by iterating over every entry of the table. n must be in
+// [0, 15], where zero selects the point at infinity.
+func (t *pointTable) Select(p *Point, n uint8) {
+	p.SetInfinity()
+	for i := uint8(1); i < 16; i++ {
+		cond := int(((uint32(i ^ n)) - 1) >> 31)
+		p.Select(&t[i-1], p, cond)
+	}
+}
+
+// ScalarMult sets p = scalar * q, and returns p.
+// The scalar is a 32-byte big-endian value, which need not be reduced.
+func (p *Point) ScalarMult(q *Point, scalar []byte) (*Point, error) {
+	if len(scalar) != 32 {
+		return nil, errors.New("invalid scalar length")
+	}
+
+	var table pointTable
+	table.init(q)
+
+	// fixed 4-bit window from the most significant nibble.
+	var r, t Point
+	r.SetInfinity()
+	for i, b := range scalar {
+		if i != 0 {
+			r.Double(&r)
+			r.Double(&r)
+			r.Double(&r)
+			r.Double(&r)
+		}
+		table.Select(&t, b>>4)
+		r.Add(&r, &t)
+
+		r.Double(&r)
+		r.Double(&r)
+		r.Double(&r)
+		r.Double(&r)
+		table.Select(&t, b&0xf)
+		r.Add(&r, &t)
+	}
+
+	return p.Set(&r), nil
+}
+
+// generatorTable holds the multiples of the generator such that
+// generatorTable[i][j-1] = j * 16^i * G, for i in [0, 63] and j in [1, 15].
+var (
+	generatorTable     *[64]pointTable
+	generatorTableOnce sync.Once
+)
+
+// generatorTables returns the precomputed multiples of the generator, which
+// are computed on first use.
+func generatorTables() *[64]pointTable {
+	generatorTableOnce.Do(func() {
+		generatorTable = new([64]pointTable)
+		initFixedBase(generatorTable, NewGenerator())
+	})
+	return generatorTable
+}
+
+// initFixedBase fills tables such that tables[i][j-1] = j * 16^i * p, for i in
+// [0, 63] and j in [1, 15].
+func initFixedBase(tables *[64]pointTable, p *Point) {
+	base := NewPoint().Set(p)
+	for i := range tables {
+		tables[i].init(base)
+		for j := 0; j < 4; j++ {
+			base.Double(base)
+		}
+	}
+}
+
+// ScalarBaseMult sets p = scalar * G, where G is the generator, and returns p.
+// The scalar is a 32-byte big-endian value, which need not be reduced.
+func (p *Point) ScalarBaseMult(scalar []byte) (*Point, error) {
+	if len(scalar) != 32 {
+		return nil, errors.New("invalid scalar length")
+	}
+	tables := generatorTables()
+
+	// since the tables cover every nibble position, no doubling is needed.
+	var r, t Point
+	r.SetInfinity()
+	for i, b := range scalar {
+		window := 2 * (31 - i)
+		tables[window].Select(&t, b&0xf)
+		r.Add(&r, &t)
+		tables[window+1].Select(&t, b>>4)
+		r.Add(&r, &t)
+	}
+
+	return p.Set(&r), nil
+}
+
+// VarTimeDoubleScalarBaseMult sets p = t * q + s * G, where G is the
+// generator, and returns p. The scalars are 32-byte big-endian values, which
+// need not be reduced.
+//
+// Execution time depends on the inputs, so it must be used with public values
+// only, such as in signature verification.
+func (p *Point) VarTimeDoubleScalarBaseMult(t []byte, q *Point, s []byte) (*Point, error) {
+	if len(t) != 32 || len(s) != 32 {
+		return nil, errors.New("invalid scalar length")
+	}
+
+	var table pointTable
+	table.init(q)
+	return p.varTimeDoubleScalarBaseMult(t, &table, s), nil
+}
+
+// varTimeDoubleScalarBaseMult sets p = t * Q + s * G, where table holds the
+// multiples of Q, and returns p.
+func (p *Point) varTimeDoubleScalarBaseMult(t []byte, table *pointTable, s []byte) *Point {
+	// t * Q with 4-bit windows, skipping zero windows.
+	var r Point
+	r.SetInfinity()
+	for i, b := range t {
+		if i != 0 {
+			r.Double(&r)
+			r.Double(&r)
+			r.Double(&r)
+			r.Double(&r)
+		}
+		if n := b >> 4; n != 0 {
+			r.Add(&r, &table[n-1])
+		}
+		r.Double(&r)
+		r.Double(&r)
+		r.Double(&r)
+		r.Double(&r)
+		if n := b & 0xf; n != 0 {
+			r.Add(&r, &table[n-1])
+		}
+	}
+
+	// s * G with the generator tables, which needs no doubling.
+	varTimeAddFixedBase(&r, generatorTables(), s)
+	return p.Set(&r)
+}
+
+// varTimeAddFixedBase sets r = r + scalar * P, where tables are filled by
+// initFixedBase with P. Zero windows are skipped.
+func varTimeAddFixedBase(r *Point, tables *[64]pointTable, scalar []byte) {
+	for i, b := range scalar {
+		window := 2 * (31 - i)
+		if n := b & 0xf; n != 0 {
+			r.Add(r, &tables[window][n-1])
+		}
+		if n := b >> 4; n != 0 {
+			r.Add(r, &tables[window+1][n-1])
+		}
+	}
+}
+
+// FixedBaseTable holds the precomputed multiples of a fixed point, which
+// trades about 90 KiB of memory for scalar multiplications without doubling.
+// A FixedBaseTable is safe for concurrent use once created.
+type FixedBaseTable struct {
+	tables [64]pointTable
+}
+
+// NewFixedBaseTable returns the precomputed multiples of p.
+func NewFixedBaseTable(p *Point) *FixedBaseTable {
+	t := new(FixedBaseTable)
+	initFixedBase(&t.tables, p)
+	return t
+}
+
+// VarTimeDoubleScalarFixedBaseMult sets p = t * Q + s * G, where G is the
+// generator and table is created with Q, and returns p. The scalars are
+// 32-byte big-endian values, which need not be reduced.
+//
+// Execution time depends on the inputs, so it must be used with public values
+// only, such as in signature verification.
+func (p *Point) VarTimeDoubleScalarFixedBaseMult(t []byte, table *FixedBaseTable, s []byte) (*Point, error) {
+	if len(t) != 32 || len(s) != 32 {
+		return nil, errors.New("invalid scalar length")
+	}
+
+	var r Point
+	r.SetInfinity()
+	varTimeAddFixedBase(&r, &table.tables, t)
+	varTimeAddFixedBase(&r, generatorTables(), s)
+	return p.Set(&r), nil
+}
+
+// VarTimeMultiScalarBaseMult sets p = s * G + scalars[0] * points[0] + ... +
+// scalars[len(points)-1] * points[len(points)-1], where G is the generator,
+// and returns p. The scalars are 32-byte big-endian values, which need not be
+// reduced.
+//
+// The multiples of the points are computed by Straus' method, which shares
+// the doublings among the points with 4-bit windows.
+//
+// Execution time depends on the inputs, so it must be used with public values
+// only, such as in batch signature verification.
+func (p *Point) VarTimeMultiScalarBaseMult(s []byte, scalars [][]byte, points []*Point) (*Point, error) {
+	if len(s) != 32 {
+		return nil, errors.New("invalid scalar length")
+	}
+	if len(scalars) != len(points) {
+		return nil, errors.New("mismatched scalars and points")
+	}
+	for _, k := range scalars {
+		if len(k) != 32 {
+			return nil, errors.New("invalid scalar length")
+		}
+	}
+
+	tables := make([]pointTable, len(points))
+	for i, q := range points {
+		tables[i].init(q)
+	}
+
+	// interleaved 4-bit windows, skipping zero windows and the doublings of
+	// the point at infinity, so that short scalars take fewer doublings.
+	var r Point
+	r.SetInfinity()
+	for i := 0; i < 32; i++ {
+		if r.IsInfinity() == 0 {
+			r.Double(&r)
+			r.Double(&r)
+			r.Double(&r)
+			r.Double(&r)
+		}
+		for j, k := range scalars {
+			if n := k[i] >> 4; n != 0 {
+				r.Add(&r, &tables[j][n-1])
+			}
+		}
+		if r.IsInfinity() == 0 {
+			r.Double(&r)
+			r.Double(&r)
+			r.Double(&r)
+			r.Double(&r)
+		}
+		for j, k := range scalars {
+			if n := k[i] & 0xf; n != 0 {
+				r.Add(&r, &tables[j][n-1])
+			}
+		}
+	}
+
+	// s * G with the generator tables, which needs no doubling.
+	varTimeAddFixedBase(&r, generatorTables(), s)
+	return p.Set(&r), nil
+}
+
+// VarTimeIsSignedSum reports whether p = ±q[0] ± q[1] ± ... ± q[len(q)-1] for
+// some choice of signs. It takes 2^(len(q)-1) point additions, so q must be
+// short.
+//
+// The sums are enumerated in Gray code order, which flips one sign per
+// addition, and compared with p by the x-coordinate, which covers p and -p
+// at once.
+//
+// Execution time depends on the inputs, so it must be used with public values
+// only, such as in batch signature verification.
+func (p *Point) VarTimeIsSignedSum(q []*Point) bool {
+	if len(q) == 0 {
+		return p.IsInfinity() == 1
+	}
+
+	var sum Point
+	sum.SetInfinity()
+	for _, qi := range q {
+		sum.Add(&sum, qi)
+	}
+	if sameX(p, &sum) {
+		return true
+	}
+
+	// flips[j] is added to flip the sign of q[j], which starts as -2 * q[j].
+	flips := make([]Point, len(q))
+	for j := 1; j < len(q); j++ {
+		flips[j].Double(q[j])
+		flips[j].Negate(&flips[j])
+	}
+	for i := uint(1); i < 1<<uint(len(q)-1); i++ {
+		j := bits.TrailingZeros(i) + 1
+		sum.Add(&sum, &flips[j])
+		flips[j].Negate(&flips[j])
+		if sameX(p, &sum) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameX reports whether p1 and p2 are both the point at infinity, or have the
+// same affine x-coordinate, i.e. p1 = ±p2.
+func sameX(p1, p2 *Point) bool {
+	inf1, inf2 := p1.IsInfinity(), p2.IsInfinity()
+	if inf1 == 1 || inf2 == 1 {
+		return inf1 == inf2
+	}
+	var x1, x2 fieldElement
+	x1.Mul(&p1.x, &p2.z)
+	x2.Mul(&p2.x, &p1.z)
+	return x1.Equal(&x2) == 1
+}
+
+// HasAffineX returns 1 if p is not the point at infinity and its affine
+// x-coordinate equals x, a 32-byte big-endian value, and zero otherwise.
+// It avoids the field inversion of the conversion to affine coordinates by
+// comparing X with x * Z.
+func (p *Point) HasAffineX(x []byte) int {
+	e, err := new(fieldElement).SetBytes(x)
+	if err != nil {
+		return 0
+	}
+	e.Mul(e, &p.z)
+	return e.Equal(&p.x) & (1 ^ p.IsInfinity())
+}
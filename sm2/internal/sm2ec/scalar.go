@@ -0,0 +1,216 @@
+package sm2ec
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// Scalar is an integer modulo the order n of the SM2 curve in the Montgomery
+// domain with R = 2^256. It is stored as four 64-bit limbs in little-endian
+// order.
+//
+// The zero value is a valid zero scalar.
+type Scalar [4]uint64
+
+// n is the order of the SM2 curve
+// n = 0xfffffffeffffffffffffffffffffffff7203df6b21c6052b53bbf40939d54123.
+var n = [4]uint64{0x53bbf40939d54123, 0x7203df6b21c6052b, 0xffffffffffffffff, 0xfffffffeffffffff}
+
+// nInv is -n^-1 mod 2^64.
+const nInv = 0x327f9e8872350975
+
+// precomputed scalars.
+var (
+	// scalarRR is R^2 mod n, used for converting into the Montgomery domain.
+	scalarRR = [4]uint64{0x901192af7c114f20, 0x3464504ade6fa2fa, 0x620fc84c3affe0d4, 0x1eb5e412a22b3d3b}
+
+	// nMinus1 is n-1 in little-endian limbs.
+	nMinus1 = [4]uint64{0x53bbf40939d54122, 0x7203df6b21c6052b, 0xffffffffffffffff, 0xfffffffeffffffff}
+
+	// nMinus2 is n-2 in big-endian, used by scalar inversion.
+	nMinus2 = []byte{
+		0xff, 0xff, 0xff, 0xfe, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x72, 0x03, 0xdf, 0x6b, 0x21, 0xc6, 0x05, 0x2b,
+		0x53, 0xbb, 0xf4, 0x09, 0x39, 0xd5, 0x41, 0x21,
+	}
+)
+
+// NewScalar returns a new Scalar set to zero.
+func NewScalar() *Scalar {
+	return new(Scalar)
+}
+
+// Set sets s = x, and returns s.
+func (s *Scalar) Set(x *Scalar) *Scalar {
+	*s = *x
+	return s
+}
+
+// One sets s = 1, and returns s.
+func (s *Scalar) One() *Scalar {
+	one := [4]uint64{1}
+	montMul((*[4]uint64)(s), &one, &scalarRR, &n, nInv)
+	return s
+}
+
+// SetBytes sets s = v, where v is a 32-byte big-endian encoding of an integer
+// in [0, n), and returns s. If v is not 32 bytes or encodes a value not less
+// than n, SetBytes returns nil and an error, and s is unchanged.
+func (s *Scalar) SetBytes(v []byte) (*Scalar, error) {
+	var x [4]uint64
+	if !bytesToLimbs(&x, v, &n) {
+		return nil, errors.New("invalid scalar encoding")
+	}
+	montMul((*[4]uint64)(s), &x, &scalarRR, &n, nInv)
+	return s, nil
+}
+
+// SetReducedBytes sets s = v mod n, where v is a 32-byte big-endian encoding
+// of any integer, and returns s. If v is not 32 bytes, SetReducedBytes returns
+// nil and an error, and s is unchanged.
+func (s *Scalar) SetReducedBytes(v []byte) (*Scalar, error) {
+	if len(v) != 32 {
+		return nil, errors.New("invalid scalar encoding")
+	}
+
+	// v < 2^256 < 2n, thus subtracting n at most once is enough.
+	var x [4]uint64
+	bytesToLimbs(&x, v, &n)
+	var t [4]uint64
+	var b uint64
+	t[0], b = bits.Sub64(x[0], n[0], 0)
+	t[1], b = bits.Sub64(x[1], n[1], b)
+	t[2], b = bits.Sub64(x[2], n[2], b)
+	t[3], b = bits.Sub64(x[3], n[3], b)
+	mask := -b
+	x[0] = (x[0] & mask) | (t[0] &^ mask)
+	x[1] = (x[1] & mask) | (t[1] &^ mask)
+	x[2] = (x[2] & mask) | (t[2] &^ mask)
+	x[3] = (x[3] & mask) | (t[3] &^ mask)
+
+	montMul((*[4]uint64)(s), &x, &scalarRR, &n, nInv)
+	return s, nil
+}
+
+// SetUniformBytes sets s = (v mod (n-1)) + 1, where v is a big-endian
+// encoding of any integer, and returns s. The result is in [1, n-1], and is
+// close to uniformly random if v is at least 40 uniformly random bytes.
+// The running time depends only on the length of v.
+func (s *Scalar) SetUniformBytes(v []byte) *Scalar {
+	var x [4]uint64
+	reduceBytes(&x, v, &nMinus1)
+
+	// x < n-1, thus x + 1 never overflows.
+	var c uint64
+	x[0], c = bits.Add64(x[0], 1, 0)
+	x[1], c = bits.Add64(x[1], 0, c)
+	x[2], c = bits.Add64(x[2], 0, c)
+	x[3], _ = bits.Add64(x[3], 0, c)
+
+	montMul((*[4]uint64)(s), &x, &scalarRR, &n, nInv)
+	return s
+}
+
+// reduceBytes sets x = v mod m, where v is a big-endian encoding of any
+// integer and m > 2^255. The value is reduced bit by bit from the most
+// significant bit, with a conditional subtraction for each bit.
+func reduceBytes(x *[4]uint64, v []byte, m *[4]uint64) {
+	var r [4]uint64
+	for _, byt := range v {
+		for i := 7; i >= 0; i-- {
+			// r = 2r + bit, which is less than 2m < 2^257.
+			top := r[3] >> 63
+			r[3] = r[3]<<1 | r[2]>>63
+			r[2] = r[2]<<1 | r[1]>>63
+			r[1] = r[1]<<1 | r[0]>>63
+			r[0] = r[0]<<1 | uint64(byt>>uint(i))&1
+
+			// subtract m if r >= m, i.e. r - m doesn't borrow.
+			var t [4]uint64
+			var b uint64
+			t[0], b = bits.Sub64(r[0], m[0], 0)
+			t[1], b = bits.Sub64(r[1], m[1], b)
+			t[2], b = bits.Sub64(r[2], m[2], b)
+			t[3], b = bits.Sub64(r[3], m[3], b)
+			_, b = bits.Sub64(top, 0, b)
+			mask := -b
+			r[0] = (r[0] & mask) | (t[0] &^ mask)
+			r[1] = (r[1] & mask) | (t[1] &^ mask)
+			r[2] = (r[2] & mask) | (t[2] &^ mask)
+			r[3] = (r[3] & mask) | (t[3] &^ mask)
+		}
+	}
+	*x = r
+}
+
+// Bytes returns the 32-byte big-endian encoding of s.
+func (s *Scalar) Bytes() []byte {
+	var x [4]uint64
+	var out [32]byte
+	fromMont(&x, (*[4]uint64)(s), &n, nInv)
+	limbsToBytes(&out, &x)
+	return out[:]
+}
+
+// Add sets s = x + y mod n, and returns s.
+func (s *Scalar) Add(x, y *Scalar) *Scalar {
+	modAdd((*[4]uint64)(s), (*[4]uint64)(x), (*[4]uint64)(y), &n)
+	return s
+}
+
+// Sub sets s = x - y mod n, and returns s.
+func (s *Scalar) Sub(x, y *Scalar) *Scalar {
+	modSub((*[4]uint64)(s), (*[4]uint64)(x), (*[4]uint64)(y), &n)
+	return s
+}
+
+// Mul sets s = x * y mod n, and returns s.
+func (s *Scalar) Mul(x, y *Scalar) *Scalar {
+	montMul((*[4]uint64)(s), (*[4]uint64)(x), (*[4]uint64)(y), &n, nInv)
+	return s
+}
+
+// Invert sets s = 1/x mod n, and returns s.
+// If x is zero, s is set to zero.
+// Since n is prime, the inverse is computed as x^(n-2).
+func (s *Scalar) Invert(x *Scalar) *Scalar {
+	// fixed 4-bit window with table[i] = x^(i+1).
+	var table [15]Scalar
+	table[0].Set(x)
+	for i := 1; i < 15; i++ {
+		table[i].Mul(&table[i-1], x)
+	}
+
+	var r Scalar
+	r.One()
+	for _, b := range nMinus2 {
+		r.Mul(&r, &r)
+		r.Mul(&r, &r)
+		r.Mul(&r, &r)
+		r.Mul(&r, &r)
+		if w := b >> 4; w != 0 {
+			r.Mul(&r, &table[w-1])
+		}
+		r.Mul(&r, &r)
+		r.Mul(&r, &r)
+		r.Mul(&r, &r)
+		r.Mul(&r, &r)
+		if w := b & 0xf; w != 0 {
+			r.Mul(&r, &table[w-1])
+		}
+	}
+	return s.Set(&r)
+}
+
+// IsZero returns 1 if s == 0, and zero otherwise.
+func (s *Scalar) IsZero() int {
+	x := s[0] | s[1] | s[2] | s[3]
+	return int(1 ^ (x|-x)>>63)
+}
+
+// Equal returns 1 if s and x are equal, and zero otherwise.
+func (s *Scalar) Equal(x *Scalar) int {
+	d := Scalar{s[0] ^ x[0], s[1] ^ x[1], s[2] ^ x[2], s[3] ^ x[3]}
+	return d.IsZero()
+}
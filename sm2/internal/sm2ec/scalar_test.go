@@ -0,0 +1,144 @@
+package sm2ec
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+var bigN = new(big.Int).SetBytes([]byte{
+	0xff, 0xff, 0xff, 0xfe, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0x72, 0x03, 0xdf, 0x6b, 0x21, 0xc6, 0x05, 0x2b,
+	0x53, 0xbb, 0xf4, 0x09, 0x39, 0xd5, 0x41, 0x23,
+})
+
+func limbsToInt(x *[4]uint64) *big.Int {
+	var out [32]byte
+	limbsToBytes(&out, x)
+	return new(big.Int).SetBytes(out[:])
+}
+
+func TestScalarConstants(t *testing.T) {
+	if got := limbsToInt(&n); got.Cmp(bigN) != 0 {
+		t.Errorf("n = %x, want %x", got, bigN)
+	}
+	r := new(big.Int).Lsh(big.NewInt(1), 256)
+	rr := new(big.Int).Mul(r, r)
+	rr.Mod(rr, bigN)
+	if got := limbsToInt(&scalarRR); got.Cmp(rr) != 0 {
+		t.Errorf("scalarRR = %x, want %x", got, rr)
+	}
+	if n[0]*nInv != ^uint64(0) {
+		t.Errorf("n[0] * nInv = %x, want -1 mod 2^64", n[0]*nInv)
+	}
+	want := new(big.Int).Sub(bigN, big.NewInt(1))
+	if got := limbsToInt(&nMinus1); got.Cmp(want) != 0 {
+		t.Errorf("nMinus1 = %x, want %x", got, want)
+	}
+	want.Sub(want, big.NewInt(1))
+	if got := new(big.Int).SetBytes(nMinus2); got.Cmp(want) != 0 {
+		t.Errorf("nMinus2 = %x, want %x", got, want)
+	}
+}
+
+func randomScalar(t *testing.T) (*Scalar, *big.Int) {
+	b := make([]byte, 40)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	x := new(big.Int).SetBytes(b)
+	x.Mod(x, bigN)
+	s, err := NewScalar().SetBytes(x.FillBytes(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, x
+}
+
+func TestScalarArithmetic(t *testing.T) {
+	for i := 0; i < 64; i++ {
+		x, bx := randomScalar(t)
+		y, by := randomScalar(t)
+		want := new(big.Int)
+		check := func(name string, got *Scalar) {
+			t.Helper()
+			wantBytes := want.FillBytes(make([]byte, 32))
+			if !bytes.Equal(got.Bytes(), wantBytes) {
+				t.Fatalf("%s(%x, %x) = %x, want %x", name, bx, by, got.Bytes(), wantBytes)
+			}
+		}
+
+		want.Add(bx, by).Mod(want, bigN)
+		check("Add", NewScalar().Add(x, y))
+		want.Sub(bx, by).Mod(want, bigN)
+		check("Sub", NewScalar().Sub(x, y))
+		want.Mul(bx, by).Mod(want, bigN)
+		check("Mul", NewScalar().Mul(x, y))
+		want.ModInverse(bx, bigN)
+		check("Invert", NewScalar().Invert(x))
+	}
+
+	if got := NewScalar().Invert(NewScalar()); got.IsZero() != 1 {
+		t.Errorf("Invert(0) = %x, want 0", got.Bytes())
+	}
+	one := NewScalar().One()
+	if got := NewScalar().Invert(one); got.Equal(one) != 1 {
+		t.Errorf("Invert(1) = %x, want 1", got.Bytes())
+	}
+}
+
+func TestScalarSetBytes(t *testing.T) {
+	if _, err := NewScalar().SetBytes(bigN.Bytes()); err == nil {
+		t.Error("SetBytes(n) succeeded, want error")
+	}
+	if _, err := NewScalar().SetBytes(make([]byte, 31)); err == nil {
+		t.Error("SetBytes() with 31 bytes succeeded, want error")
+	}
+
+	max := bytes.Repeat([]byte{0xff}, 32)
+	for _, v := range [][]byte{make([]byte, 32), bigN.Bytes(), max} {
+		want := new(big.Int).SetBytes(v)
+		want.Mod(want, bigN)
+		got, err := NewScalar().SetReducedBytes(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), want.FillBytes(make([]byte, 32))) {
+			t.Errorf("SetReducedBytes(%x) = %x, want %x", v, got.Bytes(), want)
+		}
+	}
+}
+
+func TestScalarSetUniformBytes(t *testing.T) {
+	nMinus1 := new(big.Int).Sub(bigN, big.NewInt(1))
+	random := make([]byte, 40)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range [][]byte{
+		nil,
+		make([]byte, 40),
+		nMinus1.Bytes(),
+		bigN.Bytes(),
+		bytes.Repeat([]byte{0xff}, 40),
+		random,
+	} {
+		want := new(big.Int).SetBytes(v)
+		want.Mod(want, nMinus1)
+		want.Add(want, big.NewInt(1))
+		got := NewScalar().SetUniformBytes(v)
+		if !bytes.Equal(got.Bytes(), want.FillBytes(make([]byte, 32))) {
+			t.Errorf("SetUniformBytes(%x) = %x, want %x", v, got.Bytes(), want)
+		}
+	}
+}
+
+func BenchmarkScalarInvert(b *testing.B) {
+	x := NewScalar().One()
+	x.Add(x, x)
+	for i := 0; i < b.N; i++ {
+		x.Invert(x)
+	}
+}
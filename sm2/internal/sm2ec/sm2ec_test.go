@@ -0,0 +1,322 @@
+package sm2ec
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+var bigP = new(big.Int).SetBytes([]byte{
+	0xff, 0xff, 0xff, 0xfe, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+})
+
+func randomFieldElement(t *testing.T) (*fieldElement, *big.Int) {
+	b := make([]byte, 40)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	x := new(big.Int).SetBytes(b)
+	x.Mod(x, bigP)
+	e, err := new(fieldElement).SetBytes(x.FillBytes(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e, x
+}
+
+func TestFieldArithmetic(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		x, bigX := randomFieldElement(t)
+		y, bigY := randomFieldElement(t)
+
+		want := new(big.Int)
+		check := func(op string, got *fieldElement) {
+			t.Helper()
+			if b := new(big.Int).SetBytes(got.Bytes()); b.Cmp(want) != 0 {
+				t.Fatalf("%s(%x, %x) = %x, want %x", op, bigX, bigY, b, want)
+			}
+		}
+
+		want.Add(bigX, bigY).Mod(want, bigP)
+		check("Add", new(fieldElement).Add(x, y))
+		want.Sub(bigX, bigY).Mod(want, bigP)
+		check("Sub", new(fieldElement).Sub(x, y))
+		want.Mul(bigX, bigY).Mod(want, bigP)
+		check("Mul", new(fieldElement).Mul(x, y))
+		want.ModInverse(bigX, bigP)
+		check("Invert", new(fieldElement).Invert(x))
+
+		// x^2 is always a square.
+		sq := new(fieldElement).Square(x)
+		root, ok := new(fieldElement).Sqrt(sq)
+		if ok != 1 {
+			t.Fatalf("Sqrt(%x^2) reports non-square", bigX)
+		}
+		if root.Square(root).Equal(sq) != 1 {
+			t.Fatalf("Sqrt(%x^2) returns a wrong root", bigX)
+		}
+	}
+}
+
+func TestFieldSetBytes(t *testing.T) {
+	if _, err := new(fieldElement).SetBytes(bigP.Bytes()); err == nil {
+		t.Error("SetBytes(p) succeeded, want error")
+	}
+	if _, err := new(fieldElement).SetBytes(make([]byte, 31)); err == nil {
+		t.Error("SetBytes(31 bytes) succeeded, want error")
+	}
+}
+
+func TestPointEncoding(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		k := make([]byte, 32)
+		if _, err := rand.Read(k); err != nil {
+			t.Fatal(err)
+		}
+		p, err := NewPoint().ScalarBaseMult(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		uncompressed := p.Bytes()
+		q, err := NewPoint().SetBytes(uncompressed)
+		if err != nil {
+			t.Fatalf("SetBytes(%x) error = %v", uncompressed, err)
+		}
+		if got := q.Bytes(); !bytes.Equal(got, uncompressed) {
+			t.Fatalf("SetBytes(%x).Bytes() = %x", uncompressed, got)
+		}
+
+		compressed := p.BytesCompressed()
+		q, err = NewPoint().SetBytes(compressed)
+		if err != nil {
+			t.Fatalf("SetBytes(%x) error = %v", compressed, err)
+		}
+		if got := q.Bytes(); !bytes.Equal(got, uncompressed) {
+			t.Fatalf("SetBytes(%x).Bytes() = %x, want %x", compressed, got, uncompressed)
+		}
+	}
+
+	// the point at infinity
+	p := NewPoint()
+	if got := p.Bytes(); !bytes.Equal(got, []byte{0}) {
+		t.Errorf("NewPoint().Bytes() = %x, want 00", got)
+	}
+
+	// a point not on the curve
+	invalid := NewGenerator().Bytes()
+	invalid[len(invalid)-1] ^= 1
+	if _, err := NewPoint().SetBytes(invalid); err == nil {
+		t.Errorf("SetBytes(%x) succeeded, want error", invalid)
+	}
+}
+
+func TestScalarMult(t *testing.T) {
+	k := make([]byte, 32)
+	for i := 0; i < 16; i++ {
+		if _, err := rand.Read(k); err != nil {
+			t.Fatal(err)
+		}
+		want, err := NewPoint().ScalarBaseMult(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := NewPoint().ScalarMult(NewGenerator(), k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Fatalf("ScalarMult(G, %x) = %x, want %x", k, got.Bytes(), want.Bytes())
+		}
+	}
+}
+
+func TestVarTimeDoubleScalarBaseMult(t *testing.T) {
+	k := make([]byte, 32)
+	if _, err := rand.Read(k); err != nil {
+		t.Fatal(err)
+	}
+	q, err := NewPoint().ScalarBaseMult(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := NewFixedBaseTable(q)
+
+	zero := make([]byte, 32)
+	s := make([]byte, 32)
+	u := make([]byte, 32)
+	for i := 0; i < 16; i++ {
+		if _, err := rand.Read(s); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rand.Read(u); err != nil {
+			t.Fatal(err)
+		}
+		for _, scalars := range [][2][]byte{{u, s}, {zero, s}, {u, zero}, {zero, zero}} {
+			u, s := scalars[0], scalars[1]
+			sg, err := NewPoint().ScalarBaseMult(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			uq, err := NewPoint().ScalarMult(q, u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := NewPoint().Add(uq, sg)
+			got, err := NewPoint().VarTimeDoubleScalarBaseMult(u, q, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Fatalf("VarTimeDoubleScalarBaseMult(%x, Q, %x) = %x, want %x", u, s, got.Bytes(), want.Bytes())
+			}
+			got, err = NewPoint().VarTimeDoubleScalarFixedBaseMult(u, table, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Fatalf("VarTimeDoubleScalarFixedBaseMult(%x, Q, %x) = %x, want %x", u, s, got.Bytes(), want.Bytes())
+			}
+		}
+	}
+}
+
+func TestHasAffineX(t *testing.T) {
+	p := NewGenerator()
+	p.Double(p) // projective Z != 1
+	x := p.Bytes()[1 : 1+fieldSize]
+	if p.HasAffineX(x) != 1 {
+		t.Errorf("HasAffineX(%x) = 0, want 1", x)
+	}
+
+	other := append([]byte{}, x...)
+	other[fieldSize-1] ^= 1
+	if p.HasAffineX(other) != 0 {
+		t.Errorf("HasAffineX(%x) = 1, want 0", other)
+	}
+	if p.HasAffineX(bigP.Bytes()) != 0 {
+		t.Errorf("HasAffineX(p) = 1, want 0")
+	}
+	if NewPoint().HasAffineX(make([]byte, fieldSize)) != 0 {
+		t.Errorf("HasAffineX(0) on infinity = 1, want 0")
+	}
+}
+
+func BenchmarkFieldMul(b *testing.B) {
+	x, y := generatorX, generatorY
+	for i := 0; i < b.N; i++ {
+		x.Mul(&x, &y)
+	}
+}
+
+func BenchmarkFieldInvert(b *testing.B) {
+	x := generatorX
+	for i := 0; i < b.N; i++ {
+		x.Invert(&x)
+	}
+}
+
+func TestVarTimeMultiScalarBaseMult(t *testing.T) {
+	zero := make([]byte, 32)
+	for _, n := range []int{0, 1, 2, 5} {
+		s := make([]byte, 32)
+		if _, err := rand.Read(s); err != nil {
+			t.Fatal(err)
+		}
+		want, err := NewPoint().ScalarBaseMult(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scalars := make([][]byte, n)
+		points := make([]*Point, n)
+		for i := range points {
+			k := make([]byte, 32)
+			if _, err := rand.Read(k); err != nil {
+				t.Fatal(err)
+			}
+			q, err := NewPoint().ScalarBaseMult(k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// a zero scalar, a full scalar, and 128-bit scalars.
+			scalars[i] = make([]byte, 32)
+			switch i {
+			case 1:
+			case 2:
+				copy(scalars[i], k)
+			default:
+				if _, err := rand.Read(scalars[i][16:]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			points[i] = q
+			kq, err := NewPoint().ScalarMult(q, scalars[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			want.Add(want, kq)
+		}
+		got, err := NewPoint().VarTimeMultiScalarBaseMult(s, scalars, points)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Errorf("VarTimeMultiScalarBaseMult() with %d points = %x, want %x", n, got.Bytes(), want.Bytes())
+		}
+	}
+
+	if _, err := NewPoint().VarTimeMultiScalarBaseMult(zero, [][]byte{zero}, nil); err == nil {
+		t.Error("VarTimeMultiScalarBaseMult() with mismatched lengths succeeded, want error")
+	}
+	if _, err := NewPoint().VarTimeMultiScalarBaseMult(zero, [][]byte{zero[1:]}, []*Point{NewGenerator()}); err == nil {
+		t.Error("VarTimeMultiScalarBaseMult() with a short scalar succeeded, want error")
+	}
+}
+
+func TestVarTimeIsSignedSum(t *testing.T) {
+	q := make([]*Point, 5)
+	for i := range q {
+		k := make([]byte, 32)
+		if _, err := rand.Read(k); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if q[i], err = NewPoint().ScalarBaseMult(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// every choice of signs is found.
+	for signs := 0; signs < 1<<uint(len(q)); signs++ {
+		p := NewPoint()
+		for i, qi := range q {
+			if signs>>uint(i)&1 == 1 {
+				p.Add(p, NewPoint().Negate(qi))
+			} else {
+				p.Add(p, qi)
+			}
+		}
+		if !p.VarTimeIsSignedSum(q) {
+			t.Errorf("VarTimeIsSignedSum() with signs %05b = false, want true", signs)
+		}
+	}
+
+	p := NewPoint().Add(q[0], q[0])
+	if p.VarTimeIsSignedSum(q) {
+		t.Error("VarTimeIsSignedSum() = true, want false")
+	}
+	if !NewPoint().VarTimeIsSignedSum(nil) {
+		t.Error("VarTimeIsSignedSum(nil) on infinity = false, want true")
+	}
+	if NewGenerator().VarTimeIsSignedSum(nil) {
+		t.Error("VarTimeIsSignedSum(nil) on G = true, want false")
+	}
+	if !NewPoint().VarTimeIsSignedSum([]*Point{q[0], q[0]}) {
+		t.Error("VarTimeIsSignedSum(Q, Q) on infinity = false, want true")
+	}
+}
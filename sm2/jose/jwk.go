@@ -0,0 +1,174 @@
+// Package jose implements JSON Web Keys (RFC 7517), JSON Web Signatures
+// (RFC 7515) and JSON Web Tokens (RFC 7519) for SM2 keys, with the "SM2SM3"
+// algorithm for SM2 signatures with SM3.
+//
+// SM2 keys are represented as JWKs of the key type "EC" on the curve "SM2",
+// whose coordinates and private key are encoded in the same way as the NIST
+// curves in RFC 7518 6.2.
+//
+// An "SM2SM3" signature is the raw signature r || s returned by sm2.Sign on
+// the JWS signing input, where r and s are 32 bytes each. The hash value Z
+// depends on the ID of the signer, which is not carried in any JWS or JWK
+// member. The ID of the private key is used in signing, and the ID of the
+// public key in verification, so that both parties must agree on the ID out
+// of band. If the ID is not set, sm2.DefaultID is used, as OpenSSL and GmSSL
+// do.
+package jose
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2"
+)
+
+// Identifiers of SM2 registered by this package.
+const (
+	KeyTypeEC    = "EC"     // key type of SM2 keys
+	CurveSM2     = "SM2"    // curve name of SM2 keys
+	AlgorithmSM2 = "SM2SM3" // algorithm of SM2 signatures with SM3
+)
+
+// coordinateSize is the size of an encoded coordinate or private key.
+const coordinateSize = 32
+
+// JWK is a JSON Web Key of an SM2 public or private key.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	D         string `json:"d,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+}
+
+// NewJWK converts an *sm2.PublicKey or *sm2.PrivateKey to a JWK.
+func NewJWK(key interface{}) (*JWK, error) {
+	var pub *sm2.PublicKey
+	var d *big.Int
+	switch key := key.(type) {
+	case *sm2.PublicKey:
+		pub = key
+	case *sm2.PrivateKey:
+		pub = &key.PublicKey
+		d = key.D
+	default:
+		return nil, errors.New("sm2/jose: unsupported key type")
+	}
+	if pub.Curve != sm2.Curve() {
+		return nil, errors.New("sm2/jose: unsupported elliptic curve")
+	}
+	b, err := pub.Bytes(sm2.UncompressedPoint)
+	if err != nil {
+		return nil, err
+	}
+	jwk := &JWK{
+		KeyType: KeyTypeEC,
+		Curve:   CurveSM2,
+		X:       base64.RawURLEncoding.EncodeToString(b[1 : 1+coordinateSize]),
+		Y:       base64.RawURLEncoding.EncodeToString(b[1+coordinateSize:]),
+	}
+	if d != nil {
+		if d.Sign() <= 0 || d.BitLen() > 8*coordinateSize {
+			return nil, errors.New("sm2/jose: invalid private key")
+		}
+		jwk.D = base64.RawURLEncoding.EncodeToString(d.FillBytes(make([]byte, coordinateSize)))
+	}
+	return jwk, nil
+}
+
+// ParseJWK parses a JWK in JSON.
+func ParseJWK(data []byte) (*JWK, error) {
+	var jwk JWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, err
+	}
+	return &jwk, nil
+}
+
+// PublicKey returns the public key of the JWK, which is validated as
+// specified in GB/T 32918.1-2016 6.2.1.
+func (k *JWK) PublicKey() (*sm2.PublicKey, error) {
+	if k.KeyType != KeyTypeEC || k.Curve != CurveSM2 {
+		return nil, errors.New("sm2/jose: not an SM2 key")
+	}
+	x, err := decodeCoordinate(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeCoordinate(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, 1+2*coordinateSize)
+	b = append(b, 4)
+	b = append(b, x...)
+	b = append(b, y...)
+	return sm2.ParsePublicKey(b)
+}
+
+// PrivateKey returns the private key of the JWK, which is validated to match
+// the public key.
+func (k *JWK) PrivateKey() (*sm2.PrivateKey, error) {
+	pub, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if k.D == "" {
+		return nil, errors.New("sm2/jose: not a private key")
+	}
+	d, err := decodeCoordinate(k.D)
+	if err != nil {
+		return nil, err
+	}
+	priv := &sm2.PrivateKey{
+		PublicKey: *pub,
+		D:         new(big.Int).SetBytes(d),
+	}
+	if err := priv.Validate(); err != nil {
+		return nil, err
+	}
+	return priv, nil
+}
+
+// Public returns the JWK of the public key only.
+func (k *JWK) Public() *JWK {
+	pub := *k
+	pub.D = ""
+	return &pub
+}
+
+// Thumbprint computes the JWK thumbprint defined in RFC 7638 with h, e.g.
+// sm3.New() or sha256.New().
+func (k *JWK) Thumbprint(h hash.Hash) ([]byte, error) {
+	// the required members in lexicographic order, without whitespace.
+	b, err := json.Marshal(struct {
+		Curve   string `json:"crv"`
+		KeyType string `json:"kty"`
+		X       string `json:"x"`
+		Y       string `json:"y"`
+	}{k.Curve, k.KeyType, k.X, k.Y})
+	if err != nil {
+		return nil, err
+	}
+	h.Reset()
+	h.Write(b)
+	return h.Sum(nil), nil
+}
+
+// decodeCoordinate decodes a coordinate or private key of full length.
+func decodeCoordinate(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != coordinateSize {
+		return nil, errors.New("sm2/jose: invalid coordinate length")
+	}
+	return b, nil
+}
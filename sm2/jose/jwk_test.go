@@ -0,0 +1,130 @@
+package jose
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/need-being/gmcrypto/sm2"
+)
+
+// testJWK is the key pair from GB/T 32918.5-2017 A.2.
+const testJWK = `{"kty":"EC","crv":"SM2","x":"CfnfMR5UIaFQ3X0WHkvFxnIXn60YM_wHa7CP81bzUCA","y":"zOpJDOJndaUtxupxjMGqYArtBfvzXghKZjL2By2prRM","d":"OUUgj3shRLE_NuOKxtOflYiTk2koYLUaQvuB7033xbg"}`
+
+func testKey(t *testing.T) *sm2.PrivateKey {
+	t.Helper()
+	jwk, err := ParseJWK([]byte(testJWK))
+	if err != nil {
+		t.Fatal("ParseJWK:", err)
+	}
+	priv, err := jwk.PrivateKey()
+	if err != nil {
+		t.Fatal("JWK.PrivateKey:", err)
+	}
+	return priv
+}
+
+func TestJWK(t *testing.T) {
+	priv := testKey(t)
+	if got, want := hex.EncodeToString(priv.D.Bytes()), "3945208f7b2144b13f36e38ac6d39f95889393692860b51a42fb81ef4df7c5b8"; got != want {
+		t.Errorf("JWK.PrivateKey().D = %s, want %s", got, want)
+	}
+
+	jwk, err := NewJWK(priv)
+	if err != nil {
+		t.Fatal("NewJWK:", err)
+	}
+	b, err := json.Marshal(jwk)
+	if err != nil {
+		t.Fatal("json.Marshal:", err)
+	}
+	if got := string(b); got != testJWK {
+		t.Errorf("NewJWK() = %s, want %s", got, testJWK)
+	}
+
+	pubJWK, err := NewJWK(&priv.PublicKey)
+	if err != nil {
+		t.Fatal("NewJWK:", err)
+	}
+	if !reflect.DeepEqual(pubJWK, jwk.Public()) {
+		t.Errorf("NewJWK(public key) = %v, want %v", pubJWK, jwk.Public())
+	}
+	pub, err := pubJWK.PublicKey()
+	if err != nil {
+		t.Fatal("JWK.PublicKey:", err)
+	}
+	if !pub.Equal(&priv.PublicKey) {
+		t.Errorf("JWK.PublicKey() = %v, want %v", pub, &priv.PublicKey)
+	}
+	if _, err := pubJWK.PrivateKey(); err == nil {
+		t.Error("JWK.PrivateKey() of a public key succeeded, want error")
+	}
+
+	// RFC 7638 3.1
+	input := `{"crv":"SM2","kty":"EC","x":"CfnfMR5UIaFQ3X0WHkvFxnIXn60YM_wHa7CP81bzUCA","y":"zOpJDOJndaUtxupxjMGqYArtBfvzXghKZjL2By2prRM"}`
+	want := sha256.Sum256([]byte(input))
+	got, err := jwk.Thumbprint(sha256.New())
+	if err != nil {
+		t.Fatal("JWK.Thumbprint:", err)
+	}
+	if !reflect.DeepEqual(got, want[:]) {
+		t.Errorf("JWK.Thumbprint() = %x, want %x", got, want)
+	}
+}
+
+func TestJWK_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		jwk  string
+	}{
+		{
+			name: "key type",
+			jwk:  `{"kty":"OKP","crv":"SM2","x":"CfnfMR5UIaFQ3X0WHkvFxnIXn60YM_wHa7CP81bzUCA","y":"zOpJDOJndaUtxupxjMGqYArtBfvzXghKZjL2By2prRM","d":"OUUgj3shRLE_NuOKxtOflYiTk2koYLUaQvuB7033xbg"}`,
+		},
+		{
+			name: "curve",
+			jwk:  `{"kty":"EC","crv":"P-256","x":"CfnfMR5UIaFQ3X0WHkvFxnIXn60YM_wHa7CP81bzUCA","y":"zOpJDOJndaUtxupxjMGqYArtBfvzXghKZjL2By2prRM","d":"OUUgj3shRLE_NuOKxtOflYiTk2koYLUaQvuB7033xbg"}`,
+		},
+		{
+			name: "not on curve",
+			jwk:  `{"kty":"EC","crv":"SM2","x":"CfnfMR5UIaFQ3X0WHkvFxnIXn60YM_wHa7CP81bzUCA","y":"zOpJDOJndaUtxupxjMGqYArtBfvzXghKZjL2By2prRQ","d":"OUUgj3shRLE_NuOKxtOflYiTk2koYLUaQvuB7033xbg"}`,
+		},
+		{
+			name: "short coordinate",
+			jwk:  `{"kty":"EC","crv":"SM2","x":"-d8xHlQhoVDdfRYeS8XGchefrRgz_AdrsI_zVvNQIA","y":"zOpJDOJndaUtxupxjMGqYArtBfvzXghKZjL2By2prRM","d":"OUUgj3shRLE_NuOKxtOflYiTk2koYLUaQvuB7033xbg"}`,
+		},
+		{
+			name: "padded coordinate",
+			jwk:  `{"kty":"EC","crv":"SM2","x":"CfnfMR5UIaFQ3X0WHkvFxnIXn60YM_wHa7CP81bzUCA=","y":"zOpJDOJndaUtxupxjMGqYArtBfvzXghKZjL2By2prRM","d":"OUUgj3shRLE_NuOKxtOflYiTk2koYLUaQvuB7033xbg"}`,
+		},
+		{
+			name: "private key mismatch",
+			jwk:  `{"kty":"EC","crv":"SM2","x":"CfnfMR5UIaFQ3X0WHkvFxnIXn60YM_wHa7CP81bzUCA","y":"zOpJDOJndaUtxupxjMGqYArtBfvzXghKZjL2By2prRM","d":"OUUgj3shRLE_NuOKxtOflYiTk2koYLUaQvuB7033xbk"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwk, err := ParseJWK([]byte(tt.jwk))
+			if err != nil {
+				t.Fatal("ParseJWK:", err)
+			}
+			if priv, err := jwk.PrivateKey(); err == nil {
+				t.Errorf("JWK.PrivateKey() = %v, want error", priv)
+			}
+		})
+	}
+
+	other, err := sm2.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	for _, key := range []interface{}{other, &other.PublicKey, other.D, nil} {
+		if jwk, err := NewJWK(key); err == nil {
+			t.Errorf("NewJWK(%T) = %v, want error", key, jwk)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package jose
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/need-being/gmcrypto/sm2"
+)
+
+// Header is the header of a JWS, whose members are JSON values.
+type Header map[string]interface{}
+
+// jsonSignature is a signature in the JWS JSON serialization.
+type jsonSignature struct {
+	Protected string `json:"protected,omitempty"`
+	Header    Header `json:"header,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// jsonJWS is a JWS in the general or flattened JWS JSON serialization.
+type jsonJWS struct {
+	Payload    string          `json:"payload"`
+	Signatures []jsonSignature `json:"signatures,omitempty"`
+	jsonSignature
+}
+
+// Sign signs the payload with "SM2SM3", and returns the JWS compact
+// serialization. The header, which may be nil, is integrity protected, and
+// its "alg" member is set to "SM2SM3".
+func Sign(rand io.Reader, priv *sm2.PrivateKey, payload []byte, header Header) (string, error) {
+	protected, sig, err := sign(rand, priv, payload, header)
+	if err != nil {
+		return "", err
+	}
+	return protected + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + sig, nil
+}
+
+// SignJSON signs the payload with "SM2SM3", and returns the flattened JWS JSON
+// serialization. The header, which may be nil, is integrity protected, and
+// its "alg" member is set to "SM2SM3".
+func SignJSON(rand io.Reader, priv *sm2.PrivateKey, payload []byte, header Header) ([]byte, error) {
+	protected, sig, err := sign(rand, priv, payload, header)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonJWS{
+		Payload: base64.RawURLEncoding.EncodeToString(payload),
+		jsonSignature: jsonSignature{
+			Protected: protected,
+			Signature: sig,
+		},
+	})
+}
+
+// sign returns the encoded protected header and the encoded signature.
+func sign(rand io.Reader, priv *sm2.PrivateKey, payload []byte, header Header) (string, string, error) {
+	if alg, ok := header["alg"]; ok && alg != AlgorithmSM2 {
+		return "", "", errors.New("sm2/jose: unsupported algorithm")
+	}
+	if _, ok := header["crit"]; ok {
+		return "", "", errors.New("sm2/jose: critical header parameters not supported")
+	}
+	protected := Header{"alg": AlgorithmSM2}
+	for name, value := range header {
+		protected[name] = value
+	}
+	b, err := json.Marshal(protected)
+	if err != nil {
+		return "", "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(b)
+	sig, err := sm2.Sign(rand, priv, signingInput(encoded, base64.RawURLEncoding.EncodeToString(payload)))
+	if err != nil {
+		return "", "", err
+	}
+	return encoded, base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// signingInput returns ASCII(BASE64URL(UTF8(JWS Protected Header)) || '.' ||
+// BASE64URL(JWS Payload)).
+func signingInput(protected, payload string) []byte {
+	return []byte(protected + "." + payload)
+}
+
+// Verify verifies a JWS in the compact serialization signed with "SM2SM3",
+// and returns its payload and header.
+func Verify(pub *sm2.PublicKey, jws string) ([]byte, Header, error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		return nil, nil, errors.New("sm2/jose: invalid compact serialization")
+	}
+	header, err := verify(pub, parts[0], parts[1], parts[2], nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return payload, header, nil
+}
+
+// VerifyJSON verifies a JWS in the general or flattened JSON serialization,
+// and returns its payload and the protected header of the first signature
+// verified with "SM2SM3". Signatures of other algorithms or keys are
+// skipped.
+func VerifyJSON(pub *sm2.PublicKey, jws []byte) ([]byte, Header, error) {
+	var v jsonJWS
+	if err := json.Unmarshal(jws, &v); err != nil {
+		return nil, nil, err
+	}
+	sigs := v.Signatures
+	if v.Protected != "" || v.Header != nil || v.Signature != "" {
+		if len(sigs) != 0 {
+			return nil, nil, errors.New("sm2/jose: mixed general and flattened serialization")
+		}
+		sigs = []jsonSignature{v.jsonSignature}
+	}
+	if len(sigs) == 0 {
+		return nil, nil, errors.New("sm2/jose: no signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(v.Payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = errors.New("sm2/jose: no signature verified")
+	for _, sig := range sigs {
+		var header Header
+		if header, err = verify(pub, sig.Protected, v.Payload, sig.Signature, sig.Header); err == nil {
+			return payload, header, nil
+		}
+	}
+	return nil, nil, err
+}
+
+// verify verifies a signature over the encoded protected header and payload,
+// and returns the protected header.
+func verify(pub *sm2.PublicKey, protected, payload, signature string, unprotected Header) (Header, error) {
+	b, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
+		return nil, err
+	}
+	var header Header
+	if err := json.Unmarshal(b, &header); err != nil {
+		return nil, err
+	}
+	if header["alg"] != AlgorithmSM2 {
+		return nil, errors.New("sm2/jose: unsupported algorithm")
+	}
+	if _, ok := header["crit"]; ok {
+		return nil, errors.New("sm2/jose: critical header parameters not supported")
+	}
+	for name := range unprotected {
+		if _, ok := header[name]; ok {
+			return nil, errors.New("sm2/jose: duplicate header parameter")
+		}
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, err
+	}
+	if !sm2.Verify(pub, signingInput(protected, payload), sig) {
+		return nil, errors.New("sm2/jose: invalid signature")
+	}
+	return header, nil
+}
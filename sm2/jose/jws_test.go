@@ -0,0 +1,210 @@
+package jose
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/need-being/gmcrypto/sm2"
+)
+
+// testJWS is signed by testJWK with sm2.DefaultID, and verified by OpenSSL 3.0
+// with `openssl dgst -sm3 -verify pub.pem -sigopt distid:1234567812345678`.
+const testJWS = "eyJhbGciOiJTTTJTTTMiLCJraWQiOiJBLjIifQ.SXQncyBhIGRhbmdlcm91cyBidXNpbmVzcywgRnJvZG8sIGdvaW5nIG91dCB5b3VyIGRvb3Iu.ctEUZx-kHnupljBo5MZHqWOd4A5nIZC9R2dxPVyfJ9sdhoSLFcl1Q6wKQjscSiQF8X1_rEQkA1p_ZJOtEGnEYg"
+
+const testPayload = "It's a dangerous business, Frodo, going out your door."
+
+func TestVerify(t *testing.T) {
+	pub := &testKey(t).PublicKey
+	payload, header, err := Verify(pub, testJWS)
+	if err != nil {
+		t.Fatal("Verify:", err)
+	}
+	if got := string(payload); got != testPayload {
+		t.Errorf("Verify() payload = %q, want %q", got, testPayload)
+	}
+	if header["kid"] != "A.2" {
+		t.Errorf("Verify() header = %v, want kid A.2", header)
+	}
+
+	// the signature depends on the ID.
+	other := *pub
+	other.ID = []byte("ALICE123@YAHOO.COM")
+	if _, _, err := Verify(&other, testJWS); err == nil {
+		t.Error("Verify() with another ID succeeded, want error")
+	}
+}
+
+func TestSign(t *testing.T) {
+	priv := testKey(t)
+	priv.ID = []byte("ALICE123@YAHOO.COM")
+	payload := []byte(testPayload)
+
+	jws, err := Sign(rand.Reader, priv, payload, Header{"kid": "A.2", "typ": "JOSE"})
+	if err != nil {
+		t.Fatal("Sign:", err)
+	}
+	got, header, err := Verify(&priv.PublicKey, jws)
+	if err != nil {
+		t.Fatal("Verify:", err)
+	}
+	if string(got) != testPayload {
+		t.Errorf("Verify() payload = %q, want %q", got, testPayload)
+	}
+	if header["alg"] != AlgorithmSM2 || header["kid"] != "A.2" || header["typ"] != "JOSE" {
+		t.Errorf("Verify() header = %v", header)
+	}
+
+	parts := strings.Split(jws, ".")
+	tests := []struct {
+		name string
+		jws  string
+	}{
+		{
+			name: "tampered payload",
+			jws:  parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte("tampered")) + "." + parts[2],
+		},
+		{
+			name: "tampered header",
+			jws:  base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"SM2SM3"}`)) + "." + parts[1] + "." + parts[2],
+		},
+		{
+			name: "other algorithm",
+			jws:  base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256"}`)) + "." + parts[1] + "." + parts[2],
+		},
+		{
+			name: "no signature",
+			jws:  parts[0] + "." + parts[1] + ".",
+		},
+		{
+			name: "too many parts",
+			jws:  jws + ".",
+		},
+		{
+			name: "padded signature",
+			jws:  jws + "==",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if payload, _, err := Verify(&priv.PublicKey, tt.jws); err == nil {
+				t.Errorf("Verify() = %q, want error", payload)
+			}
+		})
+	}
+
+	for _, header := range []Header{{"alg": "ES256"}, {"crit": []string{"b64"}, "b64": false}} {
+		if jws, err := Sign(rand.Reader, priv, payload, header); err == nil {
+			t.Errorf("Sign(%v) = %s, want error", header, jws)
+		}
+	}
+}
+
+func TestSignJSON(t *testing.T) {
+	priv := testKey(t)
+	payload := []byte(testPayload)
+	jws, err := SignJSON(rand.Reader, priv, payload, Header{"kid": "A.2"})
+	if err != nil {
+		t.Fatal("SignJSON:", err)
+	}
+	got, header, err := VerifyJSON(&priv.PublicKey, jws)
+	if err != nil {
+		t.Fatal("VerifyJSON:", err)
+	}
+	if string(got) != testPayload || header["kid"] != "A.2" {
+		t.Errorf("VerifyJSON() = %q, %v", got, header)
+	}
+
+	// general serialization with a signature by another key first.
+	other, err := sm2.GenerateKey(sm2.Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	otherJWS, err := SignJSON(rand.Reader, other, payload, nil)
+	if err != nil {
+		t.Fatal("SignJSON:", err)
+	}
+	var flattened, otherFlattened jsonJWS
+	if err := json.Unmarshal(jws, &flattened); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(otherJWS, &otherFlattened); err != nil {
+		t.Fatal(err)
+	}
+	general, err := json.Marshal(jsonJWS{
+		Payload: flattened.Payload,
+		Signatures: []jsonSignature{
+			otherFlattened.jsonSignature,
+			{
+				Protected: flattened.Protected,
+				Header:    Header{"x-note": "unprotected"},
+				Signature: flattened.Signature,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, header, err = VerifyJSON(&priv.PublicKey, general)
+	if err != nil {
+		t.Fatal("VerifyJSON:", err)
+	}
+	if string(got) != testPayload || header["kid"] != "A.2" {
+		t.Errorf("VerifyJSON() = %q, %v", got, header)
+	}
+
+	tests := []struct {
+		name string
+		jws  jsonJWS
+	}{
+		{
+			name: "tampered payload",
+			jws: jsonJWS{
+				Payload:       base64.RawURLEncoding.EncodeToString([]byte("tampered")),
+				jsonSignature: flattened.jsonSignature,
+			},
+		},
+		{
+			name: "duplicate header parameter",
+			jws: jsonJWS{
+				Payload: flattened.Payload,
+				jsonSignature: jsonSignature{
+					Protected: flattened.Protected,
+					Header:    Header{"kid": "other"},
+					Signature: flattened.Signature,
+				},
+			},
+		},
+		{
+			name: "mixed serialization",
+			jws: jsonJWS{
+				Payload:       flattened.Payload,
+				Signatures:    []jsonSignature{flattened.jsonSignature},
+				jsonSignature: flattened.jsonSignature,
+			},
+		},
+		{
+			name: "no signature",
+			jws: jsonJWS{
+				Payload: flattened.Payload,
+			},
+		},
+		{
+			name: "other key only",
+			jws:  otherFlattened,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.jws)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if payload, _, err := VerifyJSON(&priv.PublicKey, b); err == nil {
+				t.Errorf("VerifyJSON() = %q, want error", payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package jose
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"github.com/need-being/gmcrypto/sm2"
+)
+
+// Claims are the registered claims of a JWT defined in RFC 7519 4.1.
+// The times are NumericDate values, where zero means absent. Claims may be
+// embedded in a struct with private claims.
+type Claims struct {
+	Issuer    string      `json:"iss,omitempty"`
+	Subject   string      `json:"sub,omitempty"`
+	Audience  Audience    `json:"aud,omitempty"`
+	ExpiresAt NumericDate `json:"exp,omitempty"`
+	NotBefore NumericDate `json:"nbf,omitempty"`
+	IssuedAt  NumericDate `json:"iat,omitempty"`
+	ID        string      `json:"jti,omitempty"`
+}
+
+// Validate checks the time claims at now, allowing leeway for clock skew.
+// Other claims, such as the issuer and the audience, are left to the caller.
+func (c *Claims) Validate(now time.Time, leeway time.Duration) error {
+	if c.ExpiresAt != 0 && !now.Add(-leeway).Before(c.ExpiresAt.Time()) {
+		return errors.New("sm2/jose: token expired")
+	}
+	if c.NotBefore != 0 && now.Add(leeway).Before(c.NotBefore.Time()) {
+		return errors.New("sm2/jose: token not valid yet")
+	}
+	return nil
+}
+
+// NumericDate is the number of seconds since the epoch defined in RFC 7519 2.
+// It is encoded as an integer in JSON, and decoded from any JSON number, where
+// fractional seconds are truncated.
+type NumericDate int64
+
+// Time returns the time of d.
+func (d NumericDate) Time() time.Time {
+	return time.Unix(int64(d), 0)
+}
+
+// UnmarshalJSON decodes a JSON number, truncating fractional seconds.
+func (d *NumericDate) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var n json.Number
+	if len(b) == 0 || b[0] == '"' || json.Unmarshal(b, &n) != nil {
+		return errors.New("sm2/jose: invalid NumericDate")
+	}
+	if i, err := n.Int64(); err == nil {
+		*d = NumericDate(i)
+		return nil
+	}
+	f, err := n.Float64()
+	if err != nil || f < math.MinInt64 || f >= math.MaxInt64 {
+		return errors.New("sm2/jose: invalid NumericDate")
+	}
+	*d = NumericDate(f) // truncated toward zero
+	return nil
+}
+
+// Audience is the "aud" claim, which is either a single string or an array
+// of strings in JSON.
+type Audience []string
+
+// Contains reports whether aud is one of the audience.
+func (a Audience) Contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON encodes a single audience as a string, and others as an array.
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON decodes a string or an array of strings.
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+	var v []string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return errors.New("sm2/jose: invalid audience")
+	}
+	*a = v
+	return nil
+}
+
+// SignJWT encodes the claims in JSON, and signs them with "SM2SM3" as a JWT
+// in the JWS compact serialization. The claims are typically *Claims, or a
+// struct embedding Claims.
+func SignJWT(rand io.Reader, priv *sm2.PrivateKey, claims interface{}) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return Sign(rand, priv, payload, Header{"typ": "JWT"})
+}
+
+// ParseJWT verifies a JWT signed with "SM2SM3", and decodes its claims into
+// the value pointed to by claims. The time claims are NOT validated, which
+// can be done by Claims.Validate.
+func ParseJWT(pub *sm2.PublicKey, token string, claims interface{}) error {
+	payload, header, err := Verify(pub, token)
+	if err != nil {
+		return err
+	}
+	if _, ok := header["cty"]; ok {
+		return errors.New("sm2/jose: nested JWT not supported")
+	}
+	return json.Unmarshal(payload, claims)
+}
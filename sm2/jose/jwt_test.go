@@ -0,0 +1,189 @@
+package jose
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJWT(t *testing.T) {
+	priv := testKey(t)
+	type customClaims struct {
+		Claims
+		Scope string `json:"scope"`
+	}
+	claims := &customClaims{
+		Claims: Claims{
+			Issuer:    "issuer",
+			Subject:   "alice",
+			Audience:  Audience{"api"},
+			ExpiresAt: 1700003600,
+			IssuedAt:  1700000000,
+		},
+		Scope: "read",
+	}
+	token, err := SignJWT(rand.Reader, priv, claims)
+	if err != nil {
+		t.Fatal("SignJWT:", err)
+	}
+	_, header, err := Verify(&priv.PublicKey, token)
+	if err != nil {
+		t.Fatal("Verify:", err)
+	}
+	if header["typ"] != "JWT" {
+		t.Errorf("header = %v, want typ JWT", header)
+	}
+
+	var got customClaims
+	if err := ParseJWT(&priv.PublicKey, token, &got); err != nil {
+		t.Fatal("ParseJWT:", err)
+	}
+	if !reflect.DeepEqual(&got, claims) {
+		t.Errorf("ParseJWT() = %v, want %v", got, claims)
+	}
+	if !got.Audience.Contains("api") || got.Audience.Contains("other") {
+		t.Errorf("Audience = %v", got.Audience)
+	}
+
+	// nested JWTs are not supported.
+	nested, err := Sign(rand.Reader, priv, []byte(token), Header{"cty": "JWT"})
+	if err != nil {
+		t.Fatal("Sign:", err)
+	}
+	if err := ParseJWT(&priv.PublicKey, nested, &got); err == nil {
+		t.Error("ParseJWT() of a nested JWT succeeded, want error")
+	}
+}
+
+func TestClaims_Validate(t *testing.T) {
+	claims := &Claims{
+		NotBefore: 1700000000,
+		ExpiresAt: 1700003600,
+	}
+	tests := []struct {
+		name    string
+		now     int64
+		leeway  time.Duration
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			now:  1700001800,
+		},
+		{
+			name:    "expired",
+			now:     1700003600,
+			wantErr: true,
+		},
+		{
+			name:   "expired within leeway",
+			now:    1700003600,
+			leeway: time.Minute,
+		},
+		{
+			name:    "not valid yet",
+			now:     1699999999,
+			wantErr: true,
+		},
+		{
+			name:   "not valid yet within leeway",
+			now:    1699999999,
+			leeway: time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := claims.Validate(time.Unix(tt.now, 0), tt.leeway); (err != nil) != tt.wantErr {
+				t.Errorf("Claims.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNumericDate(t *testing.T) {
+	tests := []struct {
+		json string
+		want NumericDate
+	}{
+		{json: `1700000000`, want: 1700000000},
+		{json: `1700000000.5`, want: 1700000000},
+		{json: `1.7e9`, want: 1700000000},
+		{json: `-1.5`, want: -1},
+		{json: `null`, want: 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.json, func(t *testing.T) {
+			d := NumericDate(42)
+			if err := json.Unmarshal([]byte(tt.json), &d); err != nil {
+				t.Fatal("json.Unmarshal:", err)
+			}
+			if d != tt.want {
+				t.Errorf("json.Unmarshal() = %d, want %d", d, tt.want)
+			}
+		})
+	}
+
+	for _, data := range []string{`"1700000000"`, `1e19`, `-1e19`, `true`} {
+		var d NumericDate
+		if err := json.Unmarshal([]byte(data), &d); err == nil {
+			t.Errorf("json.Unmarshal(%s) = %d, want error", data, d)
+		}
+	}
+
+	// fractional seconds in the claims of other issuers
+	var claims Claims
+	if err := json.Unmarshal([]byte(`{"exp":1700000000.5,"nbf":1699999999.25}`), &claims); err != nil {
+		t.Fatal("json.Unmarshal:", err)
+	}
+	if claims.ExpiresAt != 1700000000 || claims.NotBefore != 1699999999 {
+		t.Errorf("json.Unmarshal() = %+v, want exp 1700000000 and nbf 1699999999", claims)
+	}
+	b, err := json.Marshal(&Claims{ExpiresAt: 1700000000})
+	if err != nil {
+		t.Fatal("json.Marshal:", err)
+	}
+	if got, want := string(b), `{"exp":1700000000}`; got != want {
+		t.Errorf("json.Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestAudience(t *testing.T) {
+	tests := []struct {
+		json string
+		aud  Audience
+	}{
+		{
+			json: `"api"`,
+			aud:  Audience{"api"},
+		},
+		{
+			json: `["api","web"]`,
+			aud:  Audience{"api", "web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.json, func(t *testing.T) {
+			var aud Audience
+			if err := json.Unmarshal([]byte(tt.json), &aud); err != nil {
+				t.Fatal("json.Unmarshal:", err)
+			}
+			if !reflect.DeepEqual(aud, tt.aud) {
+				t.Errorf("json.Unmarshal() = %v, want %v", aud, tt.aud)
+			}
+			b, err := json.Marshal(aud)
+			if err != nil {
+				t.Fatal("json.Marshal:", err)
+			}
+			if got := string(b); got != tt.json {
+				t.Errorf("json.Marshal() = %s, want %s", got, tt.json)
+			}
+		})
+	}
+
+	var aud Audience
+	if err := json.Unmarshal([]byte(`42`), &aud); err == nil {
+		t.Errorf("json.Unmarshal(42) = %v, want error", aud)
+	}
+}
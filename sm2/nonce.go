@@ -0,0 +1,190 @@
+package sm2
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2/internal/convert"
+	"github.com/need-being/gmcrypto/sm3"
+)
+
+// NonceMode specifies how the random number k is generated in signing.
+type NonceMode int
+
+const (
+	// RandomNonce reads k from the random source supplied by the caller as
+	// specified in GB/T 32918.2-2016.
+	RandomNonce NonceMode = iota
+
+	// DeterministicNonce derives k from the private key and the hash value e
+	// of the message as specified in RFC 6979 section 3.2, with HMAC-SM3.
+	// The random source is not used, and signing the same message with the
+	// same key always produces the same signature.
+	DeterministicNonce
+
+	// HedgedNonce derives k as DeterministicNonce does, with additional data
+	// read from the random source as specified in RFC 6979 section 3.6.
+	// Signatures are randomized, but k stays secret even if the random source
+	// is weak or repeated.
+	HedgedNonce
+)
+
+// SignDeterministic signs the message with a private key and returns a
+// signature in the form of r || s, where k is derived as DeterministicNonce.
+// SM3 is used for hash algorithm.
+func SignDeterministic(priv *PrivateKey, message []byte) ([]byte, error) {
+	sig, err := sign(nil, priv, message, &SignerOpts{Nonce: DeterministicNonce})
+	if err != nil {
+		return nil, err
+	}
+	return encodeSignature(sig, RawSignature)
+}
+
+// nonceGenerator returns a function generating candidates of k in [1, n-1],
+// which is called again if a candidate is rejected by the signing algorithm.
+// The candidates are encoded in big-endian to the byte size of n, so that
+// they can be handled in constant time.
+func nonceGenerator(rand io.Reader, priv *PrivateKey, e []byte, mode NonceMode) (func() ([]byte, error), error) {
+	params := priv.Curve.Params()
+	switch mode {
+	case RandomNonce:
+		return func() ([]byte, error) {
+			return randScalarBytes(rand, priv.Curve)
+		}, nil
+	case DeterministicNonce:
+		return newRFC6979(sm3.New, params.N, priv.D, e, nil).next, nil
+	case HedgedNonce:
+		entropy := make([]byte, (params.BitSize+7)/8)
+		if _, err := io.ReadFull(rand, entropy); err != nil {
+			return nil, err
+		}
+		return newRFC6979(sm3.New, params.N, priv.D, e, entropy).next, nil
+	default:
+		return nil, errors.New("sm2: unknown nonce mode")
+	}
+}
+
+// rfc6979 generates k as specified in RFC 6979 section 3.2.
+type rfc6979 struct {
+	newHash func() hash.Hash
+	q       *big.Int
+	qBytes  []byte // q encoded to rlen bytes
+	k, v    []byte
+}
+
+// newRFC6979 initializes the HMAC_DRBG state of RFC 6979 section 3.2 steps a
+// to g with the private key x, the message hash h1, and the optional
+// additional data of section 3.6.
+func newRFC6979(newHash func() hash.Hash, q, x *big.Int, h1, extra []byte) *rfc6979 {
+	g := &rfc6979{
+		newHash: newHash,
+		q:       q,
+	}
+	rlen := (q.BitLen() + 7) / 8
+	g.qBytes = q.FillBytes(make([]byte, rlen))
+	seed := make([]byte, 2*rlen, 2*rlen+len(extra))
+	convert.IntegerToBytes(x, seed[:rlen]) // x < q always fits
+	copy(seed[rlen:], g.bits2octets(h1))
+	seed = append(seed, extra...)
+
+	// b, c: V = 0x01 0x01 ..., K = 0x00 0x00 ...
+	size := newHash().Size()
+	g.v = make([]byte, size)
+	for i := range g.v {
+		g.v[i] = 0x01
+	}
+	g.k = make([]byte, size)
+
+	// d, e: K = HMAC_K(V || 0x00 || int2octets(x) || bits2octets(h1)), V = HMAC_K(V)
+	// f, g: K = HMAC_K(V || 0x01 || int2octets(x) || bits2octets(h1)), V = HMAC_K(V)
+	for _, b := range []byte{0x00, 0x01} {
+		g.k = g.mac(g.v, []byte{b}, seed)
+		g.v = g.mac(g.v)
+	}
+	return g
+}
+
+// next implements RFC 6979 section 3.2 step h, which generates the next
+// candidate of k encoded to rlen bytes.
+// Only whether a candidate is rejected depends on its value.
+func (g *rfc6979) next() ([]byte, error) {
+	qlen := g.q.BitLen()
+	for {
+		var t []byte
+		for len(t)*8 < qlen {
+			g.v = g.mac(g.v)
+			t = append(t, g.v...)
+		}
+		k := g.bits2int(t)
+
+		// prepare the state for the next candidate regardless of the result.
+		g.k = g.mac(g.v, []byte{0x00})
+		g.v = g.mac(g.v)
+
+		if inRange(k, g.qBytes) == 1 {
+			return k, nil
+		}
+	}
+}
+
+// mac computes HMAC_K of the concatenated data.
+func (g *rfc6979) mac(data ...[]byte) []byte {
+	h := hmac.New(g.newHash, g.k)
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// bits2int converts a bit string to an integer of at most qlen bits as
+// specified in RFC 6979 section 2.3.2, and encodes it to rlen bytes.
+// The running time depends only on the lengths.
+func (g *rfc6979) bits2int(b []byte) []byte {
+	qlen := g.q.BitLen()
+	rlen := (qlen + 7) / 8
+	out := make([]byte, rlen)
+	if len(b)*8 <= qlen {
+		copy(out[rlen-len(b):], b)
+		return out
+	}
+
+	// keep the leftmost qlen bits, which span exactly rlen bytes.
+	excess := len(b)*8 - qlen
+	b = b[:len(b)-excess/8]
+	shift := uint(excess % 8)
+	for i := rlen - 1; i >= 0; i-- {
+		out[i] = b[i] >> shift
+		if i > 0 {
+			out[i] |= b[i-1] << (8 - shift) // zero if shift is zero
+		}
+	}
+	return out
+}
+
+// bits2octets converts a bit string to an integer modulo q as specified in
+// RFC 6979 section 2.3.4, and encodes it to rlen bytes.
+func (g *rfc6979) bits2octets(b []byte) []byte {
+	z := new(big.Int).SetBytes(g.bits2int(b))
+	if z.Cmp(g.q) >= 0 {
+		z.Sub(z, g.q)
+	}
+	return z.FillBytes(make([]byte, (g.q.BitLen()+7)/8))
+}
+
+// inRange returns 1 if k is in [1, q-1], and zero otherwise, where k and q are
+// big-endian encodings of the same length. The running time depends only on
+// the length.
+func inRange(k, q []byte) int {
+	var borrow, acc uint32
+	for i := len(k) - 1; i >= 0; i-- {
+		d := uint32(k[i]) - uint32(q[i]) - borrow
+		borrow = (d >> 8) & 1
+		acc |= uint32(k[i])
+	}
+	// k < q iff k - q borrows, and k > 0 iff any byte is non-zero.
+	return int(borrow & uint32(subtle.ConstantTimeByteEq(byte(acc), 0)^1))
+}
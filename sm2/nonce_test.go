@@ -0,0 +1,253 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal("hex.DecodeString:", err)
+	}
+	return b
+}
+
+func TestRFC6979(t *testing.T) {
+	// RFC 6979 A.2.5, ECDSA with P-256 and SHA-256.
+	q := elliptic.P256().Params().N
+	x, _ := new(big.Int).SetString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721", 16)
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "sample",
+			message: "sample",
+			want:    "a6e3c57dd01abe90086538398355dd4c3b17aa873382b0f24d6129493d8aad60",
+		},
+		{
+			name:    "test",
+			message: "test",
+			want:    "d16b6ae827f17175e040871a1c7ec3500192c4c92677336ec2537acaee0008e0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h1 := sha256.Sum256([]byte(tt.message))
+			k, err := newRFC6979(sha256.New, q, x, h1[:], nil).next()
+			if err != nil {
+				t.Fatal("rfc6979.next:", err)
+			}
+			if got := hex.EncodeToString(k); got != tt.want {
+				t.Errorf("rfc6979.next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRFC6979_bits2int(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		b    string
+		want string
+	}{
+		{
+			name: "same length",
+			q:    "ff",
+			b:    "a5",
+			want: "a5",
+		},
+		{
+			name: "shorter",
+			q:    "ffff",
+			b:    "a5",
+			want: "00a5",
+		},
+		{
+			name: "truncated bytes",
+			q:    "ff",
+			b:    "a5c3",
+			want: "a5",
+		},
+		{
+			name: "truncated bits",
+			q:    "01ff",
+			b:    "a5c3",
+			want: "014b",
+		},
+		{
+			name: "truncated bytes and bits",
+			q:    "01ff",
+			b:    "a5c3ff",
+			want: "014b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &rfc6979{q: new(big.Int).SetBytes(decodeHex(t, tt.q))}
+			if got := hex.EncodeToString(g.bits2int(decodeHex(t, tt.b))); got != tt.want {
+				t.Errorf("rfc6979.bits2int() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_inRange(t *testing.T) {
+	q := "fffffffeffffffffffffffffffffffff7203df6b21c6052b53bbf40939d54123"
+	tests := []struct {
+		name string
+		k    string
+		want int
+	}{
+		{
+			name: "zero",
+			k:    "0000000000000000000000000000000000000000000000000000000000000000",
+			want: 0,
+		},
+		{
+			name: "one",
+			k:    "0000000000000000000000000000000000000000000000000000000000000001",
+			want: 1,
+		},
+		{
+			name: "n - 1",
+			k:    "fffffffeffffffffffffffffffffffff7203df6b21c6052b53bbf40939d54122",
+			want: 1,
+		},
+		{
+			name: "n",
+			k:    q,
+			want: 0,
+		},
+		{
+			name: "n + 1",
+			k:    "fffffffeffffffffffffffffffffffff7203df6b21c6052b53bbf40939d54124",
+			want: 0,
+		},
+		{
+			name: "max",
+			k:    "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inRange(decodeHex(t, tt.k), decodeHex(t, q)); got != tt.want {
+				t.Errorf("inRange() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignDeterministic(t *testing.T) {
+	priv := &PrivateKey{
+		PublicKey: PublicKey{
+			Curve: curve,
+			X:     testKey.X,
+			Y:     testKey.Y,
+			ID:    []byte("1234567812345678"),
+		},
+		D: testKey.D,
+	}
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "message digest",
+			message: "message digest",
+			want:    "24858ee71d63e687feefe41f5af80a59f0791eb1dabc2bbe71daf0e57f06c367" + "3d15550de52785a435004c937256ac715c0e04176ac57062c6722fa692f7a491",
+		},
+		{
+			name:    "sample",
+			message: "sample",
+			want:    "a0a6132fad3fa4a1945e04a0ec910600405f8256ff3b9f3cba25ac6aab735190" + "39bc65962595a8314af758ad2ba671833c60b023b0a6ddbcdbf14480b9e17580",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := []byte(tt.message)
+			want := decodeHex(t, tt.want)
+			got, err := SignDeterministic(priv, message)
+			if err != nil {
+				t.Fatal("SignDeterministic:", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("SignDeterministic() = %x, want %x", got, want)
+			}
+			if !Verify(&priv.PublicKey, message, got) {
+				t.Errorf("SignDeterministic() = %x, which cannot be verified", got)
+			}
+
+			// rand is not used in the deterministic mode.
+			got, err = priv.Sign(rand.Reader, message, &SignerOpts{Nonce: DeterministicNonce})
+			if err != nil {
+				t.Fatal("PrivateKey.Sign:", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("PrivateKey.Sign() = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestSignHedged(t *testing.T) {
+	priv := &PrivateKey{
+		PublicKey: PublicKey{
+			Curve: curve,
+			X:     testKey.X,
+			Y:     testKey.Y,
+			ID:    []byte("1234567812345678"),
+		},
+		D: testKey.D,
+	}
+	message := []byte("message digest")
+	opts := &SignerOpts{Nonce: HedgedNonce}
+	entropy := bytes.Repeat([]byte{0x5a}, 32)
+	want := decodeHex(t, "345033508e35a467da4a210549fb86c959ab3cd1ccebdd91614982c5cd1bb6b4"+
+		"133891ec50d37d947dbb5e77017a06e0c7e104180bbe6668558a16ccb5fcf89b")
+
+	// a repeated random source produces the same signature.
+	for i := 0; i < 2; i++ {
+		got, err := priv.Sign(bytes.NewReader(entropy), message, opts)
+		if err != nil {
+			t.Fatal("PrivateKey.Sign:", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("PrivateKey.Sign() = %x, want %x", got, want)
+		}
+	}
+
+	// signatures are randomized with a proper random source.
+	sig1, err := priv.Sign(rand.Reader, message, opts)
+	if err != nil {
+		t.Fatal("PrivateKey.Sign:", err)
+	}
+	sig2, err := priv.Sign(rand.Reader, message, opts)
+	if err != nil {
+		t.Fatal("PrivateKey.Sign:", err)
+	}
+	if bytes.Equal(sig1, sig2) {
+		t.Errorf("PrivateKey.Sign() = %x twice, want different signatures", sig1)
+	}
+	for _, sig := range [][]byte{sig1, sig2} {
+		if !Verify(&priv.PublicKey, message, sig) {
+			t.Errorf("PrivateKey.Sign() = %x, which cannot be verified", sig)
+		}
+	}
+
+	// the random source is required.
+	if _, err := priv.Sign(bytes.NewReader(nil), message, opts); err == nil {
+		t.Error("PrivateKey.Sign() with empty random source succeeded, want error")
+	}
+}
@@ -0,0 +1,110 @@
+package sm2
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2/internal/convert"
+	"github.com/need-being/gmcrypto/sm2/internal/sm2ec"
+)
+
+// PointFormat specifies the encoding of a point defined in
+// GB/T 32918.1-2016 4.2.8.
+type PointFormat int
+
+const (
+	// UncompressedPoint is the form 04 || x || y.
+	UncompressedPoint PointFormat = iota
+
+	// CompressedPoint is the form 02 || x or 03 || x, depending on the
+	// rightmost bit of y.
+	CompressedPoint
+
+	// HybridPoint is the form 06 || x || y or 07 || x || y, depending on the
+	// rightmost bit of y.
+	HybridPoint
+)
+
+// Bytes encodes pub in the given format as specified in
+// GB/T 32918.1-2016 4.2.9.
+func (pub *PublicKey) Bytes(format PointFormat) ([]byte, error) {
+	if pub.X == nil || pub.Y == nil {
+		return nil, errors.New("sm2: invalid public key")
+	}
+	return marshalPoint(pub.Curve, pub.X, pub.Y, format)
+}
+
+// ParsePublicKey parses a public key on the SM2 curve encoded in the
+// compressed, uncompressed, or hybrid form as specified in
+// GB/T 32918.1-2016 4.2.10.
+// Points not on the curve are rejected.
+func ParsePublicKey(b []byte) (*PublicKey, error) {
+	x, y, err := unmarshalPoint(b)
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{
+		Curve: curve,
+		X:     x,
+		Y:     y,
+	}, nil
+}
+
+// marshalPoint encodes a point in the given format.
+func marshalPoint(c elliptic.Curve, x, y *big.Int, format PointFormat) ([]byte, error) {
+	params := c.Params()
+	n := (params.BitSize + 7) / 8
+	buf := make([]byte, 1+2*n)
+	if err := convert.FieldToBytes(x, params.P, buf[1:1+n]); err != nil {
+		return nil, err
+	}
+
+	// the rightmost bit of y for prime fields.
+	yBit := byte(y.Bit(0))
+	switch format {
+	case UncompressedPoint:
+		buf[0] = 4
+	case CompressedPoint:
+		buf[0] = 2 | yBit
+		return buf[:1+n], nil
+	case HybridPoint:
+		buf[0] = 6 | yBit
+	default:
+		return nil, errors.New("sm2: unknown point format")
+	}
+	if err := convert.FieldToBytes(y, params.P, buf[1+n:]); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// unmarshalPoint decodes a point on the SM2 curve in any format, and checks
+// if the point is on the curve. The point at infinity is rejected.
+func unmarshalPoint(b []byte) (x, y *big.Int, err error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("sm2: invalid point encoding")
+	}
+	switch b[0] {
+	case 2, 3, 4:
+	case 6, 7:
+		// check the rightmost bit of y, and then decode as uncompressed.
+		if len(b) != 1+2*32 || b[0]&1 != b[len(b)-1]&1 {
+			return nil, nil, errors.New("sm2: invalid point encoding")
+		}
+		uncompressed := make([]byte, len(b))
+		uncompressed[0] = 4
+		copy(uncompressed[1:], b[1:])
+		b = uncompressed
+	default:
+		return nil, nil, errors.New("sm2: invalid point encoding")
+	}
+
+	// decompression and curve checks are done by SetBytes.
+	p, err := sm2ec.NewPoint().SetBytes(b)
+	if err != nil {
+		return nil, nil, errors.New("sm2: invalid point: " + err.Error())
+	}
+	x, y = curve.pointToAffine(p)
+	return x, y, nil
+}
@@ -0,0 +1,151 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestPublicKey_Bytes(t *testing.T) {
+	x := testKey.X.FillBytes(make([]byte, 32))
+	y := testKey.Y.FillBytes(make([]byte, 32)) // y is odd
+	tests := []struct {
+		name    string
+		format  PointFormat
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:   "uncompressed",
+			format: UncompressedPoint,
+			want:   concat([]byte{0x04}, x, y),
+		},
+		{
+			name:   "compressed",
+			format: CompressedPoint,
+			want:   concat([]byte{0x03}, x),
+		},
+		{
+			name:   "hybrid",
+			format: HybridPoint,
+			want:   concat([]byte{0x07}, x, y),
+		},
+		{
+			name:    "unknown format",
+			format:  PointFormat(-1),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testKey.PublicKey.Bytes(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PublicKey.Bytes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("PublicKey.Bytes() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	x := testKey.X.FillBytes(make([]byte, 32))
+	y := testKey.Y.FillBytes(make([]byte, 32)) // y is odd
+	tests := []struct {
+		name    string
+		b       []byte
+		wantErr bool
+	}{
+		{
+			name: "uncompressed",
+			b:    concat([]byte{0x04}, x, y),
+		},
+		{
+			name: "compressed",
+			b:    concat([]byte{0x03}, x),
+		},
+		{
+			name: "hybrid",
+			b:    concat([]byte{0x07}, x, y),
+		},
+		{
+			name:    "hybrid with wrong parity",
+			b:       concat([]byte{0x06}, x, y),
+			wantErr: true,
+		},
+		{
+			name:    "not on curve",
+			b:       concat([]byte{0x04}, x, x),
+			wantErr: true,
+		},
+		{
+			name:    "x not in field",
+			b:       concat([]byte{0x02}, bytes.Repeat([]byte{0xff}, 32)),
+			wantErr: true,
+		},
+		{
+			name:    "infinity",
+			b:       []byte{0x00},
+			wantErr: true,
+		},
+		{
+			name:    "truncated",
+			b:       concat([]byte{0x04}, x),
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePublicKey(tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePublicKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !got.Equal(&testKey.PublicKey) {
+				t.Errorf("ParsePublicKey() = (%x, %x), want (%x, %x)", got.X, got.Y, testKey.X, testKey.Y)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey_Negation(t *testing.T) {
+	// 02 || x encodes the negation of the point encoded by 03 || x.
+	x := testKey.X.FillBytes(make([]byte, 32))
+	got, err := ParsePublicKey(concat([]byte{0x02}, x))
+	if err != nil {
+		t.Fatal("ParsePublicKey:", err)
+	}
+	wantY := new(big.Int).Sub(curve.P, testKey.Y)
+	if got.X.Cmp(testKey.X) != 0 || got.Y.Cmp(wantY) != 0 {
+		t.Errorf("ParsePublicKey() = (%x, %x), want (%x, %x)", got.X, got.Y, testKey.X, wantY)
+	}
+}
+
+func TestPublicKeyRoundTrip(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		priv, err := GenerateKey(Curve(), rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey:", err)
+		}
+		for _, format := range []PointFormat{UncompressedPoint, CompressedPoint, HybridPoint} {
+			b, err := priv.PublicKey.Bytes(format)
+			if err != nil {
+				t.Fatalf("PublicKey.Bytes(%d) error = %v", format, err)
+			}
+			pub, err := ParsePublicKey(b)
+			if err != nil {
+				t.Fatalf("ParsePublicKey(%x) error = %v", b, err)
+			}
+			if !pub.Equal(&priv.PublicKey) {
+				t.Fatalf("ParsePublicKey(%x) = (%x, %x), want (%x, %x)", b, pub.X, pub.Y, priv.X, priv.Y)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package sm2
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2/internal/convert"
+	"github.com/need-being/gmcrypto/sm2/internal/sm2ec"
+	"github.com/need-being/gmcrypto/sm3"
+)
+
+// RecoverPublicKey recovers the public key on the SM2 curve from a signature
+// of message in the form of r || s || v, which is produced with
+// RecoverableSignature.
+//
+// The hash value e depends on the public key through Z, which therefore
+// cannot be computed from the ID during recovery. Instead, the recovery is
+// defined over z, the Z of the signer, which is usually shorter than the
+// public key and may be known in advance, e.g. registered with the address of
+// an account. The ID of the returned key is not set, and callers knowing the
+// ID should set it and check that Digest of the key returns z.
+func RecoverPublicKey(message, sig, z []byte) (*PublicKey, error) {
+	if len(z) != sm3.Size {
+		return nil, errors.New("sm2: invalid Z length")
+	}
+
+	// compute hash value e.
+	h := sm3.New() // write on sm3 never returns error
+	h.Write(z)
+	h.Write(message)
+	return RecoverPublicKeyDigest(h.Sum(nil), sig)
+}
+
+// RecoverPublicKeyDigest recovers the public key on the SM2 curve from a
+// signature of the hash value e in the form of r || s || v, which is produced
+// with RecoverableSignature.
+//
+// As k = s + (r + s)d, the public key is P = (r + s)^-1 (R - [s]G), where the
+// point R = (x1, y1) = kG is determined by x1 = (r - e) mod n and the recovery
+// ID v.
+func RecoverPublicKeyDigest(digest, sig []byte) (*PublicKey, error) {
+	if len(digest) != sm3.Size {
+		return nil, errors.New("sm2: invalid digest length")
+	}
+	params := curve.Params()
+	n := (params.N.BitLen() + 7) / 8
+	if len(sig) != 2*n+1 {
+		return nil, errors.New("sm2: invalid signature length")
+	}
+	v := sig[2*n]
+	if v > 3 {
+		return nil, errors.New("sm2: invalid recovery ID")
+	}
+	r := new(big.Int).SetBytes(sig[:n])
+	s := new(big.Int).SetBytes(sig[n : 2*n])
+	if r.Sign() <= 0 || r.Cmp(params.N) >= 0 || s.Sign() <= 0 || s.Cmp(params.N) >= 0 {
+		return nil, errors.New("sm2: invalid signature")
+	}
+
+	// recover R from x1 = (r - e) mod n, or x1 + n if bit 1 of v is set, and
+	// the parity of y1 in bit 0 of v.
+	e := convert.BytesToInteger(digest)
+	x1 := new(big.Int).Sub(r, e)
+	x1.Mod(x1, params.N)
+	if v&2 != 0 {
+		x1.Add(x1, params.N)
+		if x1.Cmp(params.P) >= 0 {
+			return nil, errors.New("sm2: invalid recovery ID")
+		}
+	}
+	buf := make([]byte, 1+(params.BitSize+7)/8)
+	buf[0] = 2 | v&1 // compressed point
+	x1.FillBytes(buf[1:])
+	point, err := sm2ec.NewPoint().SetBytes(buf)
+	if err != nil {
+		return nil, errors.New("sm2: invalid signature")
+	}
+
+	// compute P = [-s * (r + s)^-1]G + [(r + s)^-1]R.
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, params.N)
+	if t.Sign() == 0 {
+		return nil, errors.New("sm2: invalid signature")
+	}
+	t.ModInverse(t, params.N)
+	u := new(big.Int).Mul(s, t)
+	u.Neg(u)
+	u.Mod(u, params.N)
+	point, err = point.VarTimeDoubleScalarBaseMult(curve.scalarBytes(t), point, curve.scalarBytes(u))
+	if err != nil {
+		return nil, err
+	}
+	x, y := curve.pointToAffine(point)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, errors.New("sm2: invalid signature")
+	}
+	return &PublicKey{
+		Curve: curve,
+		X:     x,
+		Y:     y,
+	}, nil
+}
@@ -0,0 +1,118 @@
+package sm2
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestRecoverPublicKey(t *testing.T) {
+	for i := 0; i < 32; i++ {
+		priv, err := GenerateKey(Curve(), rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey:", err)
+		}
+		priv.ID = []byte("signer")
+		z, err := priv.Digest()
+		if err != nil {
+			t.Fatal("PublicKey.Digest:", err)
+		}
+		message := []byte("message")
+
+		sig, err := priv.Sign(rand.Reader, message, &SignerOpts{Format: RecoverableSignature})
+		if err != nil {
+			t.Fatal("PrivateKey.Sign:", err)
+		}
+		if !VerifyWithOpts(&priv.PublicKey, message, sig, &SignerOpts{Format: RecoverableSignature}) {
+			t.Fatalf("PrivateKey.Sign() = %x, which cannot be verified", sig)
+		}
+		pub, err := RecoverPublicKey(message, sig, z)
+		if err != nil {
+			t.Fatal("RecoverPublicKey:", err)
+		}
+		if pub.X.Cmp(priv.X) != 0 || pub.Y.Cmp(priv.Y) != 0 {
+			t.Fatalf("RecoverPublicKey() = (%x, %x), want (%x, %x)", pub.X, pub.Y, priv.X, priv.Y)
+		}
+
+		// the recovery ID selects another point.
+		sig[len(sig)-1] ^= 1
+		if pub, err := RecoverPublicKey(message, sig, z); err == nil && pub.X.Cmp(priv.X) == 0 && pub.Y.Cmp(priv.Y) == 0 {
+			t.Fatalf("RecoverPublicKey() = (%x, %x) with a wrong recovery ID", pub.X, pub.Y)
+		}
+	}
+}
+
+func TestRecoverPublicKeyDigest(t *testing.T) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	signer, err := NewSigner(priv)
+	if err != nil {
+		t.Fatal("NewSigner:", err)
+	}
+	h, err := priv.NewHash()
+	if err != nil {
+		t.Fatal("PublicKey.NewHash:", err)
+	}
+	message := []byte("message")
+	h.Write(message)
+	digest := h.Sum(nil)
+	sig, err := signer.Sign(rand.Reader, message, &SignerOpts{Format: RecoverableSignature, Nonce: HedgedNonce})
+	if err != nil {
+		t.Fatal("Signer.Sign:", err)
+	}
+
+	tests := []struct {
+		name    string
+		digest  []byte
+		sig     []byte
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			digest: digest,
+			sig:    sig,
+		},
+		{
+			name:    "invalid digest length",
+			digest:  digest[1:],
+			sig:     sig,
+			wantErr: true,
+		},
+		{
+			name:    "raw signature",
+			digest:  digest,
+			sig:     sig[:64],
+			wantErr: true,
+		},
+		{
+			name:    "invalid recovery ID",
+			digest:  digest,
+			sig:     append(append([]byte{}, sig[:64]...), 4),
+			wantErr: true,
+		},
+		{
+			name:    "zero r",
+			digest:  digest,
+			sig:     append(make([]byte, 32), sig[32:]...),
+			wantErr: true,
+		},
+		{
+			name:    "zero s",
+			digest:  digest,
+			sig:     append(append(append([]byte{}, sig[:32]...), make([]byte, 32)...), sig[64]),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub, err := RecoverPublicKeyDigest(tt.digest, tt.sig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecoverPublicKeyDigest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !priv.PublicKey.Equal(pub) {
+				t.Errorf("RecoverPublicKeyDigest() = (%x, %x), want (%x, %x)", pub.X, pub.Y, priv.X, priv.Y)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"io"
+
+	"github.com/need-being/gmcrypto/sm2/internal/sm2ec"
+	"github.com/need-being/gmcrypto/sm3"
+)
+
+// resolveID returns the ID used for hashing, where nil stands for DefaultID.
+func resolveID(id []byte) []byte {
+	if id == nil {
+		return []byte(DefaultID)
+	}
+	return id
+}
+
+// Signer signs messages with a private key, where the values depending only
+// on the key, i.e. Z and (1 + d)^-1, are computed once. It implements
+// crypto.Signer in the same way as PrivateKey.
+//
+// A Signer is safe for concurrent use. The private key must not be modified
+// after the Signer is created.
+type Signer struct {
+	key *signingKey
+	id  []byte // ID hashed into z
+	z   []byte // Z of the public key
+}
+
+// NewSigner validates the private key, and creates a Signer with it.
+func NewSigner(priv *PrivateKey) (*Signer, error) {
+	return newSigner(priv, priv.ID)
+}
+
+// newSigner creates a Signer computing Z with the given ID.
+func newSigner(priv *PrivateKey, id []byte) (*Signer, error) {
+	if err := priv.Validate(); err != nil {
+		return nil, err
+	}
+	z, err := priv.digest(id)
+	if err != nil {
+		return nil, err
+	}
+	key, err := newSigningKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{
+		key: key,
+		id:  resolveID(id),
+		z:   z,
+	}, nil
+}
+
+// Public returns the public key corresponding to the private key.
+func (s *Signer) Public() crypto.PublicKey {
+	return &s.key.priv.PublicKey
+}
+
+// Sign signs the given message in the same way as PrivateKey.Sign.
+func (s *Signer) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	sOpts, err := signerOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := s.sign(rand, message, sOpts)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSignature(sig, sOpts.Format)
+}
+
+// sign signs the message with the ID and k generated as specified by opts,
+// and returns a signature in the form of r || s || v.
+func (s *Signer) sign(rand io.Reader, message []byte, opts *SignerOpts) ([]byte, error) {
+	// Z is computed again only if another ID is specified.
+	z := s.z
+	if opts.UID != nil && !bytes.Equal(resolveID(opts.UID), s.id) {
+		var err error
+		if z, err = s.key.priv.digest(opts.UID); err != nil {
+			return nil, err
+		}
+	}
+
+	// A1, A2: compute hash value e
+	h := sm3.New() // write on sm3 never returns error
+	h.Write(z)
+	h.Write(message)
+	return signDigest(rand, s.key, h.Sum(nil), opts.Nonce)
+}
+
+// Verifier verifies signatures with a public key, where Z and, on the SM2
+// curve, a table of the multiples of the public key are computed once. The
+// table takes about 90 KiB of memory, and makes verification several times
+// faster. The ID of the public key is used.
+//
+// A Verifier is safe for concurrent use. The public key must not be modified
+// after the Verifier is created.
+type Verifier struct {
+	pub   *PublicKey
+	z     []byte
+	table *sm2ec.FixedBaseTable // nil if not on the SM2 curve
+}
+
+// NewVerifier validates the public key, and creates a Verifier with it.
+func NewVerifier(pub *PublicKey) (*Verifier, error) {
+	if err := pub.Validate(); err != nil {
+		return nil, err
+	}
+	z, err := pub.Digest()
+	if err != nil {
+		return nil, err
+	}
+	v := &Verifier{
+		pub: pub,
+		z:   z,
+	}
+	if c, ok := pub.Curve.(*sm2Curve); ok {
+		p, err := c.pointFromAffine(pub.X, pub.Y)
+		if err != nil {
+			return nil, errors.New("sm2: invalid public key")
+		}
+		v.table = sm2ec.NewFixedBaseTable(p)
+	}
+	return v, nil
+}
+
+// Verify reports whether sig, in the form of r || s, is a valid signature of
+// message by the public key.
+func (v *Verifier) Verify(message, sig []byte) bool {
+	// B3, B4: compute hash value e.
+	h := sm3.New() // write on sm3 never returns error
+	h.Write(v.z)
+	h.Write(message)
+	return verifyDigest(v.pub, v.table, h.Sum(nil), sig)
+}
+
+// VerifyASN1 reports whether sig, encoded in ASN.1 DER, is a valid signature
+// of message by the public key.
+func (v *Verifier) VerifyASN1(message, sig []byte) bool {
+	raw, err := ASN1ToRaw(v.pub.Curve, sig)
+	if err != nil {
+		return false
+	}
+	return v.Verify(message, raw)
+}
@@ -0,0 +1,219 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+func TestSigner(t *testing.T) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	priv.ID = []byte("signer")
+	signer, err := NewSigner(priv)
+	if err != nil {
+		t.Fatal("NewSigner:", err)
+	}
+	if !priv.PublicKey.Equal(signer.Public()) {
+		t.Errorf("Signer.Public() = %v, want %v", signer.Public(), &priv.PublicKey)
+	}
+	message := []byte("message")
+
+	tests := []struct {
+		name   string
+		opts   crypto.SignerOpts
+		verify func(*PublicKey, []byte, []byte) bool
+	}{
+		{
+			name:   "no hash",
+			opts:   crypto.Hash(0),
+			verify: Verify,
+		},
+		{
+			name:   "deterministic",
+			opts:   &SignerOpts{Nonce: DeterministicNonce},
+			verify: Verify,
+		},
+		{
+			name:   "ASN.1 signature",
+			opts:   &SignerOpts{Format: ASN1Signature, Nonce: DeterministicNonce},
+			verify: VerifyASN1,
+		},
+		{
+			name: "UID",
+			opts: &SignerOpts{Nonce: DeterministicNonce, UID: []byte("alice")},
+			verify: func(pub *PublicKey, message, sig []byte) bool {
+				return VerifyWithOpts(pub, message, sig, &SignerOpts{UID: []byte("alice")})
+			},
+		},
+		{
+			name: "empty UID",
+			opts: &SignerOpts{Nonce: DeterministicNonce, UID: []byte{}},
+			verify: func(pub *PublicKey, message, sig []byte) bool {
+				return VerifyWithOpts(pub, message, sig, &SignerOpts{UID: []byte{}})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := signer.Sign(rand.Reader, message, tt.opts)
+			if err != nil {
+				t.Fatal("Signer.Sign:", err)
+			}
+			if !tt.verify(&priv.PublicKey, message, got) {
+				t.Errorf("Signer.Sign() = %x, which cannot be verified", got)
+			}
+
+			// deterministic signatures are the same as the ones of the key.
+			if sOpts, ok := tt.opts.(*SignerOpts); ok && sOpts.Nonce == DeterministicNonce {
+				want, err := priv.Sign(nil, message, tt.opts)
+				if err != nil {
+					t.Fatal("PrivateKey.Sign:", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("Signer.Sign() = %x, want %x", got, want)
+				}
+			}
+		})
+	}
+
+	if _, err := signer.Sign(rand.Reader, message, crypto.SHA256); err == nil {
+		t.Error("Signer.Sign() with hashed message succeeded, want error")
+	}
+	invalid := &PrivateKey{PublicKey: priv.PublicKey, D: new(big.Int).Add(priv.D, one)}
+	if _, err := NewSigner(invalid); err == nil {
+		t.Error("NewSigner() with invalid key succeeded, want error")
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	priv.ID = []byte("signer")
+	message := []byte("message")
+	sig, err := Sign(rand.Reader, priv, message)
+	if err != nil {
+		t.Fatal("Sign:", err)
+	}
+	sigASN1, err := RawToASN1(sig)
+	if err != nil {
+		t.Fatal("RawToASN1:", err)
+	}
+
+	generic := priv.PublicKey
+	generic.Curve = curve.Params()
+	for _, tt := range []struct {
+		name string
+		pub  *PublicKey
+	}{
+		{name: "SM2 curve", pub: &priv.PublicKey},
+		{name: "generic curve", pub: &generic},
+	} {
+		pub := tt.pub
+		t.Run(tt.name, func(t *testing.T) {
+			verifier, err := NewVerifier(pub)
+			if err != nil {
+				t.Fatal("NewVerifier:", err)
+			}
+			if !verifier.Verify(message, sig) {
+				t.Error("Verifier.Verify() = false, want true")
+			}
+			if !verifier.VerifyASN1(message, sigASN1) {
+				t.Error("Verifier.VerifyASN1() = false, want true")
+			}
+			if verifier.Verify([]byte("malicious"), sig) {
+				t.Error("Verifier.Verify() with tampered message = true, want false")
+			}
+			if verifier.VerifyASN1(message, sig) {
+				t.Error("Verifier.VerifyASN1() with raw signature = true, want false")
+			}
+		})
+	}
+
+	invalid := &PublicKey{Curve: curve, X: priv.X, Y: new(big.Int).Add(priv.Y, one)}
+	if _, err := NewVerifier(invalid); err == nil {
+		t.Error("NewVerifier() with invalid key succeeded, want error")
+	}
+}
+
+func TestSignerVerifierConcurrency(t *testing.T) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	signer, err := NewSigner(priv)
+	if err != nil {
+		t.Fatal("NewSigner:", err)
+	}
+	verifier, err := NewVerifier(&priv.PublicKey)
+	if err != nil {
+		t.Fatal("NewVerifier:", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			message := []byte{byte(i)}
+			sig, err := signer.Sign(rand.Reader, message, nil)
+			if err != nil {
+				t.Error("Signer.Sign:", err)
+				return
+			}
+			if !verifier.Verify(message, sig) {
+				t.Error("Verifier.Verify() = false, want true")
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func BenchmarkSigner(b *testing.B) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		b.Fatal("GenerateKey:", err)
+	}
+	priv.ID = []byte("benchmark")
+	signer, err := NewSigner(priv)
+	if err != nil {
+		b.Fatal("NewSigner:", err)
+	}
+	message := []byte("message")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := signer.Sign(rand.Reader, message, nil); err != nil {
+			b.Fatal("Signer.Sign:", err)
+		}
+	}
+}
+
+func BenchmarkVerifier(b *testing.B) {
+	priv, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		b.Fatal("GenerateKey:", err)
+	}
+	priv.ID = []byte("benchmark")
+	message := []byte("message")
+	sig, err := Sign(rand.Reader, priv, message)
+	if err != nil {
+		b.Fatal("Sign:", err)
+	}
+	verifier, err := NewVerifier(&priv.PublicKey)
+	if err != nil {
+		b.Fatal("NewVerifier:", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !verifier.Verify(message, sig) {
+			b.Fatal("Verify failed")
+		}
+	}
+}
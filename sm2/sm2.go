@@ -1,15 +1,18 @@
-// Package sm2 is implemented based on GB/T 32918.1-2016, GB/T 32918.2-2016, and
-// GB/T 32918.5-2017.
+// Package sm2 is implemented based on GB/T 32918.1-2016, GB/T 32918.2-2016,
+// GB/T 32918.3-2016, GB/T 32918.4-2016, and GB/T 32918.5-2017.
 package sm2
 
 import (
 	"crypto"
 	"crypto/elliptic"
 	"errors"
+	"hash"
 	"io"
 	"math/big"
 
+	"github.com/need-being/gmcrypto/sm2/ecdh"
 	"github.com/need-being/gmcrypto/sm2/internal/convert"
+	"github.com/need-being/gmcrypto/sm2/internal/sm2ec"
 	"github.com/need-being/gmcrypto/sm3"
 )
 
@@ -20,6 +23,10 @@ var (
 	three = new(big.Int).SetInt64(3)
 )
 
+// DefaultID is the default identifier of the signer specified in
+// GM/T 0009-2012, which is used by OpenSSL and GmSSL if no ID is given.
+const DefaultID = "1234567812345678"
+
 // PublicKey represents an SM2 public key.
 type PublicKey struct {
 	elliptic.Curve
@@ -33,8 +40,12 @@ type PublicKey struct {
 	// it makes more sense to bind the ID with the public key.
 	// In the scenario that a key pair is associated with multiple identities,
 	// multiple instances of PublicKey or PrivateKey should be created for each
-	// identity with other fields remaining the same.
+	// identity with other fields remaining the same, or the ID can be
+	// overridden per call by SignerOpts.
 	// This also enables SM2 to support crypto.Signer.
+	//
+	// If ID is nil, DefaultID is used. An empty ID can be used explicitly by
+	// setting ID to a non-nil empty slice, e.g. []byte{}.
 	ID []byte
 }
 
@@ -51,7 +62,16 @@ func (pub *PublicKey) Equal(x crypto.PublicKey) bool {
 // Digest returns the value Z defined in GB/T 32918.2-2016 by hashing.
 // SM3 is used for hash algorithm.
 func (pub *PublicKey) Digest() ([]byte, error) {
-	idLength := len(pub.ID) << 3
+	return pub.digest(pub.ID)
+}
+
+// digest returns the value Z with the given ID, where nil stands for
+// DefaultID.
+func (pub *PublicKey) digest(id []byte) ([]byte, error) {
+	if id == nil {
+		id = []byte(DefaultID)
+	}
+	idLength := len(id) << 3
 	if idLength > 0xffff {
 		return nil, errors.New("sm2: ID too large")
 	}
@@ -61,7 +81,7 @@ func (pub *PublicKey) Digest() ([]byte, error) {
 
 	// write ID
 	h.Write([]byte{byte(idLength >> 8), byte(idLength)})
-	h.Write(pub.ID)
+	h.Write(id)
 
 	// write curve
 	params := pub.Curve.Params()
@@ -98,6 +118,40 @@ func (pub *PublicKey) Digest() ([]byte, error) {
 	return h.Sum(nil), nil
 }
 
+// NewHash returns a new hash.Hash computing the hash value e defined in
+// GB/T 32918.2-2016, which is primed with Z of pub. It enables messages to be
+// written in a streaming way, and the sum can be signed by SignDigest or
+// verified by VerifyDigest. Reset restores the hash to the primed state.
+func (pub *PublicKey) NewHash() (hash.Hash, error) {
+	return pub.newHash(pub.ID)
+}
+
+// newHash returns a new hash.Hash primed with Z of the given ID.
+func (pub *PublicKey) newHash(id []byte) (hash.Hash, error) {
+	z, err := pub.digest(id)
+	if err != nil {
+		return nil, err
+	}
+	h := &primedHash{
+		Hash: sm3.New(),
+		z:    z,
+	}
+	h.Reset()
+	return h, nil
+}
+
+// primedHash is an SM3 hash primed with Z.
+type primedHash struct {
+	hash.Hash
+	z []byte
+}
+
+// Reset resets the hash to its primed state.
+func (h *primedHash) Reset() {
+	h.Hash.Reset()
+	h.Hash.Write(h.z) // write on sm3 never returns error
+}
+
 // PrivateKey represents an SM2 private key.
 type PrivateKey struct {
 	PublicKey
@@ -118,17 +172,120 @@ func (priv *PrivateKey) Equal(x crypto.PrivateKey) bool {
 	return priv.PublicKey.Equal(&xx.PublicKey) && priv.D.Cmp(xx.D) == 0
 }
 
+// ECDH returns pub as an ecdh.PublicKey for the plain ECDH key agreement.
+// It fails if pub is not on the SM2 curve.
+func (pub *PublicKey) ECDH() (*ecdh.PublicKey, error) {
+	if pub.Curve != curve {
+		return nil, errors.New("sm2: unsupported curve by ecdh")
+	}
+	b, err := pub.Bytes(UncompressedPoint)
+	if err != nil {
+		return nil, err
+	}
+	return ecdh.NewPublicKey(b)
+}
+
+// ECDH returns priv as an ecdh.PrivateKey for the plain ECDH key agreement.
+// It fails if priv is not on the SM2 curve.
+func (priv *PrivateKey) ECDH() (*ecdh.PrivateKey, error) {
+	if priv.Curve != curve {
+		return nil, errors.New("sm2: unsupported curve by ecdh")
+	}
+	if priv.D == nil || priv.D.Sign() <= 0 || priv.D.BitLen() > 256 {
+		return nil, errors.New("sm2: invalid private key")
+	}
+	return ecdh.NewPrivateKey(priv.D.FillBytes(make([]byte, 32)))
+}
+
 // Sign signs the given message with priv.
 // SM2 relies on hash over message and the identity of the signer, and therefore
 // cannot handle pre-hashed messages. Thus opts.HashFunc() must return zero to
 // indicate the message hasn't been hashed if opts presents. This can be
-// achieved by passing crypto.Hash(0) as the value for opts.
+// achieved by passing crypto.Hash(0) or *SignerOpts as the value for opts.
+// Messages hashed by the hash returned by NewHash can be signed by SignDigest.
+//
+// The signature is in the form of r || s, k is read from rand, and the ID of
+// the public key is used, unless opts is a *SignerOpts selecting otherwise.
 func (priv *PrivateKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) (signature []byte, err error) {
+	sOpts, err := signerOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sign(rand, priv, message, sOpts)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSignature(sig, sOpts.Format)
+}
+
+// encodeSignature encodes a signature in the form of r || s || v, as returned
+// by signDigest, in the given format.
+func encodeSignature(sig []byte, format SignatureFormat) ([]byte, error) {
+	switch format {
+	case ASN1Signature:
+		return RawToASN1(sig[:len(sig)-1])
+	case RecoverableSignature:
+		return sig, nil
+	default:
+		return sig[:len(sig)-1], nil
+	}
+}
+
+// signerOpts checks opts passed to crypto.Signer, and converts it to
+// *SignerOpts. A nil *SignerOpts selects the defaults.
+func signerOpts(opts crypto.SignerOpts) (*SignerOpts, error) {
 	if opts != nil && opts.HashFunc() != crypto.Hash(0) {
 		return nil, errors.New("sm2: cannot sign hashed message")
 	}
+	if sOpts, ok := opts.(*SignerOpts); ok && sOpts != nil {
+		return sOpts, nil
+	}
+	return &SignerOpts{}, nil
+}
+
+// SignatureFormat specifies the encoding of an SM2 signature.
+type SignatureFormat int
+
+const (
+	// RawSignature is the form of r || s where r and s have the same length.
+	RawSignature SignatureFormat = iota
+
+	// ASN1Signature is the ASN.1 DER encoding defined in GM/T 0009-2012,
+	// which is used by X.509, CMS, OpenSSL and GmSSL.
+	ASN1Signature
+
+	// RecoverableSignature is the form of r || s || v, where v is a single
+	// byte of the recovery ID, from which RecoverPublicKey recovers the
+	// public key.
+	RecoverableSignature
+)
+
+// SignerOpts contains options for signing with an SM2 private key.
+type SignerOpts struct {
+	// Format is the encoding of the signature.
+	Format SignatureFormat
+
+	// Nonce specifies how the random number k is generated.
+	Nonce NonceMode
+
+	// UID is the identifier of the signer, which overrides the ID of the key
+	// if not nil. An empty ID can be used explicitly by setting UID to a
+	// non-nil empty slice, e.g. []byte{}.
+	UID []byte
+}
+
+// id returns the identifier of the signer with the given public key.
+func (opts *SignerOpts) id(pub *PublicKey) []byte {
+	if opts.UID != nil {
+		return opts.UID
+	}
+	return pub.ID
+}
 
-	return Sign(rand, priv, message)
+// HashFunc returns zero to indicate that SM2 signs messages which haven't been
+// hashed. It implements crypto.SignerOpts.
+func (opts *SignerOpts) HashFunc() crypto.Hash {
+	return crypto.Hash(0)
 }
 
 // GenerateKey generates a public and private key pair.
@@ -158,36 +315,156 @@ func GenerateKey(c elliptic.Curve, rand io.Reader) (*PrivateKey, error) {
 	}, nil
 }
 
+// randScalar generates a random integer k in [1, n-1].
+func randScalar(rand io.Reader, params *elliptic.CurveParams) (*big.Int, error) {
+	b := make([]byte, params.BitSize/8+8) // 64 more bits to reduce bias from mod.
+	if _, err := io.ReadFull(rand, b); err != nil {
+		return nil, err
+	}
+	k := new(big.Int).SetBytes(b)
+	n := new(big.Int).Sub(params.N, one)
+	k.Mod(k, n)
+	k.Add(k, one)
+	return k, nil
+}
+
+// randScalarBytes generates a random integer k in [1, n-1], encoded in
+// big-endian to the byte size of n. On the SM2 curve, k is reduced in constant
+// time.
+func randScalarBytes(rand io.Reader, c elliptic.Curve) ([]byte, error) {
+	params := c.Params()
+	if _, ok := c.(*sm2Curve); ok {
+		b := make([]byte, params.BitSize/8+8) // 64 more bits to reduce bias from mod.
+		if _, err := io.ReadFull(rand, b); err != nil {
+			return nil, err
+		}
+		return sm2ec.NewScalar().SetUniformBytes(b).Bytes(), nil
+	}
+	k, err := randScalar(rand, params)
+	if err != nil {
+		return nil, err
+	}
+	return k.FillBytes(make([]byte, (params.N.BitLen()+7)/8)), nil
+}
+
 // Sign signs the message with a private key and returns a signature.
 // The signature is in the form of (r, s) where r and s have the same length.
 // SM3 is used for hash algorithm.
 func Sign(rand io.Reader, priv *PrivateKey, message []byte) ([]byte, error) {
-	// A1, A2: compute hash value e
-	z, err := priv.Digest()
+	sig, err := sign(rand, priv, message, &SignerOpts{})
 	if err != nil {
 		return nil, err
 	}
-	h := sm3.New() // write on sm3 never returns error
-	h.Write(z)
-	h.Write(message)
-	e := convert.BytesToInteger(h.Sum(nil))
+	return encodeSignature(sig, RawSignature)
+}
+
+// SignDigest signs the hash value e of a message, computed by the hash returned
+// by NewHash of the public key, with a private key and returns a signature
+// in the form of r || s.
+func SignDigest(rand io.Reader, priv *PrivateKey, digest []byte) ([]byte, error) {
+	if err := priv.Validate(); err != nil {
+		return nil, err
+	}
+	if len(digest) != sm3.Size {
+		return nil, errors.New("sm2: invalid digest length")
+	}
+	key, err := newSigningKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signDigest(rand, key, digest, RandomNonce)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSignature(sig, RawSignature)
+}
+
+// sign signs the message with the ID and k generated as specified by opts,
+// and returns a signature in the form of r || s || v.
+// The format in opts is ignored.
+func sign(rand io.Reader, priv *PrivateKey, message []byte, opts *SignerOpts) ([]byte, error) {
+	signer, err := newSigner(priv, opts.id(&priv.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	return signer.sign(rand, message, opts)
+}
+
+// signingKey is a validated private key with the scalars used in signing.
+type signingKey struct {
+	priv *PrivateKey
+
+	// d and (1 + d)^-1 mod n for the constant-time implementation on the
+	// SM2 curve.
+	d, dInv *sm2ec.Scalar
+
+	// (1 + d)^-1 mod n for the generic implementation on other curves.
+	dInvInt *big.Int
+}
+
+// newSigningKey computes the scalars used in signing with a validated private
+// key.
+func newSigningKey(priv *PrivateKey) (*signingKey, error) {
+	params := priv.Curve.Params()
+	if _, ok := priv.Curve.(*sm2Curve); !ok {
+		return newGenericSigningKey(priv, params), nil
+	}
+	d, err := sm2ec.NewScalar().SetBytes(priv.D.FillBytes(make([]byte, (params.N.BitLen()+7)/8)))
+	if err != nil {
+		return nil, errors.New("sm2: invalid private key")
+	}
+	dInv := sm2ec.NewScalar().One()
+	dInv.Add(dInv, d).Invert(dInv)
+	return &signingKey{priv: priv, d: d, dInv: dInv}, nil
+}
+
+// newGenericSigningKey computes (1 + d)^-1 mod n for the generic
+// implementation on other curves.
+func newGenericSigningKey(priv *PrivateKey, params *elliptic.CurveParams) *signingKey {
+	dInv := new(big.Int).Add(one, priv.D)
+	dInv.ModInverse(dInv, params.N)
+	return &signingKey{priv: priv, dInvInt: dInv}
+}
+
+// signDigest signs the hash value e with a signing key, and returns a
+// signature in the form of r || s || v, where the recovery ID v encodes the
+// parity of y1 in bit 0, and whether x1 >= n in bit 1.
+func signDigest(rand io.Reader, key *signingKey, digest []byte, mode NonceMode) ([]byte, error) {
+	priv := key.priv
 
 	// A3: generate random k
+	nextK, err := nonceGenerator(rand, priv, digest, mode)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := priv.Curve.(*sm2Curve); ok {
+		return c.sign(key.d, key.dInv, digest, nextK)
+	}
+	return signGeneric(key, digest, nextK)
+}
+
+// signGeneric is the generic implementation of signDigest on other curves.
+func signGeneric(key *signingKey, digest []byte, nextK func() ([]byte, error)) ([]byte, error) {
+	priv := key.priv
+
+	e := convert.BytesToInteger(digest)
 	params := priv.Curve.Params()
 	r := new(big.Int)
 	s := new(big.Int)
+	var v byte
 	for {
-		b := make([]byte, params.BitSize/8+8) // 64 more bits to reduce bias from mod.
-		if _, err = io.ReadFull(rand, b); err != nil {
+		kBytes, err := nextK()
+		if err != nil {
 			return nil, err
 		}
-		k := new(big.Int).SetBytes(b)
-		n := new(big.Int).Sub(params.N, one)
-		k.Mod(k, n)
-		k.Add(k, one)
+		k := new(big.Int).SetBytes(kBytes)
 
-		// A4: compute (x, y) = kG where y is dropped
-		x, _ := priv.Curve.ScalarBaseMult(k.Bytes())
+		// A4: compute (x, y) = kG where y is kept only for the recovery ID
+		x, y := priv.Curve.ScalarBaseMult(kBytes)
+		v = byte(y.Bit(0))
+		if x.Cmp(params.N) >= 0 {
+			v |= 2
+		}
 
 		// A5: compute r = (e + x) mod n
 		r.Add(e, x)
@@ -200,11 +477,9 @@ func Sign(rand io.Reader, priv *PrivateKey, message []byte) ([]byte, error) {
 		}
 
 		// A6: compute s = ((1 + d)^-1 * (k - rd)) mod n
-		s.Add(one, priv.D)
-		s.ModInverse(s, params.N)
-		s2 := new(big.Int).Mul(r, priv.D)
-		s2.Sub(k, s2)
-		s.Mul(s, s2)
+		s.Mul(r, priv.D)
+		s.Sub(k, s)
+		s.Mul(key.dInvInt, s)
 		s.Mod(s, params.N)
 		if s.Sign() != 0 {
 			break // goto A3
@@ -213,19 +488,76 @@ func Sign(rand io.Reader, priv *PrivateKey, message []byte) ([]byte, error) {
 
 	// A7: convert r, s to byte strings
 	n := (params.BitSize + 7) / 8
-	sig := make([]byte, n*2)
-	if err = convert.IntegerToBytes(r, sig[:n]); err != nil {
+	sig := make([]byte, n*2+1)
+	if err := convert.IntegerToBytes(r, sig[:n]); err != nil {
 		return nil, err
 	}
-	if err = convert.IntegerToBytes(s, sig[n:]); err != nil {
+	if err := convert.IntegerToBytes(s, sig[n:2*n]); err != nil {
 		return nil, err
 	}
+	sig[2*n] = v
 	return sig, nil
 }
 
 // Verify reports whether sig is a valid signature of message by the given
 // public key.
 func Verify(pub *PublicKey, message, sig []byte) bool {
+	return verify(pub, message, sig, pub.ID)
+}
+
+// VerifyWithOpts reports whether sig is a valid signature of message by the
+// given public key, where the signature format and the ID of the signer are
+// specified by opts. A nil opts selects the defaults, as Verify does.
+func VerifyWithOpts(pub *PublicKey, message, sig []byte, opts *SignerOpts) bool {
+	if opts == nil {
+		opts = &SignerOpts{}
+	}
+	switch opts.Format {
+	case ASN1Signature:
+		raw, err := ASN1ToRaw(pub.Curve, sig)
+		if err != nil {
+			return false
+		}
+		sig = raw
+	case RecoverableSignature:
+		// the recovery ID is not needed for verification.
+		if len(sig) == 0 {
+			return false
+		}
+		sig = sig[:len(sig)-1]
+	}
+	return verify(pub, message, sig, opts.id(pub))
+}
+
+// verify verifies the signature of message with the given ID.
+func verify(pub *PublicKey, message, sig, id []byte) bool {
+	if pub.Validate() != nil {
+		return false
+	}
+
+	// B3, B4: compute hash value e.
+	h, err := pub.newHash(id)
+	if err != nil {
+		return false
+	}
+	h.Write(message) // write on sm3 never returns error
+	return verifyDigest(pub, nil, h.Sum(nil), sig)
+}
+
+// VerifyDigest reports whether sig is a valid signature of the hash value e
+// of a message, computed by the hash returned by NewHash of the given public
+// key.
+func VerifyDigest(pub *PublicKey, digest, sig []byte) bool {
+	if pub.Validate() != nil || len(digest) != sm3.Size {
+		return false
+	}
+	return verifyDigest(pub, nil, digest, sig)
+}
+
+// verifyDigest verifies the signature of the hash value e with a validated
+// public key. table holds the multiples of the public key on the SM2 curve if
+// not nil.
+func verifyDigest(pub *PublicKey, table *sm2ec.FixedBaseTable, digest, sig []byte) bool {
 	// parse (r, s)
 	params := pub.Curve.Params()
 	n := (params.BitSize + 7) / 8
@@ -245,15 +577,7 @@ func Verify(pub *PublicKey, message, sig []byte) bool {
 		return false
 	}
 
-	// B3, B4: compute hash value e.
-	z, err := pub.Digest()
-	if err != nil {
-		return false
-	}
-	h := sm3.New() // write on sm3 never returns error
-	h.Write(z)
-	h.Write(message)
-	e := convert.BytesToInteger(h.Sum(nil))
+	e := convert.BytesToInteger(digest)
 
 	// B5: compute t = (r + s) mod n
 	t := new(big.Int).Add(r, s)
@@ -262,6 +586,14 @@ func Verify(pub *PublicKey, message, sig []byte) bool {
 		return false
 	}
 
+	// B6, B7 for the SM2 curve, which is faster.
+	if c, ok := pub.Curve.(*sm2Curve); ok {
+		if table != nil {
+			return c.verifyWithTable(table, e, r, s, t)
+		}
+		return c.verify(pub.X, pub.Y, e, r, s, t)
+	}
+
 	// B6: compute (x, y) = sG + tP where y is dropped
 	x, y := pub.Curve.ScalarBaseMult(s.Bytes())
 	x2, y2 := pub.Curve.ScalarMult(pub.X, pub.Y, t.Bytes())
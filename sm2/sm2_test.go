@@ -389,3 +389,138 @@ func BenchmarkVerifyFailed(b *testing.B) {
 		}
 	}
 }
+
+func TestPublicKey_NewHash(t *testing.T) {
+	pub := &PublicKey{
+		Curve: curve,
+		X:     testKey.X,
+		Y:     testKey.Y,
+		ID:    []byte("1234567812345678"),
+	}
+	h, err := pub.NewHash()
+	if err != nil {
+		t.Fatal("PublicKey.NewHash:", err)
+	}
+
+	// GB/T 32918.5-2017 A.2
+	want := decodeHex(t, "f0b43e94ba45accaace692ed534382eb17e6ab5a19ce7b31f4486fdfc0d28640")
+	h.Write([]byte("message "))
+	h.Write([]byte("digest"))
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		t.Errorf("Sum() = %x, want %x", got, want)
+	}
+
+	h.Reset()
+	h.Write([]byte("message digest"))
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		t.Errorf("Sum() after Reset() = %x, want %x", got, want)
+	}
+
+	pub.ID = make([]byte, 0x2000)
+	if _, err := pub.NewHash(); err == nil {
+		t.Error("PublicKey.NewHash() with large ID succeeded, want error")
+	}
+}
+
+func TestPrivateKey_ECDH(t *testing.T) {
+	privA, err := GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	privB, err := GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	ecdhA, err := privA.ECDH()
+	if err != nil {
+		t.Fatal("PrivateKey.ECDH:", err)
+	}
+	ecdhB, err := privB.PublicKey.ECDH()
+	if err != nil {
+		t.Fatal("PublicKey.ECDH:", err)
+	}
+	want, err := privB.PublicKey.Bytes(UncompressedPoint)
+	if err != nil {
+		t.Fatal("PublicKey.Bytes:", err)
+	}
+	if got := ecdhB.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("PublicKey.ECDH().Bytes() = %x, want %x", got, want)
+	}
+
+	secret, err := ecdhA.ECDH(ecdhB)
+	if err != nil {
+		t.Fatal("ecdh.PrivateKey.ECDH:", err)
+	}
+	x, _ := curve.ScalarMult(privB.X, privB.Y, privA.D.Bytes())
+	if want := x.FillBytes(make([]byte, 32)); !bytes.Equal(secret, want) {
+		t.Errorf("ecdh.PrivateKey.ECDH() = %x, want %x", secret, want)
+	}
+
+	other, err := GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	if _, err := other.ECDH(); err == nil {
+		t.Error("PrivateKey.ECDH() on P-256 succeeded, want error")
+	}
+	if _, err := other.PublicKey.ECDH(); err == nil {
+		t.Error("PublicKey.ECDH() on P-256 succeeded, want error")
+	}
+}
+
+func TestSignDigest(t *testing.T) {
+	priv := &PrivateKey{
+		PublicKey: PublicKey{
+			Curve: curve,
+			X:     testKey.X,
+			Y:     testKey.Y,
+			ID:    []byte("1234567812345678"),
+		},
+		D: testKey.D,
+	}
+	pub := &priv.PublicKey
+
+	// GB/T 32918.5-2017 A.2
+	rand := io.MultiReader(
+		bytes.NewReader([]byte{
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // zeros
+			0x59, 0x27, 0x6e, 0x27, 0xd5, 0x06, 0x86, 0x1a,
+			0x16, 0x68, 0x0f, 0x3a, 0xd9, 0xc0, 0x2d, 0xcc,
+			0xef, 0x3c, 0xc1, 0xfa, 0x3c, 0xdb, 0xe4, 0xce,
+			0x6d, 0x54, 0xb8, 0x0d, 0xea, 0xc1, 0xbc, 0x20, // minus 1
+		}),
+		rand.Reader,
+	)
+	message := []byte("message digest")
+	digest := decodeHex(t, "f0b43e94ba45accaace692ed534382eb17e6ab5a19ce7b31f4486fdfc0d28640")
+	want := decodeHex(t, "f5a03b0648d2c4630eeac513e1bb81a15944da3827d5b74143ac7eaceee720b3"+
+		"b1b6aa29df212fd8763182bc0d421ca1bb9038fd1f7f42d4840b69c485bbc1aa")
+
+	sig, err := SignDigest(rand, priv, digest)
+	if err != nil {
+		t.Fatal("SignDigest:", err)
+	}
+	if !bytes.Equal(sig, want) {
+		t.Errorf("SignDigest() = %x, want %x", sig, want)
+	}
+	if !VerifyDigest(pub, digest, sig) {
+		t.Error("VerifyDigest() = false, want true")
+	}
+	if !Verify(pub, message, sig) {
+		t.Error("Verify() = false, want true")
+	}
+
+	// tampered digest
+	digest[0] ^= 1
+	if VerifyDigest(pub, digest, sig) {
+		t.Error("VerifyDigest() with tampered digest = true, want false")
+	}
+
+	// digests of invalid length
+	if _, err := SignDigest(rand, priv, message); err == nil {
+		t.Error("SignDigest() with invalid digest length succeeded, want error")
+	}
+	if VerifyDigest(pub, digest[:16], sig) {
+		t.Error("VerifyDigest() with invalid digest length = true, want false")
+	}
+}
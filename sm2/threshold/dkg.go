@@ -0,0 +1,350 @@
+package threshold
+
+import (
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2"
+	"github.com/need-being/gmcrypto/sm2/internal/paillier"
+)
+
+// DKGBroadcast is broadcast by each party in distributed key generation.
+type DKGBroadcast struct {
+	From int
+
+	// Commitments are the points [a_k]G for the coefficients a_0, ...,
+	// a_(t-1) of the polynomial of the party, in the compressed form.
+	Commitments [][]byte
+
+	// PaillierN is the modulus of the Paillier public key of the party.
+	PaillierN *big.Int
+}
+
+// DKGShare is sent by a party to each other party in distributed key
+// generation, and must be kept confidential.
+type DKGShare struct {
+	From, To int
+
+	// Share is f(To), where f is the polynomial of the sender.
+	Share *big.Int
+}
+
+// DKG is the state of a party in distributed key generation.
+type DKG struct {
+	id        int
+	threshold int
+	parties   []int
+	coeffs    []*big.Int // coefficients of the polynomial f
+	paillier  *paillier.PrivateKey
+}
+
+// Key is the share of a private key held by a party, which is generated by
+// distributed key generation.
+// The ID of the public key, which is used in signing, may be set after key
+// generation.
+type Key struct {
+	ID        int            // ID of the party
+	Threshold int            // number of parties needed to sign
+	Parties   []int          // IDs of all parties in ascending order
+	PublicKey *sm2.PublicKey // public key shared by all parties
+
+	share    *big.Int // share x of the private key d
+	paillier *paillier.PrivateKey
+	peers    map[int]*paillier.PublicKey // Paillier public keys of the other parties
+}
+
+// NewDKG starts distributed key generation for the party id among parties,
+// where any threshold of the parties can sign. It returns the broadcast to
+// all other parties, and the shares to be sent to each of them.
+func NewDKG(rand io.Reader, id int, parties []int, threshold int) (*DKG, *DKGBroadcast, []*DKGShare, error) {
+	parties, err := checkParties(parties)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if threshold < 1 || threshold > len(parties) {
+		return nil, nil, nil, errors.New("sm2/threshold: invalid threshold")
+	}
+	if indexOf(parties, id) < 0 {
+		return nil, nil, nil, errors.New("sm2/threshold: party not in the parties")
+	}
+
+	// generate a random polynomial f of degree t - 1, and commit to it.
+	c := sm2.Curve()
+	n := c.Params().N
+	g := &DKG{
+		id:        id,
+		threshold: threshold,
+		parties:   parties,
+		coeffs:    make([]*big.Int, threshold),
+	}
+	bcast := &DKGBroadcast{
+		From:        id,
+		Commitments: make([][]byte, threshold),
+	}
+	for i := range g.coeffs {
+		a, err := randScalar(rand, n)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		g.coeffs[i] = a
+		if bcast.Commitments[i], err = encodePoint(c.ScalarBaseMult(a.Bytes())); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if g.paillier, err = paillier.GenerateKey(rand, paillierBits); err != nil {
+		return nil, nil, nil, err
+	}
+	bcast.PaillierN = g.paillier.N
+
+	var shares []*DKGShare
+	for _, to := range parties {
+		if to != id {
+			shares = append(shares, &DKGShare{
+				From:  id,
+				To:    to,
+				Share: g.evaluate(to),
+			})
+		}
+	}
+	return g, bcast, shares, nil
+}
+
+// indexOf returns the position of id in ids, or -1 if not found.
+func indexOf(ids []int, id int) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// evaluate computes f(x) mod n.
+func (g *DKG) evaluate(x int) *big.Int {
+	n := sm2.Curve().Params().N
+	bx := big.NewInt(int64(x))
+	y := new(big.Int)
+	for i := len(g.coeffs) - 1; i >= 0; i-- {
+		y.Mul(y, bx)
+		y.Add(y, g.coeffs[i])
+		y.Mod(y, n)
+	}
+	return y
+}
+
+// Finish completes distributed key generation with the broadcasts and the
+// shares from all other parties. Each share is checked against the
+// commitments of its sender, so that an invalid share aborts key generation.
+func (g *DKG) Finish(broadcasts []*DKGBroadcast, shares []*DKGShare) (*Key, error) {
+	if g.coeffs == nil {
+		return nil, errors.New("sm2/threshold: key generation already finished")
+	}
+	senders := make([]int, len(broadcasts))
+	for i, b := range broadcasts {
+		if b == nil {
+			return nil, errors.New("sm2/threshold: invalid broadcast")
+		}
+		senders[i] = b.From
+	}
+	bIndex, err := indexMessages(senders, g.parties, g.id)
+	if err != nil {
+		return nil, err
+	}
+	senders = make([]int, len(shares))
+	for i, s := range shares {
+		if s == nil || s.To != g.id {
+			return nil, errors.New("sm2/threshold: invalid share")
+		}
+		senders[i] = s.From
+	}
+	sIndex, err := indexMessages(senders, g.parties, g.id)
+	if err != nil {
+		return nil, err
+	}
+
+	c := sm2.Curve()
+	n := c.Params().N
+	key := &Key{
+		ID:        g.id,
+		Threshold: g.threshold,
+		Parties:   g.parties,
+		share:     g.evaluate(g.id),
+		paillier:  g.paillier,
+		peers:     make(map[int]*paillier.PublicKey),
+	}
+	pubX, pubY := c.ScalarBaseMult(g.coeffs[0].Bytes())
+	for from, i := range bIndex {
+		b := broadcasts[i]
+		if len(b.Commitments) != g.threshold {
+			return nil, errors.New("sm2/threshold: invalid commitments")
+		}
+		if !isPaillierModulus(b.PaillierN) {
+			return nil, errors.New("sm2/threshold: invalid Paillier modulus")
+		}
+		key.peers[from] = paillier.NewPublicKey(b.PaillierN)
+
+		// check [f(id)]G = sum of [id^k]C_k.
+		share := shares[sIndex[from]].Share
+		if !isScalar(share, n) {
+			return nil, errors.New("sm2/threshold: invalid share")
+		}
+		x, y := new(big.Int), new(big.Int)
+		power := big.NewInt(1)
+		bid := big.NewInt(int64(g.id))
+		for k, commitment := range b.Commitments {
+			cx, cy, err := decodePoint(commitment)
+			if err != nil {
+				return nil, errors.New("sm2/threshold: invalid commitments")
+			}
+			if k == 0 {
+				pubX, pubY = c.Add(pubX, pubY, cx, cy)
+			}
+			cx, cy = c.ScalarMult(cx, cy, power.Bytes())
+			x, y = c.Add(x, y, cx, cy)
+			power.Mul(power, bid)
+			power.Mod(power, n)
+		}
+		wantX, wantY := c.ScalarBaseMult(share.Bytes())
+		if x.Cmp(wantX) != 0 || y.Cmp(wantY) != 0 {
+			return nil, errors.New("sm2/threshold: share does not match commitments")
+		}
+		key.share.Add(key.share, share)
+		key.share.Mod(key.share, n)
+	}
+
+	// the private key d must be in [1, n-2], i.e. P is neither the point at
+	// infinity nor -G.
+	key.PublicKey = &sm2.PublicKey{Curve: c, X: pubX, Y: pubY}
+	if err := key.PublicKey.Validate(); err != nil {
+		return nil, err
+	}
+	if x, y := c.Add(pubX, pubY, c.Params().Gx, c.Params().Gy); x.Sign() == 0 && y.Sign() == 0 {
+		return nil, errors.New("sm2/threshold: invalid public key")
+	}
+	g.coeffs = nil
+	return key, nil
+}
+
+// keyASN1 is the ASN.1 structure of a serialized Key.
+type keyASN1 struct {
+	ID        int
+	Threshold int
+	Parties   []int
+	PublicKey []byte // in the uncompressed form
+	UID       []byte `asn1:"optional,explicit,tag:0"` // ID of the public key if not nil
+	Share     *big.Int
+	PaillierN *big.Int
+	Lambda    *big.Int
+	Peers     []peerASN1
+}
+
+// peerASN1 is the Paillier public key of another party in keyASN1.
+type peerASN1 struct {
+	ID        int
+	PaillierN *big.Int
+}
+
+// MarshalBinary encodes the key share, including the share of the private key
+// and the Paillier private key, in ASN.1 DER. The encoding must be kept
+// confidential. It implements encoding.BinaryMarshaler.
+func (key *Key) MarshalBinary() ([]byte, error) {
+	if key.PublicKey == nil || key.share == nil || key.paillier == nil {
+		return nil, errors.New("sm2/threshold: incomplete key")
+	}
+	pub, err := key.PublicKey.Bytes(sm2.UncompressedPoint)
+	if err != nil {
+		return nil, err
+	}
+	k := keyASN1{
+		ID:        key.ID,
+		Threshold: key.Threshold,
+		Parties:   key.Parties,
+		PublicKey: pub,
+		UID:       key.PublicKey.ID,
+		Share:     key.share,
+		PaillierN: key.paillier.N,
+		Lambda:    key.paillier.Lambda(),
+	}
+	for _, id := range key.Parties {
+		if id == key.ID {
+			continue
+		}
+		peer, ok := key.peers[id]
+		if !ok {
+			return nil, errors.New("sm2/threshold: incomplete key")
+		}
+		k.Peers = append(k.Peers, peerASN1{ID: id, PaillierN: peer.N})
+	}
+	return asn1.Marshal(k)
+}
+
+// UnmarshalBinary decodes a key share encoded by MarshalBinary, and checks
+// that it is consistent. It implements encoding.BinaryUnmarshaler.
+func (key *Key) UnmarshalBinary(data []byte) error {
+	var k keyASN1
+	rest, err := asn1.Unmarshal(data, &k)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.New("sm2/threshold: trailing data after key")
+	}
+
+	parties, err := checkParties(k.Parties)
+	if err != nil {
+		return err
+	}
+	if k.Threshold < 1 || k.Threshold > len(parties) {
+		return errors.New("sm2/threshold: invalid threshold")
+	}
+	if indexOf(parties, k.ID) < 0 {
+		return errors.New("sm2/threshold: party not in the parties")
+	}
+	pub, err := sm2.ParsePublicKey(k.PublicKey)
+	if err != nil {
+		return err
+	}
+	if err := pub.Validate(); err != nil {
+		return err
+	}
+	pub.ID = k.UID
+	if !isScalar(k.Share, sm2.Curve().Params().N) {
+		return errors.New("sm2/threshold: invalid share")
+	}
+	if !isPaillierModulus(k.PaillierN) || k.Lambda == nil {
+		return errors.New("sm2/threshold: invalid Paillier modulus")
+	}
+	priv, err := paillier.NewPrivateKey(k.PaillierN, k.Lambda)
+	if err != nil {
+		return errors.New("sm2/threshold: invalid Paillier private key")
+	}
+	peers := make(map[int]*paillier.PublicKey, len(k.Peers))
+	for _, peer := range k.Peers {
+		if peer.ID == k.ID || indexOf(parties, peer.ID) < 0 {
+			return errors.New("sm2/threshold: peer not in the parties")
+		}
+		if _, ok := peers[peer.ID]; ok {
+			return errors.New("sm2/threshold: duplicate peer")
+		}
+		if !isPaillierModulus(peer.PaillierN) {
+			return errors.New("sm2/threshold: invalid Paillier modulus")
+		}
+		peers[peer.ID] = paillier.NewPublicKey(peer.PaillierN)
+	}
+	if len(peers) != len(parties)-1 {
+		return errors.New("sm2/threshold: missing peer")
+	}
+
+	*key = Key{
+		ID:        k.ID,
+		Threshold: k.Threshold,
+		Parties:   parties,
+		PublicKey: pub,
+		share:     k.Share,
+		paillier:  priv,
+		peers:     peers,
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package threshold
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2"
+	"github.com/need-being/gmcrypto/sm2/internal/paillier"
+)
+
+// The signature s = ((1 + d)^-1 * (k - rd)) mod n is rewritten as
+// s = (1 + d)^-1 * (k + r) - r, where each signer i holds the additive shares
+// k_i of k, u_i of 1 + d, and gamma_i of a random mask gamma. The signers
+// compute the additive shares delta_i of delta = gamma * (1 + d) and sigma_i
+// of sigma = gamma * k by multiplicative-to-additive conversions, open delta,
+// and then s + r = delta^-1 * (sigma + gamma * r).
+//
+//	round 1: broadcast K_i = [k_i]G and Enc_i(gamma_i)
+//	round 2: send Enc_j(gamma_j * u_i + beta) and Enc_j(gamma_j * k_i + beta')
+//	         to each signer j
+//	round 3: broadcast delta_i
+//	round 4: broadcast s_i = delta^-1 * (sigma_i + gamma_i * r)
+//	finish:  s = sum of s_i - r
+
+// SignRound1 is broadcast by each signer in round 1.
+type SignRound1 struct {
+	From int
+
+	// K is the point [k_i]G in the compressed form.
+	K []byte
+
+	// EncGamma is gamma_i encrypted with the Paillier key of the signer.
+	EncGamma *big.Int
+}
+
+// SignRound2 is sent by a signer to each other signer in round 2.
+type SignRound2 struct {
+	From, To int
+
+	// Delta and Sigma are the encryptions of gamma_To * u_From + beta and
+	// gamma_To * k_From + beta' with the Paillier key of the recipient,
+	// where beta and beta' are random masks.
+	Delta, Sigma *big.Int
+}
+
+// SignRound3 is broadcast by each signer in round 3.
+type SignRound3 struct {
+	From int
+
+	// Delta is the share delta_i of gamma * (1 + d).
+	Delta *big.Int
+}
+
+// SignRound4 is broadcast by each signer in round 4.
+type SignRound4 struct {
+	From int
+
+	// S is the share s_i of s + r.
+	S *big.Int
+}
+
+// SignSession is the state of a signer in signing a single message.
+// The rounds must be run in order, and a session cannot be reused.
+type SignSession struct {
+	key     *Key
+	rand    io.Reader
+	signers []int // IDs of the signers in ascending order
+	digest  []byte
+	round   int // last completed round
+
+	k, gamma, u *big.Int // shares of k, gamma and 1 + d
+	r           *big.Int
+	delta       *big.Int // share of delta in round 3, and delta in round 4
+	sigma       *big.Int // share of sigma
+	s           *big.Int // share of s + r
+	betaDelta   *big.Int // sum of the negated masks for delta
+	betaSigma   *big.Int // sum of the negated masks for sigma
+	round1      map[int]*SignRound1
+}
+
+// NewSignSession starts signing the message with the signers, which must
+// include the party of the key and at least Threshold parties. The hash value
+// e is computed with the ID of the public key of the key. It returns the
+// broadcast of round 1.
+func (key *Key) NewSignSession(rand io.Reader, signers []int, message []byte) (*SignSession, *SignRound1, error) {
+	signers, err := checkParties(signers)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(signers) < key.Threshold {
+		return nil, nil, errors.New("sm2/threshold: not enough signers")
+	}
+	for _, id := range signers {
+		if indexOf(key.Parties, id) < 0 {
+			return nil, nil, errors.New("sm2/threshold: signer not in the parties")
+		}
+	}
+	if indexOf(signers, key.ID) < 0 {
+		return nil, nil, errors.New("sm2/threshold: party not in the signers")
+	}
+
+	h, err := key.PublicKey.NewHash()
+	if err != nil {
+		return nil, nil, err
+	}
+	h.Write(message) // write on sm3 never returns error
+	sess := &SignSession{
+		key:     key,
+		rand:    rand,
+		signers: signers,
+		digest:  h.Sum(nil),
+	}
+
+	// u_i = lambda_i * x_i, plus 1 for the first signer.
+	c := sm2.Curve()
+	n := c.Params().N
+	sess.u = lagrange(key.ID, signers, n)
+	sess.u.Mul(sess.u, key.share)
+	if signers[0] == key.ID {
+		sess.u.Add(sess.u, one)
+	}
+	sess.u.Mod(sess.u, n)
+
+	if sess.k, err = randScalar(rand, n); err != nil {
+		return nil, nil, err
+	}
+	if sess.gamma, err = randScalar(rand, n); err != nil {
+		return nil, nil, err
+	}
+	msg := &SignRound1{From: key.ID}
+	if msg.K, err = encodePoint(c.ScalarBaseMult(sess.k.Bytes())); err != nil {
+		return nil, nil, err
+	}
+	if msg.EncGamma, err = key.paillier.Encrypt(rand, sess.gamma); err != nil {
+		return nil, nil, err
+	}
+	sess.round = 1
+	return sess, msg, nil
+}
+
+// Round2 computes r with the broadcasts of round 1 from all other signers, and
+// returns the messages of round 2 to be sent to each of them.
+func (sess *SignSession) Round2(msgs []*SignRound1) ([]*SignRound2, error) {
+	if sess.round != 1 {
+		return nil, errors.New("sm2/threshold: unexpected round")
+	}
+	sess.round = -1 // abort on any error
+	senders := make([]int, len(msgs))
+	for i, msg := range msgs {
+		if msg == nil {
+			return nil, errors.New("sm2/threshold: invalid message")
+		}
+		senders[i] = msg.From
+	}
+	index, err := indexMessages(senders, sess.signers, sess.key.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// compute (x1, y1) = [k]G = sum of K_i, and r = (e + x1) mod n.
+	c := sm2.Curve()
+	n := c.Params().N
+	x, y := c.ScalarBaseMult(sess.k.Bytes())
+	sess.round1 = make(map[int]*SignRound1, len(msgs))
+	for from, i := range index {
+		msg := msgs[i]
+		kx, ky, err := decodePoint(msg.K)
+		if err != nil {
+			return nil, errors.New("sm2/threshold: invalid message")
+		}
+		if err := sess.key.peers[from].ValidateCiphertext(msg.EncGamma); err != nil {
+			return nil, errors.New("sm2/threshold: invalid message")
+		}
+		x, y = c.Add(x, y, kx, ky)
+		sess.round1[from] = msg
+	}
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, errors.New("sm2/threshold: signing failed")
+	}
+	sess.r = new(big.Int).SetBytes(sess.digest)
+	sess.r.Add(sess.r, x)
+	sess.r.Mod(sess.r, n)
+	if sess.r.Sign() == 0 {
+		return nil, errors.New("sm2/threshold: signing failed")
+	}
+
+	// convert gamma_j * u_i and gamma_j * k_i to additive shares.
+	sess.betaDelta = new(big.Int)
+	sess.betaSigma = new(big.Int)
+	var out []*SignRound2
+	for _, to := range sess.signers {
+		if to == sess.key.ID {
+			continue
+		}
+		pub := sess.key.peers[to]
+		encGamma := sess.round1[to].EncGamma
+		delta, err := mta(sess.rand, pub, encGamma, sess.u, sess.betaDelta)
+		if err != nil {
+			return nil, err
+		}
+		sigma, err := mta(sess.rand, pub, encGamma, sess.k, sess.betaSigma)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &SignRound2{
+			From:  sess.key.ID,
+			To:    to,
+			Delta: delta,
+			Sigma: sigma,
+		})
+	}
+	sess.round = 2
+	return out, nil
+}
+
+// mta computes the encryption of a * b + beta with the Paillier key pub of the
+// holder of a, where encA is the encryption of a, and beta is a random mask
+// large enough to hide a * b statistically but never wrapping around modulo
+// N. -beta mod n is added to the share sum.
+func mta(random io.Reader, pub *paillier.PublicKey, encA, b, sum *big.Int) (*big.Int, error) {
+	n := sm2.Curve().Params().N
+	bound := new(big.Int).Mul(n, n)
+	bound.Sub(pub.N, bound)
+	beta, err := rand.Int(random, bound)
+	if err != nil {
+		return nil, err
+	}
+	encBeta, err := pub.Encrypt(random, beta)
+	if err != nil {
+		return nil, err
+	}
+	sum.Sub(sum, beta)
+	sum.Mod(sum, n)
+	return pub.Add(pub.Mul(encA, b), encBeta), nil
+}
+
+// Round3 computes the shares of delta and sigma with the messages of round 2
+// from all other signers, and returns the broadcast of round 3.
+func (sess *SignSession) Round3(msgs []*SignRound2) (*SignRound3, error) {
+	if sess.round != 2 {
+		return nil, errors.New("sm2/threshold: unexpected round")
+	}
+	sess.round = -1 // abort on any error
+	senders := make([]int, len(msgs))
+	for i, msg := range msgs {
+		if msg == nil || msg.To != sess.key.ID {
+			return nil, errors.New("sm2/threshold: invalid message")
+		}
+		senders[i] = msg.From
+	}
+	if _, err := indexMessages(senders, sess.signers, sess.key.ID); err != nil {
+		return nil, err
+	}
+
+	// delta_i = gamma_i * u_i + sum of alpha and beta, and the same for sigma_i.
+	n := sm2.Curve().Params().N
+	sess.delta = new(big.Int).Mul(sess.gamma, sess.u)
+	sess.delta.Add(sess.delta, sess.betaDelta)
+	sess.sigma = new(big.Int).Mul(sess.gamma, sess.k)
+	sess.sigma.Add(sess.sigma, sess.betaSigma)
+	for _, msg := range msgs {
+		alpha, err := sess.key.paillier.Decrypt(msg.Delta)
+		if err != nil {
+			return nil, errors.New("sm2/threshold: invalid message")
+		}
+		sess.delta.Add(sess.delta, alpha)
+		alpha, err = sess.key.paillier.Decrypt(msg.Sigma)
+		if err != nil {
+			return nil, errors.New("sm2/threshold: invalid message")
+		}
+		sess.sigma.Add(sess.sigma, alpha)
+	}
+	sess.delta.Mod(sess.delta, n)
+	sess.sigma.Mod(sess.sigma, n)
+	sess.round = 3
+	return &SignRound3{
+		From:  sess.key.ID,
+		Delta: new(big.Int).Set(sess.delta),
+	}, nil
+}
+
+// Round4 opens delta with the broadcasts of round 3 from all other signers, and
+// returns the broadcast of round 4.
+func (sess *SignSession) Round4(msgs []*SignRound3) (*SignRound4, error) {
+	if sess.round != 3 {
+		return nil, errors.New("sm2/threshold: unexpected round")
+	}
+	sess.round = -1 // abort on any error
+	senders := make([]int, len(msgs))
+	for i, msg := range msgs {
+		if msg == nil {
+			return nil, errors.New("sm2/threshold: invalid message")
+		}
+		senders[i] = msg.From
+	}
+	if _, err := indexMessages(senders, sess.signers, sess.key.ID); err != nil {
+		return nil, err
+	}
+
+	n := sm2.Curve().Params().N
+	for _, msg := range msgs {
+		if !isScalar(msg.Delta, n) {
+			return nil, errors.New("sm2/threshold: invalid message")
+		}
+		sess.delta.Add(sess.delta, msg.Delta)
+	}
+	sess.delta.Mod(sess.delta, n)
+	if sess.delta.Sign() == 0 {
+		return nil, errors.New("sm2/threshold: signing failed")
+	}
+
+	// s_i = delta^-1 * (sigma_i + gamma_i * r)
+	s := new(big.Int).Mul(sess.gamma, sess.r)
+	s.Add(s, sess.sigma)
+	s.Mul(s, new(big.Int).ModInverse(sess.delta, n))
+	s.Mod(s, n)
+	sess.s = s
+	sess.round = 4
+	return &SignRound4{
+		From: sess.key.ID,
+		S:    new(big.Int).Set(s),
+	}, nil
+}
+
+// Finish computes the signature in the form of r || s with the broadcasts of
+// round 4 from all other signers.
+// The signature is verified with the public key before it is returned, so
+// that invalid signature shares are detected.
+func (sess *SignSession) Finish(msgs []*SignRound4) ([]byte, error) {
+	if sess.round != 4 {
+		return nil, errors.New("sm2/threshold: unexpected round")
+	}
+	sess.round = -1 // a session cannot be reused
+	senders := make([]int, len(msgs))
+	for i, msg := range msgs {
+		if msg == nil {
+			return nil, errors.New("sm2/threshold: invalid message")
+		}
+		senders[i] = msg.From
+	}
+	if _, err := indexMessages(senders, sess.signers, sess.key.ID); err != nil {
+		return nil, err
+	}
+
+	// s = sum of s_i - r, where s + r = 0 implies k + r = 0.
+	n := sm2.Curve().Params().N
+	s := new(big.Int).Set(sess.s)
+	for _, msg := range msgs {
+		if !isScalar(msg.S, n) {
+			return nil, errors.New("sm2/threshold: invalid message")
+		}
+		s.Add(s, msg.S)
+	}
+	s.Mod(s, n)
+	if s.Sign() == 0 {
+		return nil, errors.New("sm2/threshold: signing failed")
+	}
+	s.Sub(s, sess.r)
+	s.Mod(s, n)
+	if s.Sign() == 0 {
+		return nil, errors.New("sm2/threshold: signing failed")
+	}
+
+	size := (n.BitLen() + 7) / 8
+	sig := make([]byte, 2*size)
+	sess.r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+	if !sm2.VerifyDigest(sess.key.PublicKey, sess.digest, sig) {
+		return nil, errors.New("sm2/threshold: invalid signature shares")
+	}
+	return sig, nil
+}
@@ -0,0 +1,132 @@
+// Package threshold implements t-of-n threshold signing for SM2, where the
+// private key is shared among n parties by distributed key generation, and
+// any t of them can jointly produce a signature which is verified by
+// sm2.Verify, while no t - 1 parties can sign or learn the private key.
+//
+// The key is shared by Feldman's verifiable secret sharing run by every party
+// in parallel, so that each party can check its shares against the public
+// commitments. In signing, the term (1 + d)^-1 is computed by masking 1 + d
+// with a shared random value gamma, whose product with 1 + d is computed and
+// opened through multiplicative-to-additive conversions based on the Paillier
+// cryptosystem.
+//
+// The protocols are secure against honest-but-curious parties, i.e. parties
+// following the protocols while trying to learn more. Deviations such as
+// invalid shares or signature shares are detected, and cause an abort, but
+// the protocols omit the zero-knowledge proofs needed against malicious
+// parties, e.g. on the Paillier moduli and the ranges of the encrypted values.
+// Messages between two parties must be sent over authenticated channels, and
+// DKGShare must also be kept confidential.
+package threshold
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/need-being/gmcrypto/sm2"
+)
+
+// paillierBits is the size of the Paillier moduli, which must be large enough
+// so that the product of two scalars and a statistically hiding mask never
+// wraps around.
+const paillierBits = 2048
+
+var one = big.NewInt(1)
+
+// checkParties checks that the party IDs are positive and distinct, and
+// returns them in ascending order.
+func checkParties(ids []int) ([]int, error) {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+	for i, id := range sorted {
+		if id <= 0 {
+			return nil, errors.New("sm2/threshold: party ID must be positive")
+		}
+		if i > 0 && sorted[i-1] == id {
+			return nil, errors.New("sm2/threshold: duplicate party ID")
+		}
+	}
+	return sorted, nil
+}
+
+// indexMessages checks that the senders of messages are exactly the parties
+// in ids other than self, and maps each sender to the position of its message.
+func indexMessages(senders, ids []int, self int) (map[int]int, error) {
+	expected := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if id != self {
+			expected[id] = true
+		}
+	}
+	index := make(map[int]int, len(senders))
+	for i, from := range senders {
+		if !expected[from] {
+			return nil, errors.New("sm2/threshold: message from unexpected party")
+		}
+		if _, ok := index[from]; ok {
+			return nil, errors.New("sm2/threshold: duplicate message")
+		}
+		index[from] = i
+	}
+	if len(index) != len(expected) {
+		return nil, errors.New("sm2/threshold: missing message")
+	}
+	return index, nil
+}
+
+// randScalar generates a random integer in [1, n-1].
+func randScalar(random io.Reader, n *big.Int) (*big.Int, error) {
+	k, err := rand.Int(random, new(big.Int).Sub(n, one))
+	if err != nil {
+		return nil, err
+	}
+	return k.Add(k, one), nil
+}
+
+// isScalar reports whether x is in [0, n-1].
+func isScalar(x, n *big.Int) bool {
+	return x != nil && x.Sign() >= 0 && x.Cmp(n) < 0
+}
+
+// isPaillierModulus reports whether n is an odd integer of at least
+// paillierBits bits.
+func isPaillierModulus(n *big.Int) bool {
+	return n != nil && n.BitLen() >= paillierBits && n.Bit(0) == 1
+}
+
+// encodePoint encodes a point in the compressed form.
+func encodePoint(x, y *big.Int) ([]byte, error) {
+	pub := &sm2.PublicKey{Curve: sm2.Curve(), X: x, Y: y}
+	return pub.Bytes(sm2.CompressedPoint)
+}
+
+// decodePoint decodes a point on the SM2 curve, where the point at infinity
+// is rejected.
+func decodePoint(b []byte) (x, y *big.Int, err error) {
+	pub, err := sm2.ParsePublicKey(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pub.X, pub.Y, nil
+}
+
+// lagrange computes the Lagrange coefficient of id at zero for the
+// interpolation over ids, i.e. the product of j / (j - id) for j != id.
+func lagrange(id int, ids []int, n *big.Int) *big.Int {
+	num := big.NewInt(1)
+	den := big.NewInt(1)
+	for _, j := range ids {
+		if j == id {
+			continue
+		}
+		num.Mul(num, big.NewInt(int64(j)))
+		num.Mod(num, n)
+		den.Mul(den, big.NewInt(int64(j-id)))
+		den.Mod(den, n)
+	}
+	den.ModInverse(den, n)
+	return num.Mul(num, den).Mod(num, n)
+}
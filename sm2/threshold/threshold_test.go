@@ -0,0 +1,375 @@
+package threshold
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/need-being/gmcrypto/sm2"
+)
+
+// runDKG runs distributed key generation with simulated parties in process.
+// If tamper is not nil, it is applied to the shares before delivery.
+func runDKG(t *testing.T, parties []int, threshold int, tamper func(*DKGShare)) ([]*Key, error) {
+	t.Helper()
+	dkgs := make([]*DKG, len(parties))
+	broadcasts := make([]*DKGBroadcast, len(parties))
+	var shares []*DKGShare
+	for i, id := range parties {
+		dkg, bcast, out, err := NewDKG(rand.Reader, id, parties, threshold)
+		if err != nil {
+			t.Fatal("NewDKG:", err)
+		}
+		dkgs[i] = dkg
+		broadcasts[i] = bcast
+		shares = append(shares, out...)
+	}
+	if tamper != nil {
+		for _, share := range shares {
+			tamper(share)
+		}
+	}
+
+	keys := make([]*Key, len(parties))
+	for i, id := range parties {
+		var bIn []*DKGBroadcast
+		for _, b := range broadcasts {
+			if b.From != id {
+				bIn = append(bIn, b)
+			}
+		}
+		var sIn []*DKGShare
+		for _, s := range shares {
+			if s.To == id {
+				sIn = append(sIn, s)
+			}
+		}
+		key, err := dkgs[i].Finish(bIn, sIn)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+// runSign runs signing with simulated signers in process.
+// If tamper is not nil, it is applied to the broadcasts of round 4 before
+// delivery.
+func runSign(t *testing.T, keys []*Key, message []byte, tamper func(*SignRound4)) ([]byte, error) {
+	t.Helper()
+	signers := make([]int, len(keys))
+	for i, key := range keys {
+		signers[i] = key.ID
+	}
+	sessions := make([]*SignSession, len(keys))
+	round1 := make([]*SignRound1, len(keys))
+	for i, key := range keys {
+		sess, msg, err := key.NewSignSession(rand.Reader, signers, message)
+		if err != nil {
+			t.Fatal("Key.NewSignSession:", err)
+		}
+		sessions[i] = sess
+		round1[i] = msg
+	}
+
+	var round2 []*SignRound2
+	for i, sess := range sessions {
+		out, err := sess.Round2(others1(round1, signers[i]))
+		if err != nil {
+			return nil, err
+		}
+		round2 = append(round2, out...)
+	}
+
+	round3 := make([]*SignRound3, len(keys))
+	for i, sess := range sessions {
+		var in []*SignRound2
+		for _, msg := range round2 {
+			if msg.To == signers[i] {
+				in = append(in, msg)
+			}
+		}
+		msg, err := sess.Round3(in)
+		if err != nil {
+			return nil, err
+		}
+		round3[i] = msg
+	}
+
+	round4 := make([]*SignRound4, len(keys))
+	for i, sess := range sessions {
+		var in []*SignRound3
+		for _, msg := range round3 {
+			if msg.From != signers[i] {
+				in = append(in, msg)
+			}
+		}
+		msg, err := sess.Round4(in)
+		if err != nil {
+			return nil, err
+		}
+		round4[i] = msg
+	}
+	if tamper != nil {
+		for _, msg := range round4 {
+			tamper(msg)
+		}
+	}
+
+	var sig []byte
+	for i, sess := range sessions {
+		var in []*SignRound4
+		for _, msg := range round4 {
+			if msg.From != signers[i] {
+				in = append(in, msg)
+			}
+		}
+		got, err := sess.Finish(in)
+		if err != nil {
+			return nil, err
+		}
+		if sig != nil && string(sig) != string(got) {
+			t.Fatalf("SignSession.Finish() = %x, which differs from %x", got, sig)
+		}
+		sig = got
+	}
+	return sig, nil
+}
+
+// others1 returns the broadcasts of round 1 from parties other than id.
+func others1(msgs []*SignRound1, id int) []*SignRound1 {
+	var out []*SignRound1
+	for _, msg := range msgs {
+		if msg.From != id {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+func TestThreshold(t *testing.T) {
+	parties := []int{1, 2, 3}
+	keys, err := runDKG(t, parties, 2, nil)
+	if err != nil {
+		t.Fatal("DKG.Finish:", err)
+	}
+	pub := keys[0].PublicKey
+	for _, key := range keys[1:] {
+		if !pub.Equal(key.PublicKey) {
+			t.Fatalf("public keys differ: %v, %v", pub, key.PublicKey)
+		}
+	}
+
+	// any two shares interpolate to the private key of the public key.
+	n := sm2.Curve().Params().N
+	for _, pair := range [][]int{{0, 1}, {0, 2}, {1, 2}} {
+		ids := []int{keys[pair[0]].ID, keys[pair[1]].ID}
+		d := new(big.Int)
+		for _, i := range pair {
+			term := lagrange(keys[i].ID, ids, n)
+			term.Mul(term, keys[i].share)
+			d.Add(d, term)
+		}
+		d.Mod(d, n)
+		priv := &sm2.PrivateKey{PublicKey: *pub, D: d}
+		if err := priv.Validate(); err != nil {
+			t.Fatalf("shares of %v: PrivateKey.Validate: %v", ids, err)
+		}
+	}
+
+	for _, key := range keys {
+		key.PublicKey.ID = []byte("custody")
+	}
+	tests := []struct {
+		name string
+		keys []*Key
+	}{
+		{
+			name: "signers 1 and 2",
+			keys: []*Key{keys[0], keys[1]},
+		},
+		{
+			name: "signers 3 and 1",
+			keys: []*Key{keys[2], keys[0]},
+		},
+		{
+			name: "all signers",
+			keys: keys,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := []byte(tt.name)
+			sig, err := runSign(t, tt.keys, message, nil)
+			if err != nil {
+				t.Fatal("sign:", err)
+			}
+			if !sm2.Verify(pub, message, sig) {
+				t.Errorf("signature %x cannot be verified", sig)
+			}
+		})
+	}
+
+	t.Run("serialized keys", func(t *testing.T) {
+		restored := make([]*Key, len(keys))
+		for i, key := range keys {
+			data, err := key.MarshalBinary()
+			if err != nil {
+				t.Fatal("Key.MarshalBinary:", err)
+			}
+			restored[i] = new(Key)
+			if err := restored[i].UnmarshalBinary(data); err != nil {
+				t.Fatal("Key.UnmarshalBinary:", err)
+			}
+			again, err := restored[i].MarshalBinary()
+			if err != nil {
+				t.Fatal("Key.MarshalBinary:", err)
+			}
+			if !bytes.Equal(again, data) {
+				t.Errorf("Key.MarshalBinary() of the restored key %d differs", key.ID)
+			}
+			if !bytes.Equal(restored[i].PublicKey.ID, key.PublicKey.ID) {
+				t.Errorf("restored ID of the public key = %q, want %q", restored[i].PublicKey.ID, key.PublicKey.ID)
+			}
+
+			// corrupted encodings are rejected.
+			for _, bad := range [][]byte{data[:len(data)-1], append(data, 0)} {
+				if err := new(Key).UnmarshalBinary(bad); err == nil {
+					t.Errorf("Key.UnmarshalBinary() of %d bytes succeeded, want error", len(bad))
+				}
+			}
+		}
+
+		message := []byte("serialized keys")
+		sig, err := runSign(t, []*Key{restored[1], keys[2]}, message, nil)
+		if err != nil {
+			t.Fatal("sign:", err)
+		}
+		if !sm2.Verify(pub, message, sig) {
+			t.Errorf("signature %x cannot be verified", sig)
+		}
+
+		// a nil ID, which stands for the default ID, and the empty ID give
+		// different Z, so they are kept apart.
+		for _, id := range [][]byte{nil, {}, []byte("alice")} {
+			key := *keys[0]
+			key.PublicKey = &sm2.PublicKey{Curve: pub.Curve, X: pub.X, Y: pub.Y, ID: id}
+			data, err := key.MarshalBinary()
+			if err != nil {
+				t.Fatal("Key.MarshalBinary:", err)
+			}
+			var got Key
+			if err := got.UnmarshalBinary(data); err != nil {
+				t.Fatal("Key.UnmarshalBinary:", err)
+			}
+			if (got.PublicKey.ID == nil) != (id == nil) || !bytes.Equal(got.PublicKey.ID, id) {
+				t.Errorf("restored ID of the public key = %#v, want %#v", got.PublicKey.ID, id)
+			}
+			z, err := got.PublicKey.Digest()
+			if err != nil {
+				t.Fatal("PublicKey.Digest:", err)
+			}
+			want, err := key.PublicKey.Digest()
+			if err != nil {
+				t.Fatal("PublicKey.Digest:", err)
+			}
+			if !bytes.Equal(z, want) {
+				t.Errorf("Z of the restored public key with ID %#v = %x, want %x", id, z, want)
+			}
+		}
+		if _, err := new(Key).MarshalBinary(); err == nil {
+			t.Error("Key.MarshalBinary() of an empty key succeeded, want error")
+		}
+	})
+
+	t.Run("tampered signature share", func(t *testing.T) {
+		_, err := runSign(t, keys[:2], []byte("message"), func(msg *SignRound4) {
+			if msg.From == 2 {
+				msg.S.Add(msg.S, one)
+				msg.S.Mod(msg.S, n)
+			}
+		})
+		if err == nil {
+			t.Error("signing succeeded with a tampered signature share")
+		}
+	})
+
+	t.Run("not enough signers", func(t *testing.T) {
+		if _, _, err := keys[0].NewSignSession(rand.Reader, []int{1}, nil); err == nil {
+			t.Error("Key.NewSignSession() succeeded with 1 signer")
+		}
+	})
+
+	t.Run("unexpected round", func(t *testing.T) {
+		sess, _, err := keys[0].NewSignSession(rand.Reader, []int{1, 2}, nil)
+		if err != nil {
+			t.Fatal("Key.NewSignSession:", err)
+		}
+		if _, err := sess.Round3(nil); err == nil {
+			t.Error("SignSession.Round3() succeeded before Round2")
+		}
+		if _, err := sess.Round2(nil); err == nil {
+			t.Error("SignSession.Round2() succeeded without messages")
+		}
+	})
+}
+
+func TestDKG_invalid(t *testing.T) {
+	t.Run("tampered share", func(t *testing.T) {
+		_, err := runDKG(t, []int{1, 2}, 2, func(share *DKGShare) {
+			if share.From == 1 {
+				share.Share.Add(share.Share, one)
+			}
+		})
+		if err == nil {
+			t.Error("DKG.Finish() accepted a tampered share")
+		}
+	})
+
+	tests := []struct {
+		name      string
+		id        int
+		parties   []int
+		threshold int
+	}{
+		{
+			name:      "threshold too large",
+			id:        1,
+			parties:   []int{1, 2},
+			threshold: 3,
+		},
+		{
+			name:      "zero threshold",
+			id:        1,
+			parties:   []int{1, 2},
+			threshold: 0,
+		},
+		{
+			name:      "duplicate party",
+			id:        1,
+			parties:   []int{1, 2, 2},
+			threshold: 2,
+		},
+		{
+			name:      "party not positive",
+			id:        0,
+			parties:   []int{0, 1},
+			threshold: 2,
+		},
+		{
+			name:      "party not in the parties",
+			id:        3,
+			parties:   []int{1, 2},
+			threshold: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := NewDKG(rand.Reader, tt.id, tt.parties, tt.threshold); err == nil {
+				t.Error("NewDKG() succeeded, want error")
+			}
+		})
+	}
+}
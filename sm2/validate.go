@@ -0,0 +1,99 @@
+package sm2
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2/internal/sm2ec"
+)
+
+// Validate checks if pub is a valid public key as specified in
+// GB/T 32918.1-2016 6.2.1.
+func (pub *PublicKey) Validate() error {
+	if pub.Curve == nil || pub.X == nil || pub.Y == nil {
+		return errors.New("sm2: incomplete public key")
+	}
+
+	// a) check P is not the point at infinity, which is represented as (0, 0).
+	if pub.X.Sign() == 0 && pub.Y.Sign() == 0 {
+		return errors.New("sm2: public key is the point at infinity")
+	}
+
+	// b) check x and y are elements of the field, i.e. in [0, p-1].
+	params := pub.Curve.Params()
+	if pub.X.Sign() < 0 || pub.X.Cmp(params.P) >= 0 || pub.Y.Sign() < 0 || pub.Y.Cmp(params.P) >= 0 {
+		return errors.New("sm2: public key not in the field")
+	}
+
+	// c) check y^2 = x^3 + ax + b.
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return errors.New("sm2: public key not on the curve")
+	}
+
+	// d) check [n]P = O, which always holds for the SM2 curve since its
+	// cofactor h is 1.
+	if pub.Curve != Curve() {
+		x, y := pub.Curve.ScalarMult(pub.X, pub.Y, params.N.Bytes())
+		if x.Sign() != 0 || y.Sign() != 0 {
+			return errors.New("sm2: public key not in the subgroup")
+		}
+	}
+	return nil
+}
+
+// Validate checks if priv is a valid private key, whose public key is valid
+// as specified in GB/T 32918.1-2016 6.2.1 and matches the private key.
+func (priv *PrivateKey) Validate() error {
+	if err := priv.PublicKey.Validate(); err != nil {
+		return err
+	}
+	if priv.D == nil {
+		return errors.New("sm2: incomplete private key")
+	}
+
+	if c, ok := priv.Curve.(*sm2Curve); ok {
+		return c.validatePrivateKey(priv)
+	}
+
+	// check d in [1, n-2], as required by GB/T 32918.1-2016 6.1.
+	n := new(big.Int).Sub(priv.Curve.Params().N, one)
+	if priv.D.Sign() <= 0 || priv.D.Cmp(n) >= 0 {
+		return errors.New("sm2: private key out of range")
+	}
+
+	// check P = dG.
+	x, y := priv.Curve.ScalarBaseMult(priv.D.Bytes())
+	if x.Cmp(priv.X) != 0 || y.Cmp(priv.Y) != 0 {
+		return errors.New("sm2: private key does not match public key")
+	}
+	return nil
+}
+
+// validatePrivateKey is the same as PrivateKey.Validate on the SM2 curve,
+// where d is loaded once as a fixed-width scalar, and checked in constant
+// time.
+func (c *sm2Curve) validatePrivateKey(priv *PrivateKey) error {
+	d, err := privateScalar(priv.D)
+	if err != nil {
+		return err
+	}
+
+	// check d in [1, n-2], i.e. d != 0 and d + 1 != 0 mod n, as required by
+	// GB/T 32918.1-2016 6.1.
+	d1 := sm2ec.NewScalar().One()
+	d1.Add(d1, d)
+	if d.IsZero()|d1.IsZero() == 1 {
+		return errors.New("sm2: private key out of range")
+	}
+
+	// check P = dG.
+	p, err := sm2ec.NewPoint().ScalarBaseMult(d.Bytes())
+	if err != nil {
+		return err
+	}
+	x, y := c.pointToAffine(p)
+	if x.Cmp(priv.X) != 0 || y.Cmp(priv.Y) != 0 {
+		return errors.New("sm2: private key does not match public key")
+	}
+	return nil
+}
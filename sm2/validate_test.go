@@ -0,0 +1,184 @@
+package sm2
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestPublicKey_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		pub     *PublicKey
+		wantErr bool
+	}{
+		{
+			name: "GB/T 32918.5-2017 A.2",
+			pub:  &testKey.PublicKey,
+		},
+		{
+			name: "generic curve",
+			pub:  &PublicKey{Curve: curve.Params(), X: testKey.X, Y: testKey.Y},
+		},
+		{
+			name:    "missing coordinates",
+			pub:     &PublicKey{Curve: curve, X: testKey.X},
+			wantErr: true,
+		},
+		{
+			name:    "point at infinity",
+			pub:     &PublicKey{Curve: curve, X: new(big.Int), Y: new(big.Int)},
+			wantErr: true,
+		},
+		{
+			name:    "x not in field",
+			pub:     &PublicKey{Curve: curve, X: new(big.Int).Add(testKey.X, curve.P), Y: testKey.Y},
+			wantErr: true,
+		},
+		{
+			name:    "negative y",
+			pub:     &PublicKey{Curve: curve, X: testKey.X, Y: new(big.Int).Sub(testKey.Y, curve.P)},
+			wantErr: true,
+		},
+		{
+			name:    "not on curve",
+			pub:     &PublicKey{Curve: curve, X: testKey.X, Y: new(big.Int).Add(testKey.Y, one)},
+			wantErr: true,
+		},
+		{
+			name:    "not in subgroup",
+			pub:     &PublicKey{Curve: elliptic.P256(), X: testKey.X, Y: testKey.Y},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.pub.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("PublicKey.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrivateKey_Validate(t *testing.T) {
+	mismatched, err := GenerateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	x2, y2 := curve.Double(curve.Gx, curve.Gy)
+	tests := []struct {
+		name    string
+		priv    *PrivateKey
+		wantErr bool
+	}{
+		{
+			name: "GB/T 32918.5-2017 A.2",
+			priv: testKey,
+		},
+		{
+			name: "generic curve",
+			priv: &PrivateKey{
+				PublicKey: PublicKey{
+					Curve: curve.Params(),
+					X:     testKey.X,
+					Y:     testKey.Y,
+				},
+				D: testKey.D,
+			},
+		},
+		{
+			name: "n-2",
+			priv: &PrivateKey{
+				PublicKey: PublicKey{
+					Curve: curve,
+					X:     x2,
+					Y:     new(big.Int).Sub(curve.P, y2),
+				},
+				D: new(big.Int).Sub(curve.N, big.NewInt(2)),
+			},
+		},
+		{
+			name:    "negative",
+			priv:    &PrivateKey{PublicKey: testKey.PublicKey, D: new(big.Int).Neg(testKey.D)},
+			wantErr: true,
+		},
+		{
+			name:    "n",
+			priv:    &PrivateKey{PublicKey: testKey.PublicKey, D: curve.N},
+			wantErr: true,
+		},
+		{
+			name:    "n+d",
+			priv:    &PrivateKey{PublicKey: testKey.PublicKey, D: new(big.Int).Add(curve.N, testKey.D)},
+			wantErr: true,
+		},
+		{
+			name:    "missing private key",
+			priv:    &PrivateKey{PublicKey: testKey.PublicKey},
+			wantErr: true,
+		},
+		{
+			name:    "zero",
+			priv:    &PrivateKey{PublicKey: testKey.PublicKey, D: new(big.Int)},
+			wantErr: true,
+		},
+		{
+			name: "n-1",
+			priv: &PrivateKey{
+				PublicKey: PublicKey{
+					Curve: curve,
+					X:     curve.Gx,
+					Y:     new(big.Int).Sub(curve.P, curve.Gy),
+				},
+				D: new(big.Int).Sub(curve.N, one),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "public key mismatched",
+			priv:    &PrivateKey{PublicKey: mismatched.PublicKey, D: testKey.D},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.priv.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("PrivateKey.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvalidKeysRejected(t *testing.T) {
+	invalidPub := &PublicKey{Curve: curve, X: testKey.X, Y: new(big.Int).Add(testKey.Y, one)}
+	invalidPriv := &PrivateKey{PublicKey: *invalidPub, D: testKey.D}
+	message := []byte("message")
+
+	if _, err := Sign(rand.Reader, invalidPriv, message); err == nil {
+		t.Error("Sign() with invalid key succeeded, want error")
+	}
+	sig, err := Sign(rand.Reader, testKey, message)
+	if err != nil {
+		t.Fatal("Sign:", err)
+	}
+	if Verify(invalidPub, message, sig) {
+		t.Error("Verify() with invalid key = true, want false")
+	}
+	if _, err := Encrypt(rand.Reader, invalidPub, message); err == nil {
+		t.Error("Encrypt() with invalid key succeeded, want error")
+	}
+	ciphertext, err := Encrypt(rand.Reader, &testKey.PublicKey, message)
+	if err != nil {
+		t.Fatal("Encrypt:", err)
+	}
+	if _, err := Decrypt(invalidPriv, ciphertext); err == nil {
+		t.Error("Decrypt() with invalid key succeeded, want error")
+	}
+	if _, err := NewKeyExchangeInitiator(rand.Reader, testKey, invalidPub); err == nil {
+		t.Error("NewKeyExchangeInitiator() with invalid peer key succeeded, want error")
+	}
+	if _, err := NewKeyExchangeResponder(rand.Reader, invalidPriv, &testKey.PublicKey); err == nil {
+		t.Error("NewKeyExchangeResponder() with invalid key succeeded, want error")
+	}
+}
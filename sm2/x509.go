@@ -0,0 +1,271 @@
+package sm2
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+
+	"github.com/need-being/gmcrypto/sm2/internal/convert"
+)
+
+// object identifiers of SM2 keys.
+var (
+	// oidPublicKeyEC is id-ecPublicKey defined in RFC 5480.
+	oidPublicKeyEC = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+	// oidNamedCurveSM2 is the SM2 curve defined in GM/T 0006-2012.
+	oidNamedCurveSM2 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+)
+
+// ecPrivateKeyVersion is the version of ecPrivateKey defined in RFC 5915.
+const ecPrivateKeyVersion = 1
+
+// publicKeyInfo is SubjectPublicKeyInfo defined in RFC 5280.
+type publicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// pkcs8 is PrivateKeyInfo defined in RFC 5208.
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	// optional attributes omitted.
+}
+
+// ecPrivateKey is ECPrivateKey defined in SEC 1 and RFC 5915.
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// algorithmIdentifier returns the algorithm identifier of SM2 keys, which is
+// id-ecPublicKey with the SM2 curve as parameters.
+func algorithmIdentifier() (pkix.AlgorithmIdentifier, error) {
+	params, err := asn1.Marshal(oidNamedCurveSM2)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{
+		Algorithm: oidPublicKeyEC,
+		Parameters: asn1.RawValue{
+			FullBytes: params,
+		},
+	}, nil
+}
+
+// checkAlgorithmIdentifier checks if the algorithm identifier represents an
+// SM2 key.
+func checkAlgorithmIdentifier(algo pkix.AlgorithmIdentifier) error {
+	if !algo.Algorithm.Equal(oidPublicKeyEC) {
+		return errors.New("sm2: unknown public key algorithm")
+	}
+	var namedCurve asn1.ObjectIdentifier
+	rest, err := asn1.Unmarshal(algo.Parameters.FullBytes, &namedCurve)
+	if err != nil {
+		return errors.New("sm2: failed to parse curve parameters")
+	}
+	if len(rest) != 0 {
+		return errors.New("sm2: trailing data after curve parameters")
+	}
+	if !namedCurve.Equal(oidNamedCurveSM2) {
+		return errors.New("sm2: unsupported elliptic curve")
+	}
+	return nil
+}
+
+// MarshalPKIXPublicKey converts a public key to PKIX, ASN.1 DER form.
+// The encoded public key is a SubjectPublicKeyInfo structure
+// (see RFC 5280, Section 4.1).
+//
+// This kind of key is commonly encoded in PEM blocks of type "PUBLIC KEY".
+func MarshalPKIXPublicKey(pub *PublicKey) ([]byte, error) {
+	if pub.Curve != curve {
+		return nil, errors.New("sm2: unsupported elliptic curve")
+	}
+	algo, err := algorithmIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	publicKeyBytes, err := marshalPoint(pub.Curve, pub.X, pub.Y, UncompressedPoint)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(publicKeyInfo{
+		Algorithm: algo,
+		PublicKey: asn1.BitString{
+			Bytes:     publicKeyBytes,
+			BitLength: 8 * len(publicKeyBytes),
+		},
+	})
+}
+
+// ParsePKIXPublicKey parses a public key in PKIX, ASN.1 DER form.
+// The encoded public key is a SubjectPublicKeyInfo structure
+// (see RFC 5280, Section 4.1).
+//
+// This kind of key is commonly encoded in PEM blocks of type "PUBLIC KEY".
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	var pki publicKeyInfo
+	rest, err := asn1.Unmarshal(der, &pki)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("sm2: trailing data after public key")
+	}
+	if err := checkAlgorithmIdentifier(pki.Algorithm); err != nil {
+		return nil, err
+	}
+	x, y, err := unmarshalPoint(pki.PublicKey.RightAlign())
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{
+		Curve: curve,
+		X:     x,
+		Y:     y,
+	}, nil
+}
+
+// MarshalPKCS8PrivateKey converts a private key to PKCS #8, ASN.1 DER form.
+//
+// This kind of key is commonly encoded in PEM blocks of type "PRIVATE KEY".
+func MarshalPKCS8PrivateKey(priv *PrivateKey) ([]byte, error) {
+	if priv.Curve != curve {
+		return nil, errors.New("sm2: unsupported elliptic curve")
+	}
+	algo, err := algorithmIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	// the curve is omitted since it is already in the algorithm identifier.
+	privateKey, err := marshalECPrivateKey(priv, nil)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{
+		Algo:       algo,
+		PrivateKey: privateKey,
+	})
+}
+
+// ParsePKCS8PrivateKey parses a private key in PKCS #8, ASN.1 DER form.
+//
+// This kind of key is commonly encoded in PEM blocks of type "PRIVATE KEY".
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	var privKey pkcs8
+	rest, err := asn1.Unmarshal(der, &privKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("sm2: trailing data after private key")
+	}
+	if err := checkAlgorithmIdentifier(privKey.Algo); err != nil {
+		return nil, err
+	}
+	return parseECPrivateKey(privKey.PrivateKey, oidNamedCurveSM2)
+}
+
+// MarshalECPrivateKey converts a private key to SEC 1, ASN.1 DER form.
+//
+// This kind of key is commonly encoded in PEM blocks of type "EC PRIVATE KEY"
+// or "SM2 PRIVATE KEY".
+func MarshalECPrivateKey(priv *PrivateKey) ([]byte, error) {
+	if priv.Curve != curve {
+		return nil, errors.New("sm2: unsupported elliptic curve")
+	}
+	return marshalECPrivateKey(priv, oidNamedCurveSM2)
+}
+
+// ParseECPrivateKey parses a private key in SEC 1, ASN.1 DER form. The key
+// must name the SM2 curve, and its public key, if present, must match the
+// private key.
+//
+// This kind of key is commonly encoded in PEM blocks of type "EC PRIVATE KEY"
+// or "SM2 PRIVATE KEY".
+func ParseECPrivateKey(der []byte) (*PrivateKey, error) {
+	return parseECPrivateKey(der, nil)
+}
+
+// marshalECPrivateKey marshals a private key into ECPrivateKey, optionally
+// with the curve.
+func marshalECPrivateKey(priv *PrivateKey, oid asn1.ObjectIdentifier) ([]byte, error) {
+	params := priv.Curve.Params()
+	privateKey := make([]byte, (params.N.BitLen()+7)/8)
+	if err := convert.IntegerToBytes(priv.D, privateKey); err != nil {
+		return nil, err
+	}
+	publicKeyBytes, err := marshalPoint(priv.Curve, priv.X, priv.Y, UncompressedPoint)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ecPrivateKey{
+		Version:       ecPrivateKeyVersion,
+		PrivateKey:    privateKey,
+		NamedCurveOID: oid,
+		PublicKey: asn1.BitString{
+			Bytes:     publicKeyBytes,
+			BitLength: 8 * len(publicKeyBytes),
+		},
+	})
+}
+
+// parseECPrivateKey parses an ECPrivateKey on the SM2 curve, where oid is the
+// curve named outside the key, e.g. in the algorithm identifier of PKCS #8, or
+// nil if the key must name the curve itself. The public key is derived from
+// the private key, and checked against the one in the key if present.
+func parseECPrivateKey(der []byte, oid asn1.ObjectIdentifier) (*PrivateKey, error) {
+	var privKey ecPrivateKey
+	rest, err := asn1.Unmarshal(der, &privKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("sm2: trailing data after private key")
+	}
+	if privKey.Version != ecPrivateKeyVersion {
+		return nil, errors.New("sm2: unknown EC private key version")
+	}
+	if privKey.NamedCurveOID != nil {
+		oid = privKey.NamedCurveOID
+	}
+	if oid == nil {
+		return nil, errors.New("sm2: missing elliptic curve")
+	}
+	if !oid.Equal(oidNamedCurveSM2) {
+		return nil, errors.New("sm2: unsupported elliptic curve")
+	}
+
+	// check d in [1, n-2]
+	params := curve.Params()
+	d := convert.BytesToInteger(privKey.PrivateKey)
+	if d.Sign() <= 0 || d.Cmp(new(big.Int).Sub(params.N, one)) >= 0 {
+		return nil, errors.New("sm2: invalid private key value")
+	}
+
+	x, y := curve.ScalarBaseMult(curve.scalarBytes(d))
+	if privKey.PublicKey.BitLength != 0 {
+		px, py, err := unmarshalPoint(privKey.PublicKey.RightAlign())
+		if err != nil {
+			return nil, err
+		}
+		if px.Cmp(x) != 0 || py.Cmp(y) != 0 {
+			return nil, errors.New("sm2: public key does not match private key")
+		}
+	}
+	return &PrivateKey{
+		PublicKey: PublicKey{
+			Curve: curve,
+			X:     x,
+			Y:     y,
+		},
+		D: d,
+	}, nil
+}
@@ -0,0 +1,104 @@
+// Package hkdf implements the HMAC-based Extract-and-Expand Key Derivation
+// Function (HKDF) as defined in RFC 5869, instantiated with SM3.
+//
+// HKDF is a cryptographic key derivation function (KDF) with the goal of
+// expanding limited input keying material into one or more cryptographically
+// strong secret keys.
+package hkdf
+
+import (
+	"crypto/hmac"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/need-being/gmcrypto/sm3"
+)
+
+// MaxLength is the maximum length of the output keying material in bytes,
+// which is 255 times the SM3 checksum size.
+const MaxLength = 255 * sm3.Size
+
+// Extract generates a pseudorandom key for use with Expand from an input
+// secret and an optional independent salt.
+//
+// Only use this function if you need to reuse the extracted key with multiple
+// Expand invocations and different context values. Most common scenarios,
+// including the generation of multiple keys, should use New instead.
+func Extract(secret, salt []byte) []byte {
+	if salt == nil {
+		salt = make([]byte, sm3.Size)
+	}
+	extractor := hmac.New(sm3.New, salt)
+	extractor.Write(secret) // write on sm3 never returns error
+	return extractor.Sum(nil)
+}
+
+// reader is the output keying material of HKDF.
+type reader struct {
+	expander hash.Hash
+	info     []byte
+	counter  byte
+	prev     []byte // T(counter)
+	buf      []byte // unread bytes of T(counter)
+}
+
+// Read reads the next len(p) bytes of the output keying material. It returns
+// an error after MaxLength bytes.
+func (r *reader) Read(p []byte) (int, error) {
+	need := len(p)
+	remains := len(r.buf) + int(255-r.counter)*sm3.Size
+	if remains < need {
+		return 0, errors.New("sm3/hkdf: entropy limit reached")
+	}
+
+	n := copy(p, r.buf)
+	p = p[n:]
+	for len(p) > 0 {
+		// T(i) = HMAC-Hash(PRK, T(i-1) || info || i)
+		r.counter++
+		r.expander.Reset()
+		r.expander.Write(r.prev)
+		r.expander.Write(r.info)
+		r.expander.Write([]byte{r.counter})
+		r.prev = r.expander.Sum(r.prev[:0])
+		r.buf = r.prev
+		n = copy(p, r.buf)
+		p = p[n:]
+	}
+	r.buf = r.buf[n:]
+	return need, nil
+}
+
+// Expand returns a reader, from which keys can be read, using the given
+// pseudorandom key and optional context info, skipping the extraction step.
+//
+// The pseudorandomKey should have been generated by Extract, or be a uniformly
+// random or pseudorandom cryptographically strong key. See RFC 5869, Section
+// 3.3.
+func Expand(pseudorandomKey, info []byte) io.Reader {
+	return &reader{
+		expander: hmac.New(sm3.New, pseudorandomKey),
+		info:     append([]byte(nil), info...),
+		prev:     make([]byte, 0, sm3.Size),
+	}
+}
+
+// New returns a reader, from which keys can be read, using the given secret,
+// salt and context info. Salt and info can be nil.
+func New(secret, salt, info []byte) io.Reader {
+	return Expand(Extract(secret, salt), info)
+}
+
+// Key derives a key of length bytes from the given secret, salt and context
+// info in one shot. It returns an error if length exceeds MaxLength.
+func Key(secret, salt, info []byte, length int) ([]byte, error) {
+	if length < 0 {
+		return nil, errors.New("sm3/hkdf: negative key length")
+	}
+	key := make([]byte, length)
+	if _, err := io.ReadFull(New(secret, salt, info), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
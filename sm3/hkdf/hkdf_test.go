@@ -0,0 +1,92 @@
+package hkdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+// The inputs are from RFC 5869 A.1 and A.3, and the outputs are computed by
+// OpenSSL 3.0 with `openssl kdf -kdfopt digest:SM3 HKDF`.
+var tests = []struct {
+	name   string
+	secret string
+	salt   string
+	info   string
+	prk    string
+	okm    string
+}{
+	{
+		name:   "basic",
+		secret: "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+		salt:   "000102030405060708090a0b0c",
+		info:   "f0f1f2f3f4f5f6f7f8f9",
+		prk:    "e0d6f7b0bd056327b7659f1f39ad850561fbcf4fb10fb58e88eafa55cf7cd01e",
+		okm:    "c69fe91b7aaee2dd5718d72dcaee0cce93f1b8e41f792da51261b6a517e68b36ed2c595572b01dfa359b",
+	},
+	{
+		name:   "zero-length salt and info",
+		secret: "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+		okm:    "c8c91a38ae2fb3b023a7c38ce9f0748f28230d59b6b950ba3ba949bf0d713a5774815778801741cb2034",
+	},
+}
+
+func TestHKDF(t *testing.T) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, _ := hex.DecodeString(tt.secret)
+			salt, _ := hex.DecodeString(tt.salt)
+			info, _ := hex.DecodeString(tt.info)
+			want, _ := hex.DecodeString(tt.okm)
+
+			if tt.prk != "" {
+				if got := hex.EncodeToString(Extract(secret, salt)); got != tt.prk {
+					t.Errorf("Extract() = %s, want %s", got, tt.prk)
+				}
+			}
+			got, err := Key(secret, salt, info, len(want))
+			if err != nil {
+				t.Fatal("Key:", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Key() = %x, want %x", got, want)
+			}
+
+			// read in pieces
+			r := New(secret, salt, info)
+			got = got[:0]
+			for _, n := range []int{1, 30, 2, 0, 9} {
+				buf := make([]byte, n)
+				if _, err := io.ReadFull(r, buf); err != nil {
+					t.Fatal("Read:", err)
+				}
+				got = append(got, buf...)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("New() read in pieces = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestHKDF_limit(t *testing.T) {
+	r := New([]byte("secret"), nil, nil)
+	buf := make([]byte, MaxLength)
+	if _, err := io.ReadFull(r, buf[:MaxLength-1]); err != nil {
+		t.Fatal("Read:", err)
+	}
+	if _, err := r.Read(buf[:2]); err == nil {
+		t.Error("Read() beyond MaxLength succeeded, want error")
+	}
+	if _, err := r.Read(buf[:1]); err != nil {
+		t.Error("Read() up to MaxLength:", err)
+	}
+
+	if key, err := Key([]byte("secret"), nil, nil, MaxLength+1); err == nil {
+		t.Errorf("Key(MaxLength+1) = %x, want error", key)
+	}
+	if key, err := Key([]byte("secret"), nil, nil, -1); err == nil {
+		t.Errorf("Key(-1) = %x, want error", key)
+	}
+}
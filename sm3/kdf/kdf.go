@@ -0,0 +1,84 @@
+// Package kdf implements the key derivation function defined in GB/T
+// 32918.4-2016 5.4.3, which derives keying material from a shared secret Z by
+// SM3 in counter mode:
+//
+//	K = SM3(Z || 1) || SM3(Z || 2) || ... || SM3(Z || ct)
+//
+// where the counter ct is a 32-bit big-endian integer starting from 1.
+// It is used by SM2 encryption and the SM2 key exchange protocol, and
+// applicable to TLCP and other protocols based on them.
+package kdf
+
+import (
+	"encoding/binary"
+	"hash"
+	"io"
+
+	"github.com/need-being/gmcrypto/sm3"
+)
+
+// MaxLength is the maximum length of a derived key in bytes, which is limited
+// by the 32-bit counter.
+const MaxLength = (1<<32 - 1) * sm3.Size
+
+// reader is the keystream of a shared secret.
+type reader struct {
+	z   []byte
+	h   hash.Hash
+	ct  uint32         // counter of the block in buf
+	buf [sm3.Size]byte // current block of the keystream
+	off int            // read offset in buf
+}
+
+// NewReader returns a reader of the keystream derived from the shared secret
+// z, so that a key of any length can be read from it in pieces. Reading n
+// bytes in total produces the same bytes as Key(z, n).
+// The reader returns io.EOF after MaxLength bytes.
+func NewReader(z []byte) io.Reader {
+	return &reader{
+		z:   append([]byte(nil), z...),
+		h:   sm3.New(),
+		off: sm3.Size,
+	}
+}
+
+// Read reads the next len(p) bytes of the keystream.
+func (r *reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if r.off == sm3.Size {
+			if r.ct == 1<<32-1 {
+				return n, io.EOF
+			}
+			r.ct++
+			r.next()
+		}
+		c := copy(p[n:], r.buf[r.off:])
+		r.off += c
+		n += c
+	}
+	return n, nil
+}
+
+// next computes the block of the keystream for the current counter.
+func (r *reader) next() {
+	var counter [4]byte
+	binary.BigEndian.PutUint32(counter[:], r.ct)
+	r.h.Reset()
+	r.h.Write(r.z) // write on sm3 never returns error
+	r.h.Write(counter[:])
+	r.h.Sum(r.buf[:0])
+	r.off = 0
+}
+
+// Key derives a key of length bytes from the shared secret z.
+// It panics if length is negative or greater than MaxLength.
+func Key(z []byte, length int) []byte {
+	if length < 0 || uint64(length) > MaxLength {
+		panic("sm3/kdf: invalid key length")
+	}
+	key := make([]byte, length)
+	r := NewReader(z)
+	r.Read(key) // never fails within MaxLength
+	return key
+}
@@ -0,0 +1,106 @@
+package kdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+var tests = []struct {
+	name string
+	z    string
+	key  string
+}{
+	{
+		// GB/T 32918.5-2017 C.2, t = KDF(x2 || y2, klen)
+		name: "encryption",
+		z: "335e18d751e51f040e27d468138b7ab1dc86ad7f981d7d416222fd6ab3ed230d" +
+			"ab743ebcfb22d64f7b6ab791f70658f25b48fa93e54064fdbfbed3f0bd847ac9",
+		key: "44e60fdbf0bae81437665374bef26749046c9e",
+	},
+	{
+		// GB/T 32918.5-2017 B.2, K = KDF(xV || yV || ZA || ZB, klen)
+		name: "key exchange",
+		z: "c558b44bee5301d9f52b44d939bb59584d75b9034dd6a9fc826872109a65739f" +
+			"3252b35b191d8ae01cd122c025204334c5eacf68a0cb4854c6a7d367ecad4de7" +
+			"3b85a57179e11e7e513aa622991f2ca74d1807a0bd4d4b38f90987a17ac245b1" +
+			"79c988d63229d97ef19fe02ca1056e01e6a7411ed24694aa8f834f4a4ab022f7",
+		key: "6c89347354de2484c60b4ab1fde4c6e5",
+	},
+}
+
+func TestKey(t *testing.T) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z, _ := hex.DecodeString(tt.z)
+			want, _ := hex.DecodeString(tt.key)
+			if got := Key(z, len(want)); !bytes.Equal(got, want) {
+				t.Errorf("Key() = %x, want %x", got, want)
+			}
+		})
+	}
+
+	if got := Key([]byte("z"), 0); len(got) != 0 {
+		t.Errorf("Key(0) = %x, want empty", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Key(-1) did not panic")
+		}
+	}()
+	Key([]byte("z"), -1)
+}
+
+func TestNewReader(t *testing.T) {
+	z := []byte("shared secret")
+	want := Key(z, 1000)
+	for _, size := range []int{1, 7, 31, 32, 33, 64, 1000} {
+		r := NewReader(z)
+		got := make([]byte, 0, len(want))
+		buf := make([]byte, size)
+		for len(got) < len(want) {
+			n, err := r.Read(buf)
+			if err != nil {
+				t.Fatal("Read:", err)
+			}
+			got = append(got, buf[:n]...)
+		}
+		if !bytes.Equal(got[:len(want)], want) {
+			t.Errorf("reading by %d bytes = %x, want %x", size, got, want)
+		}
+	}
+
+	// the reader keeps a copy of the secret.
+	secret := []byte("shared secret")
+	r := NewReader(secret)
+	secret[0] ^= 1
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(r, got); err != nil {
+		t.Fatal("io.ReadFull:", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Read() after modifying the secret = %x, want %x", got, want)
+	}
+}
+
+func TestNewReader_exhausted(t *testing.T) {
+	r := NewReader([]byte("z")).(*reader)
+	r.ct = 1<<32 - 2
+	buf := make([]byte, 2*32)
+	n, err := r.Read(buf)
+	if n != 32 || err != io.EOF {
+		t.Errorf("Read() at the end of the keystream = %d, %v, want 32, EOF", n, err)
+	}
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("Read() after the end of the keystream = %d, %v, want 0, EOF", n, err)
+	}
+}
+
+func BenchmarkKey(b *testing.B) {
+	z := make([]byte, 64)
+	b.SetBytes(1024)
+	for i := 0; i < b.N; i++ {
+		Key(z, 1024)
+	}
+}
@@ -0,0 +1,274 @@
+package sm3
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"sort"
+)
+
+// Lanes is the number of messages hashed side by side by SumMany and
+// MultiHash.
+const Lanes = 8
+
+// multiState holds the states of the lanes word by word, i.e. multiState[i][l]
+// is the word i of the state of lane l.
+type multiState [8][Lanes]uint32
+
+// init sets the states of all lanes to the initial value.
+func (s *multiState) init() {
+	for i, v := range [8]uint32{init0, init1, init2, init3, init4, init5, init6, init7} {
+		for l := range s[i] {
+			s[i][l] = v
+		}
+	}
+}
+
+// checkSum returns the checksum of lane l.
+func (s *multiState) checkSum(l int) [Size]byte {
+	var digest [Size]byte
+	for i := range s {
+		binary.BigEndian.PutUint32(digest[i*4:], s[i][l])
+	}
+	return digest
+}
+
+// blockMultiGeneric is a portable, pure Go version of the multi-buffer SM3
+// block step, which compresses one block of each lane. The lanes are
+// compressed in pairs by blockPair.
+// It's used by sm3block_generic.go and tests.
+func blockMultiGeneric(h *multiState, x *[Lanes][chunk]byte) {
+	for l := 0; l < Lanes; l += 2 {
+		blockPair(h, x, l)
+	}
+}
+
+// blockPair compresses one block of the lanes l and l+1. The rounds of the two
+// lanes are interleaved, so that their independent dependency chains are
+// executed in parallel, while all the state words still fit in registers on
+// most architectures.
+func blockPair(h *multiState, x *[Lanes][chunk]byte, l int) {
+	var w, v [68]uint32
+	p, q := &x[l], &x[l+1]
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(p[i*4:])
+		v[i] = binary.BigEndian.Uint32(q[i*4:])
+	}
+	for i := 16; i < 68; i++ {
+		w[i] = p1(w[i-16]^w[i-9]^bits.RotateLeft32(w[i-3], 15)) ^ bits.RotateLeft32(w[i-13], 7) ^ w[i-6]
+		v[i] = p1(v[i-16]^v[i-9]^bits.RotateLeft32(v[i-3], 15)) ^ bits.RotateLeft32(v[i-13], 7) ^ v[i-6]
+	}
+
+	a, b, c, d, e, f, g, hh := h[0][l], h[1][l], h[2][l], h[3][l], h[4][l], h[5][l], h[6][l], h[7][l]
+	A, B, C, D, E, F, G, HH := h[0][l+1], h[1][l+1], h[2][l+1], h[3][l+1], h[4][l+1], h[5][l+1], h[6][l+1], h[7][l+1]
+	for i := 0; i < 64; i++ {
+		t := bits.RotateLeft32(t1, i)
+		if i < 16 {
+			t = bits.RotateLeft32(t0, i)
+		}
+		a12, A12 := bits.RotateLeft32(a, 12), bits.RotateLeft32(A, 12)
+		ss1, SS1 := bits.RotateLeft32(a12+e+t, 7), bits.RotateLeft32(A12+E+t, 7)
+		var tt1, tt2, TT1, TT2 uint32
+		if i < 16 {
+			tt1 = ff0(a, b, c) + d + (ss1 ^ a12) + (w[i] ^ w[i+4])
+			tt2 = gg0(e, f, g) + hh + ss1 + w[i]
+			TT1 = ff0(A, B, C) + D + (SS1 ^ A12) + (v[i] ^ v[i+4])
+			TT2 = gg0(E, F, G) + HH + SS1 + v[i]
+		} else {
+			tt1 = ff1(a, b, c) + d + (ss1 ^ a12) + (w[i] ^ w[i+4])
+			tt2 = gg1(e, f, g) + hh + ss1 + w[i]
+			TT1 = ff1(A, B, C) + D + (SS1 ^ A12) + (v[i] ^ v[i+4])
+			TT2 = gg1(E, F, G) + HH + SS1 + v[i]
+		}
+		d, c, b, a = c, bits.RotateLeft32(b, 9), a, tt1
+		hh, g, f, e = g, bits.RotateLeft32(f, 19), e, p0(tt2)
+		D, C, B, A = C, bits.RotateLeft32(B, 9), A, TT1
+		HH, G, F, E = G, bits.RotateLeft32(F, 19), E, p0(TT2)
+	}
+
+	h[0][l], h[0][l+1] = h[0][l]^a, h[0][l+1]^A
+	h[1][l], h[1][l+1] = h[1][l]^b, h[1][l+1]^B
+	h[2][l], h[2][l+1] = h[2][l]^c, h[2][l+1]^C
+	h[3][l], h[3][l+1] = h[3][l]^d, h[3][l+1]^D
+	h[4][l], h[4][l+1] = h[4][l]^e, h[4][l+1]^E
+	h[5][l], h[5][l+1] = h[5][l]^f, h[5][l+1]^F
+	h[6][l], h[6][l+1] = h[6][l]^g, h[6][l+1]^G
+	h[7][l], h[7][l+1] = h[7][l]^hh, h[7][l+1]^HH
+}
+
+// SumMany returns the SM3 checksums of msgs, which are identical to the
+// results of Sum. The messages are hashed side by side in Lanes lanes, which
+// is faster than hashing them one by one, especially for many short messages.
+// Messages of similar lengths are grouped together, so that few lanes are left
+// idle.
+func SumMany(msgs [][]byte) [][Size]byte {
+	sums := make([][Size]byte, len(msgs))
+	order := make([]int, len(msgs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(msgs[order[i]]) < len(msgs[order[j]])
+	})
+
+	var group [Lanes][]byte
+	for len(order) > 0 {
+		n := 0
+		for n < Lanes && n < len(order) {
+			group[n] = msgs[order[n]]
+			n++
+		}
+		if n == 1 {
+			sums[order[0]] = Sum(group[0])
+		} else {
+			sumLanes(sums, order[:n], group[:n])
+		}
+		order = order[n:]
+	}
+	return sums
+}
+
+// sumLanes computes the checksums of at most Lanes messages side by side, and
+// stores the checksum of msgs[l] in sums[index[l]].
+func sumLanes(sums [][Size]byte, index []int, msgs [][]byte) {
+	var (
+		state  multiState
+		x      [Lanes][chunk]byte
+		tails  [Lanes][2 * chunk]byte // padded last blocks
+		full   [Lanes]int             // number of full blocks in the message
+		blocks [Lanes]int             // number of blocks after padding
+		max    int
+	)
+	state.init()
+	for l, msg := range msgs {
+		full[l] = len(msg) / chunk
+		rest := copy(tails[l][:], msg[full[l]*chunk:])
+		tails[l][rest] = 0x80
+		n := 1
+		if rest >= chunk-8 {
+			n = 2
+		}
+		binary.BigEndian.PutUint64(tails[l][n*chunk-8:], uint64(len(msg))<<3)
+		blocks[l] = full[l] + n
+		if blocks[l] > max {
+			max = blocks[l]
+		}
+	}
+
+	for k := 0; k < max; k++ {
+		for l, msg := range msgs {
+			switch {
+			case k < full[l]:
+				copy(x[l][:], msg[k*chunk:])
+			case k < blocks[l]:
+				copy(x[l][:], tails[l][(k-full[l])*chunk:])
+			}
+		}
+		blockMulti(&state, &x)
+		for l := range msgs {
+			if k == blocks[l]-1 {
+				sums[index[l]] = state.checkSum(l)
+			}
+		}
+	}
+}
+
+// MultiHash is a lane-based hasher, which computes the SM3 checksums of Lanes
+// messages of the same length side by side. The messages are written in
+// chunks, where the chunks written at a time have the same length.
+type MultiHash struct {
+	h   multiState
+	x   [Lanes][chunk]byte
+	nx  int
+	len uint64
+}
+
+// NewMultiHash returns a new MultiHash.
+func NewMultiHash() *MultiHash {
+	m := new(MultiHash)
+	m.Reset()
+	return m
+}
+
+// Reset resets all lanes to their initial state.
+func (m *MultiHash) Reset() {
+	m.h.init()
+	m.nx = 0
+	m.len = 0
+}
+
+// Write writes the chunk p[l] to lane l for each l. All chunks must have the
+// same length. If fewer than Lanes chunks are given, the remaining lanes are
+// written with zeros, and their checksums are to be ignored.
+func (m *MultiHash) Write(p [][]byte) error {
+	if len(p) > Lanes {
+		return errors.New("gmcrypto/sm3: too many lanes")
+	}
+	n := 0
+	if len(p) > 0 {
+		n = len(p[0])
+	}
+	for _, b := range p {
+		if len(b) != n {
+			return errors.New("gmcrypto/sm3: chunks of different lengths")
+		}
+	}
+	m.write(p, n)
+	return nil
+}
+
+// write writes n bytes of each chunk in p to the lanes.
+func (m *MultiHash) write(p [][]byte, n int) {
+	m.len += uint64(n)
+	for off := 0; off < n; {
+		c := chunk - m.nx
+		if c > n-off {
+			c = n - off
+		}
+		for l := range m.x {
+			if l < len(p) {
+				copy(m.x[l][m.nx:], p[l][off:off+c])
+			} else {
+				copy(m.x[l][m.nx:m.nx+c], zeros[:])
+			}
+		}
+		m.nx += c
+		off += c
+		if m.nx == chunk {
+			blockMulti(&m.h, &m.x)
+			m.nx = 0
+		}
+	}
+}
+
+// zeros is a block of zeros for unused lanes.
+var zeros [chunk]byte
+
+// Sum returns the checksums of all lanes. It does not change the underlying
+// state, so that more chunks can be written.
+func (m *MultiHash) Sum() [Lanes][Size]byte {
+	// Make a copy of m so that caller can keep writing and summing.
+	m0 := *m
+
+	// Padding. Add a 1 bit and 0 bits until 56 bytes mod 64.
+	var tmp [chunk + 8]byte
+	tmp[0] = 0x80
+	pad := 56 - int(m0.len%chunk)
+	if pad <= 0 {
+		pad += chunk
+	}
+
+	// Length in bits.
+	binary.BigEndian.PutUint64(tmp[pad:], m0.len<<3)
+	var p [Lanes][]byte
+	for l := range p {
+		p[l] = tmp[:pad+8]
+	}
+	m0.write(p[:], pad+8)
+
+	var sums [Lanes][Size]byte
+	for l := range sums {
+		sums[l] = m0.h.checkSum(l)
+	}
+	return sums
+}
@@ -0,0 +1,142 @@
+package sm3
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+)
+
+// Tests that blockMultiGeneric, blockMulti and blockGeneric on each lane
+// match.
+func TestBlockMultiGeneric(t *testing.T) {
+	for i := 0; i < 30; i++ { // arbitrary factor
+		var gen, asm multiState
+		var state [8 * Lanes * 4]byte
+		rand.Read(state[:])
+		for j := range gen {
+			for l := range gen[j] {
+				gen[j][l] = binary.BigEndian.Uint32(state[(j*Lanes+l)*4:])
+			}
+		}
+		asm = gen
+		var x [Lanes][chunk]byte
+		for l := range x {
+			rand.Read(x[l][:])
+		}
+
+		blockMultiGeneric(&gen, &x)
+		blockMulti(&asm, &x)
+		if gen != asm {
+			t.Fatalf("For %#v blockMulti and blockMultiGeneric resulted in different states", x)
+		}
+		for l := range x {
+			var d digest
+			for j := range d.h {
+				d.h[j] = binary.BigEndian.Uint32(state[(j*Lanes+l)*4:])
+			}
+			blockGeneric(&d, x[l][:])
+			for j := range d.h {
+				if gen[j][l] != d.h[j] {
+					t.Fatalf("For %#v lane %d of blockMultiGeneric and blockGeneric resulted in different states", x[l], l)
+				}
+			}
+		}
+	}
+}
+
+func TestSumMany(t *testing.T) {
+	var msgs [][]byte
+	for _, n := range []int{0, 1, 3, 55, 56, 63, 64, 65, 119, 120, 128, 200, 1000} {
+		msg := make([]byte, n)
+		rand.Read(msg)
+		msgs = append(msgs, msg)
+	}
+	for _, g := range golden {
+		msgs = append(msgs, []byte(g.in))
+	}
+
+	for _, count := range []int{0, 1, 2, Lanes, Lanes + 1, len(msgs)} {
+		sums := SumMany(msgs[:count])
+		if len(sums) != count {
+			t.Fatalf("len(SumMany(%d messages)) = %d", count, len(sums))
+		}
+		for i, sum := range sums {
+			if want := Sum(msgs[i]); sum != want {
+				t.Errorf("SumMany(%d messages)[%d] = %x, want %x", count, i, sum, want)
+			}
+		}
+	}
+}
+
+func TestMultiHash(t *testing.T) {
+	for _, n := range []int{0, 1, 55, 56, 64, 100, 300} {
+		var msgs [Lanes][]byte
+		for l := range msgs {
+			msgs[l] = make([]byte, n)
+			rand.Read(msgs[l][:])
+		}
+		m := NewMultiHash()
+		for off := 0; off < n; off += 7 {
+			end := off + 7
+			if end > n {
+				end = n
+			}
+			var chunks [Lanes][]byte
+			for l := range chunks {
+				chunks[l] = msgs[l][off:end]
+			}
+			if err := m.Write(chunks[:]); err != nil {
+				t.Fatal("MultiHash.Write:", err)
+			}
+		}
+		sums := m.Sum()
+		for l, sum := range sums {
+			if want := Sum(msgs[l]); sum != want {
+				t.Errorf("MultiHash.Sum()[%d] for %d bytes = %x, want %x", l, n, sum, want)
+			}
+		}
+		if again := m.Sum(); again != sums {
+			t.Errorf("MultiHash.Sum() changed the state")
+		}
+	}
+
+	// unused lanes
+	m := NewMultiHash()
+	if err := m.Write([][]byte{[]byte("abc")}); err != nil {
+		t.Fatal("MultiHash.Write:", err)
+	}
+	if got, want := m.Sum()[0], Sum([]byte("abc")); got != want {
+		t.Errorf("MultiHash.Sum()[0] = %x, want %x", got, want)
+	}
+
+	m.Reset()
+	if err := m.Write([][]byte{[]byte("abc"), []byte("ab")}); err == nil {
+		t.Error("MultiHash.Write() with chunks of different lengths succeeded, want error")
+	}
+	if err := m.Write(make([][]byte, Lanes+1)); err == nil {
+		t.Error("MultiHash.Write() with too many lanes succeeded, want error")
+	}
+	if got, want := m.Sum()[0], Sum(nil); !bytes.Equal(got[:], want[:]) {
+		t.Errorf("MultiHash.Sum()[0] after failed writes = %x, want %x", got, want)
+	}
+}
+
+func benchmarkSumMany(b *testing.B, size int) {
+	msgs := make([][]byte, 64)
+	for i := range msgs {
+		msgs[i] = buf[:size]
+	}
+	b.SetBytes(int64(size * len(msgs)))
+	for i := 0; i < b.N; i++ {
+		SumMany(msgs)
+	}
+}
+
+func BenchmarkSumMany64Bytes(b *testing.B) {
+	benchmarkSumMany(b, 64)
+}
+
+func BenchmarkSumMany1K(b *testing.B) {
+	benchmarkSumMany(b, 1024)
+}
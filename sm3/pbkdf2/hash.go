@@ -0,0 +1,107 @@
+package pbkdf2
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/need-being/gmcrypto/sm3"
+)
+
+// Identifier is the algorithm identifier in the password hash strings.
+const Identifier = "pbkdf2-sm3"
+
+// DefaultIterations is the iteration count used by Hash.
+const DefaultIterations = 600000
+
+// MaxIterations is the largest iteration count accepted by HashWithIterations
+// and Compare, so that a crafted hash string cannot make Compare run for an
+// unbounded time.
+const MaxIterations = 10 * DefaultIterations
+
+// maxHashSize is the largest hash value accepted by Compare in bytes. Each
+// sm3.Size bytes of the hash value take another run of the iterations.
+const maxHashSize = 64
+
+// SaltSize is the size of the random salts generated by Hash in bytes.
+const SaltSize = 16
+
+// ErrMismatchedHashAndPassword is returned by Compare if the password does not
+// match the hash.
+var ErrMismatchedHashAndPassword = errors.New("sm3/pbkdf2: hash is not the hash of the given password")
+
+// encoding of the salt and the hash in the password hash strings.
+var b64 = base64.RawStdEncoding
+
+// Hash returns the hash of the password with a random salt and
+// DefaultIterations, in the self-describing format of the PHC string format
+//
+//	$pbkdf2-sm3$i=<iterations>$<salt>$<hash>
+//
+// where the salt and the hash are encoded in base64 without padding. The
+// string can be stored as is, and verified by Compare.
+func Hash(password []byte) (string, error) {
+	return HashWithIterations(rand.Reader, password, DefaultIterations)
+}
+
+// HashWithIterations is like Hash, but reads the salt from rand, and uses the
+// given iteration count, which must be in [1, MaxIterations].
+func HashWithIterations(rand io.Reader, password []byte, iter int) (string, error) {
+	if iter < 1 || iter > MaxIterations {
+		return "", errors.New("sm3/pbkdf2: invalid iteration count")
+	}
+	salt := make([]byte, SaltSize)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return "", err
+	}
+	key := Key(password, salt, iter, sm3.Size)
+	return "$" + Identifier + "$i=" + strconv.Itoa(iter) + "$" + b64.EncodeToString(salt) + "$" + b64.EncodeToString(key), nil
+}
+
+// Compare compares a password hash returned by Hash with the password in
+// constant time. It returns nil on success, ErrMismatchedHashAndPassword if
+// the password does not match, or another error if the hash is malformed. The
+// hash is rejected without computing the key if its iteration count exceeds
+// MaxIterations or its hash value exceeds 64 bytes.
+func Compare(hash string, password []byte) error {
+	iter, salt, key, err := parseHash(hash)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(Key(password, salt, iter, len(key)), key) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+	return nil
+}
+
+// parseHash parses a password hash string.
+func parseHash(hash string) (iter int, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 5 || parts[0] != "" {
+		return 0, nil, nil, errors.New("sm3/pbkdf2: malformed hash")
+	}
+	if parts[1] != Identifier {
+		return 0, nil, nil, errors.New("sm3/pbkdf2: unsupported algorithm")
+	}
+	if !strings.HasPrefix(parts[2], "i=") {
+		return 0, nil, nil, errors.New("sm3/pbkdf2: missing iteration count")
+	}
+	value := parts[2][len("i="):]
+	iter, err = strconv.Atoi(value)
+	if err != nil || iter < 1 || iter > MaxIterations || strconv.Itoa(iter) != value {
+		return 0, nil, nil, errors.New("sm3/pbkdf2: invalid iteration count")
+	}
+	salt, err = b64.DecodeString(parts[3])
+	if err != nil || len(salt) == 0 {
+		return 0, nil, nil, errors.New("sm3/pbkdf2: invalid salt")
+	}
+	key, err = b64.DecodeString(parts[4])
+	if err != nil || len(key) == 0 || len(key) > maxHashSize {
+		return 0, nil, nil, errors.New("sm3/pbkdf2: invalid hash value")
+	}
+	return iter, salt, key, nil
+}
@@ -0,0 +1,118 @@
+package pbkdf2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	password := []byte("correct horse battery staple")
+	hash, err := HashWithIterations(rand.Reader, password, 1000)
+	if err != nil {
+		t.Fatal("HashWithIterations:", err)
+	}
+	if !strings.HasPrefix(hash, "$pbkdf2-sm3$i=1000$") {
+		t.Errorf("HashWithIterations() = %s, want prefix $pbkdf2-sm3$i=1000$", hash)
+	}
+	if err := Compare(hash, password); err != nil {
+		t.Error("Compare:", err)
+	}
+	if err := Compare(hash, []byte("wrong password")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("Compare() with a wrong password = %v, want %v", err, ErrMismatchedHashAndPassword)
+	}
+
+	// salted
+	other, err := HashWithIterations(rand.Reader, password, 1000)
+	if err != nil {
+		t.Fatal("HashWithIterations:", err)
+	}
+	if other == hash {
+		t.Errorf("HashWithIterations() returned the same hash twice: %s", hash)
+	}
+
+	if hash, err := HashWithIterations(rand.Reader, password, 0); err == nil {
+		t.Errorf("HashWithIterations(0) = %s, want error", hash)
+	}
+	if hash, err := HashWithIterations(rand.Reader, password, MaxIterations+1); err == nil {
+		t.Errorf("HashWithIterations(MaxIterations+1) = %s, want error", hash)
+	}
+	if hash, err := HashWithIterations(bytes.NewReader(nil), password, 1); err == nil {
+		t.Errorf("HashWithIterations() without randomness = %s, want error", hash)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// hash of "password" with the salt "saltSALTsaltSALT" and 4096 iterations,
+	// computed by OpenSSL 3.0 with `openssl kdf -kdfopt digest:SM3 PBKDF2`.
+	const hash = "$pbkdf2-sm3$i=4096$c2FsdFNBTFRzYWx0U0FMVA$0EY7jFu0sBlF9wTWPRLd90YImwt++iAd28/XJtheB+I"
+	if err := Compare(hash, []byte("password")); err != nil {
+		t.Error("Compare:", err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{
+			name: "truncated hash value",
+			hash: "$pbkdf2-sm3$i=4096$c2FsdFNBTFRzYWx0U0FMVA$0EY7jFu0sBlF9wTWPRLd90YImwt++iAd28/XJtheB",
+		},
+		{
+			name: "other iteration count",
+			hash: "$pbkdf2-sm3$i=4095$c2FsdFNBTFRzYWx0U0FMVA$0EY7jFu0sBlF9wTWPRLd90YImwt++iAd28/XJtheB+I",
+		},
+		{
+			name: "non-canonical iteration count",
+			hash: "$pbkdf2-sm3$i=04096$c2FsdFNBTFRzYWx0U0FMVA$0EY7jFu0sBlF9wTWPRLd90YImwt++iAd28/XJtheB+I",
+		},
+		{
+			name: "zero iteration count",
+			hash: "$pbkdf2-sm3$i=0$c2FsdFNBTFRzYWx0U0FMVA$0EY7jFu0sBlF9wTWPRLd90YImwt++iAd28/XJtheB+I",
+		},
+		{
+			name: "excessive iteration count",
+			hash: "$pbkdf2-sm3$i=6000001$c2FsdFNBTFRzYWx0U0FMVA$0EY7jFu0sBlF9wTWPRLd90YImwt++iAd28/XJtheB+I",
+		},
+		{
+			name: "overflowing iteration count",
+			hash: "$pbkdf2-sm3$i=99999999999999999999$c2FsdFNBTFRzYWx0U0FMVA$0EY7jFu0sBlF9wTWPRLd90YImwt++iAd28/XJtheB+I",
+		},
+		{
+			name: "excessive hash value",
+			hash: "$pbkdf2-sm3$i=4096$c2FsdFNBTFRzYWx0U0FMVA$" + strings.Repeat("A", 88),
+		},
+		{
+			name: "missing iteration count",
+			hash: "$pbkdf2-sm3$c2FsdFNBTFRzYWx0U0FMVA$0EY7jFu0sBlF9wTWPRLd90YImwt++iAd28/XJtheB+I",
+		},
+		{
+			name: "other algorithm",
+			hash: "$pbkdf2-sha256$i=4096$c2FsdFNBTFRzYWx0U0FMVA$0EY7jFu0sBlF9wTWPRLd90YImwt++iAd28/XJtheB+I",
+		},
+		{
+			name: "padded salt",
+			hash: "$pbkdf2-sm3$i=4096$c2FsdFNBTFRzYWx0U0FMVA==$0EY7jFu0sBlF9wTWPRLd90YImwt++iAd28/XJtheB+I",
+		},
+		{
+			name: "empty salt",
+			hash: "$pbkdf2-sm3$i=4096$$0EY7jFu0sBlF9wTWPRLd90YImwt++iAd28/XJtheB+I",
+		},
+		{
+			name: "empty hash value",
+			hash: "$pbkdf2-sm3$i=4096$c2FsdFNBTFRzYWx0U0FMVA$",
+		},
+		{
+			name: "empty",
+			hash: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Compare(tt.hash, []byte("password")); err == nil {
+				t.Error("Compare() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package pbkdf2 implements the key derivation function PBKDF2 as defined in
+// RFC 8018 (PKCS #5 v2.1), with HMAC-SM3 as the pseudorandom function, and a
+// password hash storage format based on it.
+//
+// PBKDF2 derives keys from passwords by iterating the pseudorandom function,
+// so that brute force attacks are slowed down by the iteration count.
+package pbkdf2
+
+import (
+	"encoding"
+	"encoding/binary"
+	"hash"
+
+	"github.com/need-being/gmcrypto/sm3"
+)
+
+// prf is HMAC-SM3 keyed by a password. The states of the inner and the outer
+// hashes after absorbing the padded keys are saved, so that each invocation
+// restores them instead of compressing the padded keys again, which halves
+// the number of compressions in the iterations.
+type prf struct {
+	inner, outer           hash.Hash
+	innerState, outerState []byte
+	sum                    [sm3.Size]byte
+}
+
+// newPRF returns HMAC-SM3 keyed by password.
+func newPRF(password []byte) *prf {
+	if len(password) > sm3.BlockSize {
+		sum := sm3.Sum(password)
+		password = sum[:]
+	}
+	var ipad, opad [sm3.BlockSize]byte
+	copy(ipad[:], password)
+	copy(opad[:], password)
+	for i := range ipad {
+		ipad[i] ^= 0x36
+		opad[i] ^= 0x5c
+	}
+
+	p := &prf{
+		inner: sm3.New(),
+		outer: sm3.New(),
+	}
+	// write and marshal on sm3 never return error
+	p.inner.Write(ipad[:])
+	p.outer.Write(opad[:])
+	p.innerState, _ = p.inner.(encoding.BinaryMarshaler).MarshalBinary()
+	p.outerState, _ = p.outer.(encoding.BinaryMarshaler).MarshalBinary()
+	return p
+}
+
+// mac computes HMAC-SM3 of the concatenation of msgs into dst, which must
+// have the length of sm3.Size.
+func (p *prf) mac(dst []byte, msgs ...[]byte) {
+	// unmarshal on sm3 never returns error on its own states
+	p.inner.(encoding.BinaryUnmarshaler).UnmarshalBinary(p.innerState)
+	for _, msg := range msgs {
+		p.inner.Write(msg)
+	}
+	p.inner.Sum(p.sum[:0])
+	p.outer.(encoding.BinaryUnmarshaler).UnmarshalBinary(p.outerState)
+	p.outer.Write(p.sum[:])
+	p.outer.Sum(dst[:0])
+}
+
+// Key derives a key of keyLen bytes from the password, salt and iteration
+// count, using HMAC-SM3 as the pseudorandom function.
+//
+// The salt should be random and at least 16 bytes long, and the iteration
+// count should be as large as the latency budget allows. See Hash for a
+// storage format with recommended parameters.
+//
+// An iteration count less than 1 is treated as 1, and a zero keyLen returns an
+// empty key. Key panics if keyLen is negative.
+func Key(password, salt []byte, iter, keyLen int) []byte {
+	p := newPRF(password)
+	numBlocks := (keyLen + sm3.Size - 1) / sm3.Size
+
+	var counter [4]byte
+	var t, u [sm3.Size]byte
+	dk := make([]byte, 0, numBlocks*sm3.Size)
+	for block := 1; block <= numBlocks; block++ {
+		// U_1 = PRF(password, salt || uint(block))
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		p.mac(u[:], salt, counter[:])
+		t = u
+
+		// T_block = U_1 xor U_2 xor ... xor U_iter
+		for n := 2; n <= iter; n++ {
+			p.mac(u[:], u[:])
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+		dk = append(dk, t[:]...)
+	}
+	return dk[:keyLen]
+}
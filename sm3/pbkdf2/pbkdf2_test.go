@@ -0,0 +1,117 @@
+package pbkdf2
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/need-being/gmcrypto/sm3"
+)
+
+// The inputs are from RFC 6070, and the outputs are computed by OpenSSL 3.0
+// with `openssl kdf -kdfopt digest:SM3 PBKDF2`.
+var tests = []struct {
+	password string
+	salt     string
+	iter     int
+	key      string
+}{
+	{
+		password: "password",
+		salt:     "salt",
+		iter:     1,
+		key:      "4612f922a1fdcefaf4312fc6f8f3322b489cbf24f2ea361b44c2bd8fa2c6dcb0",
+	},
+	{
+		password: "password",
+		salt:     "salt",
+		iter:     2,
+		key:      "fee723a2bc966e11dffb66133f4e8df577383c78ade30e3298edbd3e54ed85b7",
+	},
+	{
+		password: "password",
+		salt:     "salt",
+		iter:     4096,
+		key:      "b6e8f2074c87432b78f62e5ced980fdff89e86af2f693dab1638e2b3683045dd",
+	},
+	{
+		password: "passwordPASSWORDpassword",
+		salt:     "saltSALTsaltSALTsaltSALTsaltSALTsalt",
+		iter:     4096,
+		key:      "3b6282ac8519f059e465abff0ea37b0dbfe6c672a76e6b805312d53900db630732ccc1a88fa5512a",
+	},
+}
+
+func TestKey(t *testing.T) {
+	for _, tt := range tests {
+		want, _ := hex.DecodeString(tt.key)
+		if got := Key([]byte(tt.password), []byte(tt.salt), tt.iter, len(want)); !bytes.Equal(got, want) {
+			t.Errorf("Key(%q, %q, %d) = %x, want %x", tt.password, tt.salt, tt.iter, got, want)
+		}
+	}
+}
+
+func TestKey_arguments(t *testing.T) {
+	password, salt := []byte("password"), []byte("salt")
+	want := Key(password, salt, 1, sm3.Size)
+	for _, iter := range []int{0, -1} {
+		if got := Key(password, salt, iter, sm3.Size); !bytes.Equal(got, want) {
+			t.Errorf("Key(iter = %d) = %x, want %x as with 1", iter, got, want)
+		}
+	}
+	if got := Key(password, salt, 1, 0); len(got) != 0 {
+		t.Errorf("Key(keyLen = 0) = %x, want empty", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Key(keyLen = -1) did not panic")
+		}
+	}()
+	Key(password, salt, 1, -1)
+}
+
+// referenceKey is PBKDF2 by crypto/hmac without saved states.
+func referenceKey(password, salt []byte, iter, keyLen int) []byte {
+	mac := hmac.New(sm3.New, password)
+	var dk []byte
+	for block := uint32(1); len(dk) < keyLen; block++ {
+		var counter [4]byte
+		binary.BigEndian.PutUint32(counter[:], block)
+		mac.Reset()
+		mac.Write(salt)
+		mac.Write(counter[:])
+		u := mac.Sum(nil)
+		t := append([]byte(nil), u...)
+		for n := 2; n <= iter; n++ {
+			mac.Reset()
+			mac.Write(u)
+			u = mac.Sum(u[:0])
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+		dk = append(dk, t...)
+	}
+	return dk[:keyLen]
+}
+
+func TestKey_reference(t *testing.T) {
+	for _, n := range []int{0, 1, 32, 63, 64, 65, 200} { // password lengths around the block size
+		password := bytes.Repeat([]byte{byte(n)}, n)
+		for _, keyLen := range []int{0, 1, 32, 33, 100} {
+			want := referenceKey(password, []byte("salt"), 3, keyLen)
+			if got := Key(password, []byte("salt"), 3, keyLen); !bytes.Equal(got, want) {
+				t.Errorf("Key(%d byte password, %d) = %x, want %x", n, keyLen, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkKey(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Key([]byte("password"), []byte("salt"), 1000, sm3.Size)
+	}
+}
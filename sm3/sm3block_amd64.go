@@ -0,0 +1,30 @@
+//go:build amd64 && gc && !purego
+// +build amd64,gc,!purego
+
+package sm3
+
+import "github.com/need-being/gmcrypto/internal/cpu"
+
+var useAVX2 = cpu.X86.HasAVX2 && cpu.X86.HasBMI2
+
+var (
+	block      = blockGeneric
+	blockMulti = blockMultiGeneric
+)
+
+func init() {
+	if useAVX2 {
+		block = blockAVX2
+		blockMulti = blockMultiAVX2
+	}
+}
+
+// blockAVX2 is implemented in sm3block_amd64.s.
+//
+//go:noescape
+func blockAVX2(dig *digest, p []byte)
+
+// blockMultiAVX2 is implemented in sm3multi_amd64.s.
+//
+//go:noescape
+func blockMultiAVX2(h *multiState, x *[Lanes][chunk]byte)
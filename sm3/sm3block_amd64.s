@@ -0,0 +1,263 @@
+//go:build amd64 && gc && !purego
+// +build amd64,gc,!purego
+
+#include "textflag.h"
+
+// SM3 block function for processors with AVX2 and BMI2.
+//
+// The message block is loaded and byte swapped with AVX2, and the expanded
+// words W[0..67] are stored on the stack. Since W[j] depends on W[j-3], the
+// message expansion computes three words at a time in the vector registers,
+// and W[j..j+2] is computed in round j-16, 12 rounds ahead of its
+// first use, so that the message expansion fills the execution units left
+// idle by the serial dependency of the rounds. Rotations in the rounds are
+// done by RORX, which leaves its source intact.
+//
+// The state words a, b, ..., h are held in R8, ..., R15, and instead of
+// moving them at the end of each round, the registers are renamed in the
+// arguments of the next round, which restores the original order every four
+// rounds:
+//
+//	d <- tt1, b <- b <<< 9, h <- P0(tt2), f <- f <<< 19
+//
+// AX, BX, CX and DX are scratch registers, DI points to the digest and SI to
+// the current block.
+
+// stack layout
+#define W_OFFSET 0     // W[0..70], where W[68..70] are garbage
+#define END_OFFSET 288 // end of the input
+
+#define W(i) (W_OFFSET+(i)*4)(SP)
+
+// SCHED computes W[j], W[j+1] and W[j+2] in the lanes of X0 by
+//
+//	W[j] = P1(W[j-16] ^ W[j-9] ^ (W[j-3] <<< 15)) ^ (W[j-13] <<< 7) ^ W[j-6]
+//
+// and stores them with a garbage word in W[j+3], which is overwritten by the
+// next call before it is read.
+#define SCHED(j) \
+	VMOVDQU W(j-16), X0;      \
+	VPXOR   W(j-9), X0, X0;   \
+	VMOVDQU W(j-3), X1;       \
+	VPSLLD  $15, X1, X3;      \
+	VPSRLD  $17, X1, X1;      \
+	VPXOR   X1, X3, X1;       \
+	VPXOR   X1, X0, X0;       \
+	VPSLLD  $15, X0, X1;      \
+	VPSRLD  $17, X0, X3;      \
+	VPXOR   X1, X3, X1;       \
+	VPSLLD  $23, X0, X3;      \
+	VPSRLD  $9, X0, X4;       \
+	VPXOR   X3, X4, X3;       \
+	VPXOR   X1, X0, X0;       \
+	VPXOR   X3, X0, X0;       \
+	VMOVDQU W(j-13), X1;      \
+	VPSLLD  $7, X1, X3;       \
+	VPSRLD  $25, X1, X1;      \
+	VPXOR   X1, X3, X1;       \
+	VPXOR   X1, X0, X0;       \
+	VPXOR   W(j-6), X0, X0;   \
+	VMOVDQU X0, W(j)
+
+// FF0 and FF1 compute FF(a, b, c) into CX, using DX as scratch. The terms of
+// b and c, which are ready earlier than a, are computed first.
+#define FF0(a, b, c) \
+	MOVL b, CX; \
+	XORL c, CX; \
+	XORL a, CX
+
+#define FF1(a, b, c) \
+	MOVL b, CX; \
+	XORL c, CX; \
+	ANDL a, CX; \
+	MOVL b, DX; \
+	ANDL c, DX; \
+	XORL DX, CX
+
+// GG0 and GG1 compute GG(e, f, g) into CX.
+#define GG0(e, f, g) \
+	MOVL f, CX; \
+	XORL g, CX; \
+	XORL e, CX
+
+#define GG1(e, f, g) \
+	MOVL f, CX; \
+	XORL g, CX; \
+	ANDL e, CX; \
+	XORL g, CX
+
+// ROUND computes round i with the rotated constant t = T_i <<< i, where
+//
+//	SS1 = ((a <<< 12) + e + t) <<< 7
+//	SS2 = SS1 ^ (a <<< 12)
+//	TT1 = FF(a, b, c) + d + SS2 + (W[i] ^ W[i+4])
+//	TT2 = GG(e, f, g) + h + SS1 + W[i]
+//
+// The terms of TT1 and TT2 other than SS1 and SS2 are summed up first, so as
+// to shorten the dependency chains across rounds.
+#define ROUND(i, t, a, b, c, d, e, f, g, h, FF, GG) \
+	MOVL  W(i), CX;      \
+	XORL  W(i+4), CX;    \
+	ADDL  CX, d;         \
+	FF(a, b, c);         \
+	ADDL  CX, d;         \
+	ADDL  W(i), h;       \
+	GG(e, f, g);         \
+	ADDL  CX, h;         \
+	RORXL $20, a, AX;    \
+	LEAL  t(e), BX;      \
+	ADDL  AX, BX;        \
+	RORXL $25, BX, BX;   \
+	ADDL  BX, h;         \
+	XORL  BX, AX;        \
+	ADDL  AX, d;         \
+	RORXL $23, b, b;     \
+	RORXL $13, f, f;     \
+	RORXL $23, h, CX;    \
+	RORXL $15, h, DX;    \
+	XORL  CX, DX;        \
+	XORL  DX, h
+
+#define ROUND_AND_SCHED_0(i, t, a, b, c, d, e, f, g, h) \
+	SCHED(i+16); \
+	ROUND(i, t, a, b, c, d, e, f, g, h, FF0, GG0)
+
+#define ROUND_AND_SCHED_1(i, t, a, b, c, d, e, f, g, h) \
+	SCHED(i+16); \
+	ROUND(i, t, a, b, c, d, e, f, g, h, FF1, GG1)
+
+#define ROUND_0(i, t, a, b, c, d, e, f, g, h) \
+	ROUND(i, t, a, b, c, d, e, f, g, h, FF0, GG0)
+
+#define ROUND_1(i, t, a, b, c, d, e, f, g, h) \
+	ROUND(i, t, a, b, c, d, e, f, g, h, FF1, GG1)
+
+// func blockAVX2(dig *digest, p []byte)
+TEXT ·blockAVX2(SB), 0, $296-32
+	MOVQ dig+0(FP), DI
+	MOVQ p_base+8(FP), SI
+	MOVQ p_len+16(FP), DX
+	SHRQ $6, DX
+	SHLQ $6, DX
+	JEQ  done
+	ADDQ SI, DX
+	MOVQ DX, END_OFFSET(SP)
+
+	VMOVDQU flip_mask<>(SB), Y2
+
+	MOVL (0*4)(DI), R8
+	MOVL (1*4)(DI), R9
+	MOVL (2*4)(DI), R10
+	MOVL (3*4)(DI), R11
+	MOVL (4*4)(DI), R12
+	MOVL (5*4)(DI), R13
+	MOVL (6*4)(DI), R14
+	MOVL (7*4)(DI), R15
+
+loop:
+	// W[0..15] is the block in big-endian.
+	VMOVDQU (0*32)(SI), Y0
+	VMOVDQU (1*32)(SI), Y1
+	VPSHUFB Y2, Y0, Y0
+	VPSHUFB Y2, Y1, Y1
+	VMOVDQU Y0, W(0)
+	VMOVDQU Y1, W(8)
+
+	// the rotated constants are signed to fit in the displacements of LEAL.
+	ROUND_AND_SCHED_0(0, 0x79cc4519, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_0(1, -0x0c6775ce, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_0(2, -0x18ceeb9b, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_AND_SCHED_0(3, -0x319dd735, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_0(4, -0x633bae69, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_0(5, 0x3988a32f, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_AND_SCHED_0(6, 0x7311465e, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_0(7, -0x19dd7344, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_0(8, -0x33bae687, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_AND_SCHED_0(9, -0x6775cd0d, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_0(10, 0x311465e7, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_0(11, 0x6228cbce, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_AND_SCHED_0(12, -0x3bae6864, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_0(13, -0x775cd0c7, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_0(14, 0x11465e73, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_AND_SCHED_0(15, 0x228cbce6, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_1(16, -0x62758579, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_1(17, 0x3b14f50f, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_AND_SCHED_1(18, 0x7629ea1e, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_1(19, -0x13ac2bc4, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_1(20, -0x27585787, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_AND_SCHED_1(21, -0x4eb0af0d, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_1(22, 0x629ea1e7, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_1(23, -0x3ac2bc32, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_AND_SCHED_1(24, -0x75857863, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_1(25, 0x14f50f3b, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_1(26, 0x29ea1e76, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_AND_SCHED_1(27, 0x53d43cec, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_1(28, -0x58578628, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_1(29, 0x4f50f3b1, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_AND_SCHED_1(30, -0x615e189e, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_1(31, 0x3d43cec5, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_1(32, 0x7a879d8a, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_AND_SCHED_1(33, -0x0af0c4ec, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_1(34, -0x15e189d7, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_1(35, -0x2bc313ad, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_AND_SCHED_1(36, -0x57862759, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_1(37, 0x50f3b14f, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_1(38, -0x5e189d62, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_AND_SCHED_1(39, 0x43cec53d, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_1(40, -0x78627586, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_1(41, 0x0f3b14f5, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_AND_SCHED_1(42, 0x1e7629ea, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_1(43, 0x3cec53d4, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_1(44, 0x79d8a7a8, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_AND_SCHED_1(45, -0x0c4eb0b0, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_1(46, -0x189d615f, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_1(47, -0x313ac2bd, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_AND_SCHED_1(48, -0x62758579, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_1(49, 0x3b14f50f, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_1(50, 0x7629ea1e, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_AND_SCHED_1(51, -0x13ac2bc4, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_1(52, -0x27585787, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_1(53, -0x4eb0af0d, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_1(54, 0x629ea1e7, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_1(55, -0x3ac2bc32, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_1(56, -0x75857863, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_1(57, 0x14f50f3b, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_1(58, 0x29ea1e76, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_1(59, 0x53d43cec, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND_1(60, -0x58578628, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND_1(61, 0x4f50f3b1, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND_1(62, -0x615e189e, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND_1(63, 0x3d43cec5, R9, R10, R11, R8, R13, R14, R15, R12)
+
+	XORL (0*4)(DI), R8
+	XORL (1*4)(DI), R9
+	XORL (2*4)(DI), R10
+	XORL (3*4)(DI), R11
+	XORL (4*4)(DI), R12
+	XORL (5*4)(DI), R13
+	XORL (6*4)(DI), R14
+	XORL (7*4)(DI), R15
+	MOVL R8, (0*4)(DI)
+	MOVL R9, (1*4)(DI)
+	MOVL R10, (2*4)(DI)
+	MOVL R11, (3*4)(DI)
+	MOVL R12, (4*4)(DI)
+	MOVL R13, (5*4)(DI)
+	MOVL R14, (6*4)(DI)
+	MOVL R15, (7*4)(DI)
+
+	ADDQ $64, SI
+	CMPQ SI, END_OFFSET(SP)
+	JB   loop
+
+	VZEROUPPER
+
+done:
+	RET
+
+// shuffle mask for VPSHUFB to swap the bytes of each 32-bit word.
+DATA flip_mask<>+0x00(SB)/8, $0x0405060700010203
+DATA flip_mask<>+0x08(SB)/8, $0x0c0d0e0f08090a0b
+DATA flip_mask<>+0x10(SB)/8, $0x0405060700010203
+DATA flip_mask<>+0x18(SB)/8, $0x0c0d0e0f08090a0b
+GLOBL flip_mask<>(SB), RODATA, $32
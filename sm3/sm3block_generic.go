@@ -1,3 +1,8 @@
+//go:build !amd64 || !gc || purego
+// +build !amd64 !gc purego
+
 package sm3
 
 var block = blockGeneric
+
+var blockMulti = blockMultiGeneric
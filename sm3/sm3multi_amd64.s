@@ -0,0 +1,318 @@
+//go:build amd64 && gc && !purego
+// +build amd64,gc,!purego
+
+#include "textflag.h"
+
+// Multi-buffer SM3 block function for processors with AVX2, which compresses
+// one block of each of the 8 lanes, with the 32-bit lanes of the YMM registers
+// holding the words of the 8 messages.
+//
+// The state words a, b, ..., h are held in Y0, ..., Y7, and renamed in the
+// arguments of the next round as in sm3block_amd64.s. The expanded words
+// W[0..67] are stored on the stack, where W[i+16] is computed in round i.
+// Y8, ..., Y11 are scratch registers of the rounds, and Y12, ..., Y15 of the
+// message expansion. Without a vector rotation in AVX2, x <<< n is computed
+// as (x << n) | (x >> (32-n)).
+
+#define W(i) ((i)*32)(SP)
+
+// ROTL sets dst = src <<< n, using tmp as scratch. dst may be src.
+#define ROTL(n, src, dst, tmp) \
+	VPSLLD $n, src, tmp;      \
+	VPSRLD $(32-n), src, dst; \
+	VPOR   tmp, dst, dst
+
+// LOAD loads the word i of all lanes into W[i] in big-endian, where SI points
+// to the blocks, Y13 holds the offsets of the lanes and Y15 the byte shuffle
+// mask.
+#define LOAD(i) \
+	VPCMPEQD   Y14, Y14, Y14;        \
+	VPGATHERDD Y14, (i*4)(SI)(Y13*1), Y12; \
+	VPSHUFB    Y15, Y12, Y12;        \
+	VMOVDQU    Y12, W(i)
+
+// SCHED computes
+//
+//	W[j] = P1(W[j-16] ^ W[j-9] ^ (W[j-3] <<< 15)) ^ (W[j-13] <<< 7) ^ W[j-6]
+#define SCHED(j) \
+	VMOVDQU W(j-16), Y12;    \
+	VPXOR   W(j-9), Y12, Y12; \
+	VMOVDQU W(j-3), Y13;     \
+	ROTL(15, Y13, Y13, Y14); \
+	VPXOR   Y13, Y12, Y12;   \
+	ROTL(15, Y12, Y13, Y14); \
+	ROTL(23, Y12, Y14, Y15); \
+	VPXOR   Y13, Y12, Y12;   \
+	VPXOR   Y14, Y12, Y12;   \
+	VMOVDQU W(j-13), Y13;    \
+	ROTL(7, Y13, Y13, Y14);  \
+	VPXOR   Y13, Y12, Y12;   \
+	VPXOR   W(j-6), Y12, Y12; \
+	VMOVDQU Y12, W(j)
+
+// FF0 and FF1 compute FF(a, b, c) into Y10, using Y11 as scratch.
+#define FF0(a, b, c) \
+	VPXOR c, b, Y10; \
+	VPXOR a, Y10, Y10
+
+#define FF1(a, b, c) \
+	VPXOR c, b, Y10;   \
+	VPAND a, Y10, Y10; \
+	VPAND c, b, Y11;   \
+	VPXOR Y11, Y10, Y10
+
+// GG0 and GG1 compute GG(e, f, g) into Y10.
+#define GG0(e, f, g) \
+	VPXOR g, f, Y10; \
+	VPXOR e, Y10, Y10
+
+#define GG1(e, f, g) \
+	VPXOR g, f, Y10;   \
+	VPAND e, Y10, Y10; \
+	VPXOR g, Y10, Y10
+
+// ROUND computes round i, where
+//
+//	SS1 = ((a <<< 12) + e + (T_i <<< i)) <<< 7
+//	SS2 = SS1 ^ (a <<< 12)
+//	TT1 = FF(a, b, c) + d + SS2 + (W[i] ^ W[i+4])
+//	TT2 = GG(e, f, g) + h + SS1 + W[i]
+//	d <- TT1, b <- b <<< 9, h <- P0(TT2), f <- f <<< 19
+#define ROUND(i, a, b, c, d, e, f, g, h, FF, GG) \
+	ROTL(12, a, Y8, Y9);                   \
+	VPADDD       e, Y8, Y9;                \
+	VPBROADCASTD constants<>+(i*4)(SB), Y10; \
+	VPADDD       Y10, Y9, Y9;              \
+	ROTL(7, Y9, Y9, Y10);                  \
+	VPXOR        Y9, Y8, Y8;               \
+	VPADDD       W(i), h, h;               \
+	GG(e, f, g);                           \
+	VPADDD       Y10, h, h;                \
+	VPADDD       Y9, h, h;                 \
+	VMOVDQU      W(i), Y10;                \
+	VPXOR        W(i+4), Y10, Y10;         \
+	VPADDD       Y10, d, d;                \
+	FF(a, b, c);                           \
+	VPADDD       Y10, d, d;                \
+	VPADDD       Y8, d, d;                 \
+	ROTL(9, b, b, Y10);                    \
+	ROTL(19, f, f, Y10);                   \
+	ROTL(9, h, Y10, Y11);                  \
+	ROTL(17, h, Y11, Y8);                  \
+	VPXOR        Y10, h, h;                \
+	VPXOR        Y11, h, h
+
+#define ROUND_AND_SCHED_0(i, a, b, c, d, e, f, g, h) \
+	SCHED(i+16); \
+	ROUND(i, a, b, c, d, e, f, g, h, FF0, GG0)
+
+#define ROUND_AND_SCHED_1(i, a, b, c, d, e, f, g, h) \
+	SCHED(i+16); \
+	ROUND(i, a, b, c, d, e, f, g, h, FF1, GG1)
+
+#define ROUND_1(i, a, b, c, d, e, f, g, h) \
+	ROUND(i, a, b, c, d, e, f, g, h, FF1, GG1)
+
+// func blockMultiAVX2(h *multiState, x *[Lanes][chunk]byte)
+TEXT ·blockMultiAVX2(SB), 0, $2176-16
+	MOVQ h+0(FP), DI
+	MOVQ x+8(FP), SI
+
+	// W[0..15] are the words of the blocks, which are gathered from the lanes.
+	VMOVDQU lane_offsets<>(SB), Y13
+	VMOVDQU flip_mask<>(SB), Y15
+	LOAD(0)
+	LOAD(1)
+	LOAD(2)
+	LOAD(3)
+	LOAD(4)
+	LOAD(5)
+	LOAD(6)
+	LOAD(7)
+	LOAD(8)
+	LOAD(9)
+	LOAD(10)
+	LOAD(11)
+	LOAD(12)
+	LOAD(13)
+	LOAD(14)
+	LOAD(15)
+
+	VMOVDQU (0*32)(DI), Y0
+	VMOVDQU (1*32)(DI), Y1
+	VMOVDQU (2*32)(DI), Y2
+	VMOVDQU (3*32)(DI), Y3
+	VMOVDQU (4*32)(DI), Y4
+	VMOVDQU (5*32)(DI), Y5
+	VMOVDQU (6*32)(DI), Y6
+	VMOVDQU (7*32)(DI), Y7
+
+	ROUND_AND_SCHED_0(0, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_0(1, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_0(2, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_0(3, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_0(4, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_0(5, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_0(6, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_0(7, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_0(8, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_0(9, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_0(10, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_0(11, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_0(12, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_0(13, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_0(14, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_0(15, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_1(16, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_1(17, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_1(18, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_1(19, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_1(20, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_1(21, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_1(22, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_1(23, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_1(24, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_1(25, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_1(26, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_1(27, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_1(28, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_1(29, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_1(30, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_1(31, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_1(32, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_1(33, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_1(34, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_1(35, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_1(36, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_1(37, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_1(38, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_1(39, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_1(40, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_1(41, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_1(42, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_1(43, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_1(44, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_1(45, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_1(46, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_1(47, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_AND_SCHED_1(48, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_AND_SCHED_1(49, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_AND_SCHED_1(50, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_AND_SCHED_1(51, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_1(52, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_1(53, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_1(54, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_1(55, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_1(56, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_1(57, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_1(58, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_1(59, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+	ROUND_1(60, Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+	ROUND_1(61, Y3, Y0, Y1, Y2, Y7, Y4, Y5, Y6)
+	ROUND_1(62, Y2, Y3, Y0, Y1, Y6, Y7, Y4, Y5)
+	ROUND_1(63, Y1, Y2, Y3, Y0, Y5, Y6, Y7, Y4)
+
+	VPXOR (0*32)(DI), Y0, Y0
+	VPXOR (1*32)(DI), Y1, Y1
+	VPXOR (2*32)(DI), Y2, Y2
+	VPXOR (3*32)(DI), Y3, Y3
+	VPXOR (4*32)(DI), Y4, Y4
+	VPXOR (5*32)(DI), Y5, Y5
+	VPXOR (6*32)(DI), Y6, Y6
+	VPXOR (7*32)(DI), Y7, Y7
+	VMOVDQU Y0, (0*32)(DI)
+	VMOVDQU Y1, (1*32)(DI)
+	VMOVDQU Y2, (2*32)(DI)
+	VMOVDQU Y3, (3*32)(DI)
+	VMOVDQU Y4, (4*32)(DI)
+	VMOVDQU Y5, (5*32)(DI)
+	VMOVDQU Y6, (6*32)(DI)
+	VMOVDQU Y7, (7*32)(DI)
+
+	VZEROUPPER
+	RET
+
+// offsets of the blocks of the lanes for VPGATHERDD.
+DATA lane_offsets<>+0x00(SB)/4, $0
+DATA lane_offsets<>+0x04(SB)/4, $64
+DATA lane_offsets<>+0x08(SB)/4, $128
+DATA lane_offsets<>+0x0c(SB)/4, $192
+DATA lane_offsets<>+0x10(SB)/4, $256
+DATA lane_offsets<>+0x14(SB)/4, $320
+DATA lane_offsets<>+0x18(SB)/4, $384
+DATA lane_offsets<>+0x1c(SB)/4, $448
+GLOBL lane_offsets<>(SB), RODATA, $32
+
+// shuffle mask for VPSHUFB to swap the bytes of each 32-bit word.
+DATA flip_mask<>+0x00(SB)/8, $0x0405060700010203
+DATA flip_mask<>+0x08(SB)/8, $0x0c0d0e0f08090a0b
+DATA flip_mask<>+0x10(SB)/8, $0x0405060700010203
+DATA flip_mask<>+0x18(SB)/8, $0x0c0d0e0f08090a0b
+GLOBL flip_mask<>(SB), RODATA, $32
+
+// round constants T_i <<< i.
+DATA constants<>+0x00(SB)/4, $0x79cc4519
+DATA constants<>+0x04(SB)/4, $0xf3988a32
+DATA constants<>+0x08(SB)/4, $0xe7311465
+DATA constants<>+0x0c(SB)/4, $0xce6228cb
+DATA constants<>+0x10(SB)/4, $0x9cc45197
+DATA constants<>+0x14(SB)/4, $0x3988a32f
+DATA constants<>+0x18(SB)/4, $0x7311465e
+DATA constants<>+0x1c(SB)/4, $0xe6228cbc
+DATA constants<>+0x20(SB)/4, $0xcc451979
+DATA constants<>+0x24(SB)/4, $0x988a32f3
+DATA constants<>+0x28(SB)/4, $0x311465e7
+DATA constants<>+0x2c(SB)/4, $0x6228cbce
+DATA constants<>+0x30(SB)/4, $0xc451979c
+DATA constants<>+0x34(SB)/4, $0x88a32f39
+DATA constants<>+0x38(SB)/4, $0x11465e73
+DATA constants<>+0x3c(SB)/4, $0x228cbce6
+DATA constants<>+0x40(SB)/4, $0x9d8a7a87
+DATA constants<>+0x44(SB)/4, $0x3b14f50f
+DATA constants<>+0x48(SB)/4, $0x7629ea1e
+DATA constants<>+0x4c(SB)/4, $0xec53d43c
+DATA constants<>+0x50(SB)/4, $0xd8a7a879
+DATA constants<>+0x54(SB)/4, $0xb14f50f3
+DATA constants<>+0x58(SB)/4, $0x629ea1e7
+DATA constants<>+0x5c(SB)/4, $0xc53d43ce
+DATA constants<>+0x60(SB)/4, $0x8a7a879d
+DATA constants<>+0x64(SB)/4, $0x14f50f3b
+DATA constants<>+0x68(SB)/4, $0x29ea1e76
+DATA constants<>+0x6c(SB)/4, $0x53d43cec
+DATA constants<>+0x70(SB)/4, $0xa7a879d8
+DATA constants<>+0x74(SB)/4, $0x4f50f3b1
+DATA constants<>+0x78(SB)/4, $0x9ea1e762
+DATA constants<>+0x7c(SB)/4, $0x3d43cec5
+DATA constants<>+0x80(SB)/4, $0x7a879d8a
+DATA constants<>+0x84(SB)/4, $0xf50f3b14
+DATA constants<>+0x88(SB)/4, $0xea1e7629
+DATA constants<>+0x8c(SB)/4, $0xd43cec53
+DATA constants<>+0x90(SB)/4, $0xa879d8a7
+DATA constants<>+0x94(SB)/4, $0x50f3b14f
+DATA constants<>+0x98(SB)/4, $0xa1e7629e
+DATA constants<>+0x9c(SB)/4, $0x43cec53d
+DATA constants<>+0xa0(SB)/4, $0x879d8a7a
+DATA constants<>+0xa4(SB)/4, $0x0f3b14f5
+DATA constants<>+0xa8(SB)/4, $0x1e7629ea
+DATA constants<>+0xac(SB)/4, $0x3cec53d4
+DATA constants<>+0xb0(SB)/4, $0x79d8a7a8
+DATA constants<>+0xb4(SB)/4, $0xf3b14f50
+DATA constants<>+0xb8(SB)/4, $0xe7629ea1
+DATA constants<>+0xbc(SB)/4, $0xcec53d43
+DATA constants<>+0xc0(SB)/4, $0x9d8a7a87
+DATA constants<>+0xc4(SB)/4, $0x3b14f50f
+DATA constants<>+0xc8(SB)/4, $0x7629ea1e
+DATA constants<>+0xcc(SB)/4, $0xec53d43c
+DATA constants<>+0xd0(SB)/4, $0xd8a7a879
+DATA constants<>+0xd4(SB)/4, $0xb14f50f3
+DATA constants<>+0xd8(SB)/4, $0x629ea1e7
+DATA constants<>+0xdc(SB)/4, $0xc53d43ce
+DATA constants<>+0xe0(SB)/4, $0x8a7a879d
+DATA constants<>+0xe4(SB)/4, $0x14f50f3b
+DATA constants<>+0xe8(SB)/4, $0x29ea1e76
+DATA constants<>+0xec(SB)/4, $0x53d43cec
+DATA constants<>+0xf0(SB)/4, $0xa7a879d8
+DATA constants<>+0xf4(SB)/4, $0x4f50f3b1
+DATA constants<>+0xf8(SB)/4, $0x9ea1e762
+DATA constants<>+0xfc(SB)/4, $0x3d43cec5
+GLOBL constants<>(SB), RODATA, $256
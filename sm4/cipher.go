@@ -7,7 +7,7 @@ import (
 )
 
 // BlockSize is the SM4 block size in bytes.
-const BlockSize = 8
+const BlockSize = 16
 
 // KeySizeError indicates invalid key size
 type KeySizeError int
@@ -104,6 +104,16 @@ func TestCipherDecryptRepeated(t *testing.T) {
 	}
 }
 
+func TestCipherBlockSize(t *testing.T) {
+	c, err := NewCipher(encryptTests[0].key)
+	if err != nil {
+		t.Fatal("NewCipher:", err)
+	}
+	if got := c.BlockSize(); got != 16 {
+		t.Errorf("Cipher.BlockSize() = %d, want 16", got)
+	}
+}
+
 func BenchmarkNewCipher(b *testing.B) {
 	tt := encryptTests[0]
 	b.ResetTimer()